@@ -45,6 +45,7 @@ func deleteUnreachableBlocks(f *Function) {
 			if debugBlockOpt {
 				fmt.Fprintln(os.Stderr, "unreachable", b)
 			}
+			f.noteDeadStmt(b)
 			f.Blocks[i] = nil // delete b
 		}
 	}
@@ -310,12 +310,14 @@ func (b *builder) builtin(fn *Function, obj *types.Builtin, args []ast.Expr, typ
 		// Otherwise treat as normal.
 
 	case "panic":
-		fn.emit(&Panic{
+		p := &Panic{
 			X:   emitConv(fn, b.expr(fn, args[0]), tEface),
 			pos: pos,
 			endP: endP,
-		})
+		}
+		fn.emit(p)
 		fn.currentBlock = fn.newBasicBlock("unreachable", nil)
+		fn.notePanicBlock(fn.currentBlock, p)
 		return vFalse // any non-nil Value will do
 	}
 	return nil // treat all others as a regular function call
@@ -498,8 +500,7 @@ func (b *builder) expr0(fn *Function, e ast.Expr) Value {
 			Prog:      fn.Prog,
 			syntax:    e,
 			Breakpoint: false,
-			Scope     : nil,
-			LocalsByName: make(map[NameScope]uint),
+			Scope     : astScope(fn, e.Type),
 			endP:       e.Body.End(),
 		}
 		fn.AnonFuncs = append(fn.AnonFuncs, fn2)
@@ -2190,7 +2191,6 @@ func (b *builder) buildFuncDecl(pkg *Package, decl *ast.FuncDecl) {
 			pos:       decl.Name.NamePos,
 			Pkg:       pkg,
 			Prog:      pkg.Prog,
-			LocalsByName: make(map[NameScope]uint),
 			syntax:    decl,
 		}
 
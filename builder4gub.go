@@ -12,8 +12,11 @@ func astScope(fn *Function, node ast.Node) *Scope {
 	return scope
 }
 
+// ParentScope returns the scope enclosing scope, or nil if there is
+// none in fn's package, as for the universe scope.
 func ParentScope(fn *Function, scope *Scope) *Scope {
-	// FIXME: reinstate scope
-	// return fn.Pkg.TypeScope2Scope[scope.Scope.Parent()]
-	return nil
+	if scope == nil || fn.Pkg == nil {
+		return nil
+	}
+	return fn.Pkg.TypeScope2Scope[scope.Scope.Parent()]
 }
@@ -0,0 +1,197 @@
+// Copyright 2013 Rocky Bernstein.
+
+// Package callgraph builds static call graphs of ssa2 programs.
+//
+// A call graph has a node for each function which may be called and
+// an edge for each call site and function it may call. Calls through
+// interfaces and of function values are resolved by one of two
+// analyses:
+//
+// Class Hierarchy Analysis (CHA) assumes a call through an interface
+// may go to the method of that name of any type in the program which
+// implements the interface, and a call of a function value to any
+// function of the same signature whose address is taken.
+//
+// Rapid Type Analysis (RTA) starts from some root functions, such as
+// main and init, and only considers the types converted to an
+// interface and the functions whose address is taken in code which
+// is reachable from them. This gives fewer edges than CHA, at the
+// cost of missing calls which happen only through reflection.
+//
+// Calls of built-in functions aren't part of the graph.
+package callgraph
+
+import (
+	"sort"
+
+	"code.google.com/p/go.tools/go/types"
+	"github.com/rocky/ssa-interp"
+)
+
+// A Graph is a call graph.
+type Graph struct {
+	Nodes map[*ssa2.Function]*Node
+	Roots []*Node // functions the graph was built from; nil for CHA
+}
+
+// A Node is a function in a call graph.
+type Node struct {
+	Func *ssa2.Function
+	In   []*Edge // calls of Func
+	Out  []*Edge // calls by Func
+}
+
+// An Edge is a call, at Site in Caller, which may go to Callee.
+type Edge struct {
+	Caller *Node
+	Site   ssa2.CallInstruction
+	Callee *Node
+}
+
+func newGraph() *Graph {
+	return &Graph{Nodes: make(map[*ssa2.Function]*Node)}
+}
+
+// node gives the node of fn, adding one if need be.
+func (g *Graph) node(fn *ssa2.Function) *Node {
+	n := g.Nodes[fn]
+	if n == nil {
+		n = &Node{Func: fn}
+		g.Nodes[fn] = n
+	}
+	return n
+}
+
+// addEdge adds the edge for a call at site in caller of callee.
+func (g *Graph) addEdge(caller *ssa2.Function, site ssa2.CallInstruction, callee *ssa2.Function) {
+	e := &Edge{Caller: g.node(caller), Site: site, Callee: g.node(callee)}
+	e.Caller.Out = append(e.Caller.Out, e)
+	e.Callee.In = append(e.Callee.In, e)
+}
+
+// Callers gives the calls of fn, in no particular order. The slice
+// is the caller's own, to sort or otherwise change.
+func (g *Graph) Callers(fn *ssa2.Function) []*Edge {
+	if n := g.Nodes[fn]; n != nil {
+		return append([]*Edge(nil), n.In...)
+	}
+	return nil
+}
+
+// Callees gives the functions the call at site may go to, sorted by
+// name.
+func (g *Graph) Callees(site ssa2.CallInstruction) []*ssa2.Function {
+	n := g.Nodes[site.Parent()]
+	if n == nil {
+		return nil
+	}
+	var callees []*ssa2.Function
+	for _, e := range n.Out {
+		if e.Site == site {
+			callees = append(callees, e.Callee.Func)
+		}
+	}
+	sort.Sort(byName(callees))
+	return callees
+}
+
+// Reachable gives the functions which may be called, directly or
+// not, from those of roots, including roots themselves.
+func (g *Graph) Reachable(roots ...*ssa2.Function) map[*ssa2.Function]bool {
+	seen := make(map[*ssa2.Function]bool)
+	var visit func(fn *ssa2.Function)
+	visit = func(fn *ssa2.Function) {
+		if seen[fn] {
+			return
+		}
+		seen[fn] = true
+		if n := g.Nodes[fn]; n != nil {
+			for _, e := range n.Out {
+				visit(e.Callee.Func)
+			}
+		}
+	}
+	for _, fn := range roots {
+		visit(fn)
+	}
+	return seen
+}
+
+// byName sorts functions by their String().
+type byName []*ssa2.Function
+
+func (s byName) Len() int           { return len(s) }
+func (s byName) Less(i, j int) bool { return s[i].String() < s[j].String() }
+func (s byName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// CallSites gives the calls, go and defer statements of fn.
+func CallSites(fn *ssa2.Function) []ssa2.CallInstruction {
+	var sites []ssa2.CallInstruction
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			if site, ok := instr.(ssa2.CallInstruction); ok {
+				sites = append(sites, site)
+			}
+		}
+	}
+	return sites
+}
+
+// addressTaken gives the functions fn uses other than by calling
+// them directly: those which may be called through function values.
+func addressTaken(fn *ssa2.Function) []*ssa2.Function {
+	var fns []*ssa2.Function
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			var callee ssa2.Value
+			if site, ok := instr.(ssa2.CallInstruction); ok {
+				callee = site.Common().Value
+			}
+			var buf [10]*ssa2.Value
+			for _, op := range instr.Operands(buf[:0]) {
+				if f, ok := (*op).(*ssa2.Function); ok && *op != callee {
+					fns = append(fns, f)
+				}
+			}
+		}
+	}
+	return fns
+}
+
+// implements gives the method implementing the method of call, a
+// call through an interface, for T, or nil if T doesn't implement
+// the interface.
+func implements(prog *ssa2.Program, T types.Type, call *ssa2.CallCommon) *ssa2.Function {
+	if _, ok := T.Underlying().(*types.Interface); ok {
+		return nil
+	}
+	iface, ok := call.Value.Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil
+	}
+	if meth, _ := types.MissingMethod(T, iface, true); meth != nil {
+		return nil
+	}
+	sel := T.MethodSet().Lookup(call.Method.Pkg(), call.Method.Name())
+	if sel == nil {
+		return nil
+	}
+	return prog.Method(sel)
+}
+
+// dynamic reports whether call is of a function value rather than a
+// static call, a call through an interface or of a built-in.
+func dynamic(call *ssa2.CallCommon) bool {
+	if call.IsInvoke() || call.StaticCallee() != nil {
+		return false
+	}
+	_, builtin := call.Value.(*ssa2.Builtin)
+	return !builtin
+}
+
+// sameSignature reports whether fn may be the function value called
+// by call.
+func sameSignature(fn *ssa2.Function, call *ssa2.CallCommon) bool {
+	return fn.Signature.Recv() == nil &&
+		types.IsIdentical(fn.Signature, call.Signature())
+}
@@ -0,0 +1,172 @@
+// Copyright 2013 Rocky Bernstein.
+
+package callgraph_test
+
+import (
+	"bytes"
+	"go/parser"
+	"strings"
+	"testing"
+
+	"code.google.com/p/go.tools/importer"
+	"github.com/rocky/ssa-interp"
+	"github.com/rocky/ssa-interp/callgraph"
+)
+
+const src = `
+package main
+
+type I interface{ M() int }
+
+type A int
+
+func (a A) M() int { return 1 }
+
+type B int
+
+func (b B) M() int { return 2 }
+
+func f(x int) int { return x }
+
+func g(x int) int { return -x }
+
+func call(i I) int { return i.M() }
+
+func unused() int {
+	var i I = B(0)
+	return i.M() + g(0)
+}
+
+func main() {
+	var h func(int) int = f
+	h(call(A(0)))
+}
+`
+
+func build(t *testing.T, src string) (*ssa2.Program, *ssa2.Package) {
+	imp := importer.New(new(importer.Config))
+	file, err := parser.ParseFile(imp.Fset, "<input>", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := imp.CreatePackage("main", file)
+	prog := ssa2.NewProgram(imp.Fset, ssa2.NaiveForm)
+	if err := prog.CreatePackages(imp); err != nil {
+		t.Fatal(err)
+	}
+	prog.BuildAll()
+	return prog, prog.Package(info.Pkg)
+}
+
+// names gives the names of fns.
+func names(fns []*ssa2.Function) map[string]bool {
+	m := make(map[string]bool)
+	for _, fn := range fns {
+		m[fn.String()] = true
+	}
+	return m
+}
+
+// invokeCallees gives the callees of the call through an interface in
+// main.call.
+func invokeCallees(t *testing.T, g *callgraph.Graph, pkg *ssa2.Package) map[string]bool {
+	for _, b := range pkg.Func("call").Blocks {
+		for _, instr := range b.Instrs {
+			if site, ok := instr.(ssa2.CallInstruction); ok && site.Common().IsInvoke() {
+				return names(g.Callees(site))
+			}
+		}
+	}
+	t.Fatal("no call through an interface in main.call")
+	return nil
+}
+
+func TestCHA(t *testing.T) {
+	prog, pkg := build(t, src)
+	g := callgraph.CHA(prog)
+
+	callees := invokeCallees(t, g, pkg)
+	if !callees["(main.A).M"] || !callees["(main.B).M"] {
+		t.Errorf("CHA callees of i.M() are %v; want (main.A).M and (main.B).M", callees)
+	}
+	callers := make(map[string]bool)
+	for _, e := range g.Callers(pkg.Func("f")) {
+		callers[e.Caller.Func.String()] = true
+	}
+	if !callers["main.main"] {
+		t.Errorf("CHA callers of f are %v; want main.main", callers)
+	}
+	if len(g.Callers(pkg.Func("g"))) != 1 {
+		t.Errorf("g should only be called from main.unused")
+	}
+	// Callers' slice is ours; changing it leaves the graph alone.
+	edges := g.Callers(pkg.Func("f"))
+	edges[0] = nil
+	if g.Callers(pkg.Func("f"))[0] == nil {
+		t.Errorf("changing the slice from Callers changed the graph")
+	}
+}
+
+func TestRTA(t *testing.T) {
+	prog, pkg := build(t, src)
+	roots := []*ssa2.Function{pkg.Func("init"), pkg.Func("main")}
+	g := callgraph.RTA(prog, roots)
+
+	callees := invokeCallees(t, g, pkg)
+	if !callees["(main.A).M"] || callees["(main.B).M"] {
+		t.Errorf("RTA callees of i.M() are %v; want only (main.A).M", callees)
+	}
+	reachable := g.Reachable(roots...)
+	for _, name := range []string{"main", "call", "f"} {
+		if !reachable[pkg.Func(name)] {
+			t.Errorf("%s should be reachable from main", name)
+		}
+	}
+	for _, name := range []string{"unused", "g"} {
+		if reachable[pkg.Func(name)] {
+			t.Errorf("%s shouldn't be reachable from main", name)
+		}
+	}
+}
+
+// Tests the GraphViz output, with the styles of go and defer
+// statements and a call of a function value.
+func TestWriteDot(t *testing.T) {
+	prog, pkg := build(t, `
+package main
+
+func f(x int) int { return x }
+
+func g(x int) int { return -x }
+
+func main() {
+	defer f(1)
+	go f(2)
+	h := g
+	h(3)
+}
+`)
+	var buf bytes.Buffer
+	callgraph.CHA(prog).WriteDot(&buf, pkg)
+	calls := buf.String()
+	node := func(name string) string {
+		for _, line := range strings.Split(calls, "\n") {
+			if strings.Contains(line, `[label="`+name+`"`) {
+				return strings.Fields(line)[0]
+			}
+		}
+		t.Errorf("call graph lacks %s:\n%s", name, calls)
+		return "?"
+	}
+	main, f, g := node("main.main"), node("main.f"), node("main.g")
+	for _, want := range []string{
+		"digraph callgraph {",
+		main + " -> " + f + ` [style="dashed"];`,
+		main + " -> " + f + ` [style="dotted"];`,
+		main + " -> " + g + ` [style="solid"];`,
+	} {
+		if !strings.Contains(calls, want) {
+			t.Errorf("call graph lacks %q:\n%s", want, calls)
+		}
+	}
+}
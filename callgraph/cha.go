@@ -0,0 +1,58 @@
+// Copyright 2013 Rocky Bernstein.
+
+package callgraph
+
+import (
+	"github.com/rocky/ssa-interp"
+)
+
+// CHA gives the call graph of prog by Class Hierarchy Analysis: a
+// call through an interface may go to the method of any type with a
+// method set which implements the interface, and a call of a function
+// value to any function of its signature whose address is taken
+// anywhere in the program.
+//
+// Precondition: all packages are built.
+//
+func CHA(prog *ssa2.Program) *Graph {
+	g := newGraph()
+	fns := ssa2.AllFunctions(prog)
+
+	var addrTaken []*ssa2.Function
+	seen := make(map[*ssa2.Function]bool)
+	for fn := range fns {
+		for _, f := range addressTaken(fn) {
+			if !seen[f] {
+				seen[f] = true
+				addrTaken = append(addrTaken, f)
+			}
+		}
+	}
+	msets := prog.TypesWithMethodSets()
+
+	for fn := range fns {
+		g.node(fn)
+		for _, site := range CallSites(fn) {
+			call := site.Common()
+			switch {
+			case call.IsInvoke():
+				for _, T := range msets {
+					if meth := implements(prog, T, call); meth != nil {
+						g.addEdge(fn, site, meth)
+					}
+				}
+			case dynamic(call):
+				for _, f := range addrTaken {
+					if sameSignature(f, call) {
+						g.addEdge(fn, site, f)
+					}
+				}
+			default:
+				if callee := call.StaticCallee(); callee != nil {
+					g.addEdge(fn, site, callee)
+				}
+			}
+		}
+	}
+	return g
+}
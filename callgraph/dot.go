@@ -0,0 +1,74 @@
+// Copyright 2013 Rocky Bernstein.
+
+package callgraph
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/rocky/ssa-interp"
+)
+
+// WriteDot writes to w in AT&T GraphViz (.dot) format the part of g
+// made of the functions of pkg and their calls, or all of g if pkg is
+// nil. Calls by go statements are drawn dashed and by defer
+// statements dotted. Functions of other packages which pkg calls
+// appear as ellipses, but not their calls.
+func (g *Graph) WriteDot(w io.Writer, pkg *ssa2.Package) {
+	var fns []*ssa2.Function
+	for fn := range g.Nodes {
+		if pkg == nil || fn.Pkg == pkg {
+			fns = append(fns, fn)
+		}
+	}
+	sort.Sort(byName(fns))
+
+	nodes := make(map[*ssa2.Function]int)
+	node := func(fn *ssa2.Function) int {
+		n, ok := nodes[fn]
+		if !ok {
+			n = len(nodes)
+			nodes[fn] = n
+			shape := "box"
+			if fn.Pkg != pkg && pkg != nil {
+				shape = "ellipse"
+			}
+			fmt.Fprintf(w, "\tn%d [label=\"%s\",shape=\"%s\"];\n",
+				n, ssa2.DotEscape(fn.String()), shape)
+		}
+		return n
+	}
+
+	name := "program"
+	if pkg != nil {
+		name = pkg.Object.Path()
+	}
+	fmt.Fprintln(w, "// call graph of", name)
+	fmt.Fprintln(w, "digraph callgraph {")
+	for _, fn := range fns {
+		node(fn)
+	}
+	type dotEdge struct {
+		callee *ssa2.Function
+		style  string
+	}
+	for _, fn := range fns {
+		seen := make(map[dotEdge]bool)
+		for _, e := range g.Nodes[fn].Out {
+			style := "solid"
+			switch e.Site.(type) {
+			case *ssa2.Go:
+				style = "dashed"
+			case *ssa2.Defer:
+				style = "dotted"
+			}
+			if de := (dotEdge{e.Callee.Func, style}); !seen[de] {
+				seen[de] = true
+				fmt.Fprintf(w, "\tn%d -> n%d [style=\"%s\"];\n",
+					node(fn), node(e.Callee.Func), style)
+			}
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
@@ -0,0 +1,131 @@
+// Copyright 2013 Rocky Bernstein.
+
+package callgraph
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"code.google.com/p/go.tools/go/types/typemap"
+	"github.com/rocky/ssa-interp"
+)
+
+// RTA gives the call graph of the functions of prog reachable from
+// roots by Rapid Type Analysis: a call through an interface may only
+// go to the methods of types converted to an interface in reachable
+// code, and a call of a function value to functions of its signature
+// whose address is taken in reachable code.
+//
+// Types which are only made into interface values by reflection or by
+// external functions are missed, and so are calls of their methods.
+//
+// Precondition: all packages are built.
+//
+func RTA(prog *ssa2.Program, roots []*ssa2.Function) *Graph {
+	r := &rta{
+		prog:      prog,
+		g:         newGraph(),
+		reachable: make(map[*ssa2.Function]bool),
+		addrTaken: make(map[*ssa2.Function]bool),
+	}
+	for _, fn := range roots {
+		r.g.Roots = append(r.g.Roots, r.g.node(fn))
+		r.add(fn)
+	}
+	for len(r.worklist) > 0 {
+		fn := r.worklist[len(r.worklist)-1]
+		r.worklist = r.worklist[:len(r.worklist)-1]
+		r.visit(fn)
+	}
+	return r.g
+}
+
+// rta is the state of Rapid Type Analysis. The call sites we've seen
+// through interfaces and of function values are kept, so that they
+// get the new edges when we find another type converted to an
+// interface or function whose address is taken.
+type rta struct {
+	prog        *ssa2.Program
+	g           *Graph
+	worklist    []*ssa2.Function
+	reachable   map[*ssa2.Function]bool
+	addrTaken   map[*ssa2.Function]bool
+	addrFuncs   []*ssa2.Function // keys of addrTaken, in the order found
+	ifaceTypes  typemap.M        // types converted to interfaces
+	ifaceList   []types.Type     // keys of ifaceTypes, in the order found
+	invokeSites []ssa2.CallInstruction
+	dynSites    []ssa2.CallInstruction
+}
+
+// add makes fn reachable, adding it to the worklist if it wasn't.
+func (r *rta) add(fn *ssa2.Function) {
+	if !r.reachable[fn] {
+		r.reachable[fn] = true
+		r.g.node(fn)
+		r.worklist = append(r.worklist, fn)
+	}
+}
+
+// edge adds the edge for a call at site of callee, which is now
+// reachable.
+func (r *rta) edge(site ssa2.CallInstruction, callee *ssa2.Function) {
+	r.g.addEdge(site.Parent(), site, callee)
+	r.add(callee)
+}
+
+// visit adds the calls of fn, a reachable function, and what it makes
+// callable through interfaces and function values.
+func (r *rta) visit(fn *ssa2.Function) {
+	for _, f := range addressTaken(fn) {
+		if r.addrTaken[f] {
+			continue
+		}
+		r.addrTaken[f] = true
+		r.addrFuncs = append(r.addrFuncs, f)
+		for _, site := range r.dynSites {
+			if sameSignature(f, site.Common()) {
+				r.edge(site, f)
+			}
+		}
+	}
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			mi, ok := instr.(*ssa2.MakeInterface)
+			if !ok {
+				continue
+			}
+			T := mi.X.Type()
+			if r.ifaceTypes.At(T) != nil {
+				continue
+			}
+			r.ifaceTypes.Set(T, true)
+			r.ifaceList = append(r.ifaceList, T)
+			for _, site := range r.invokeSites {
+				if meth := implements(r.prog, T, site.Common()); meth != nil {
+					r.edge(site, meth)
+				}
+			}
+		}
+	}
+	for _, site := range CallSites(fn) {
+		call := site.Common()
+		switch {
+		case call.IsInvoke():
+			r.invokeSites = append(r.invokeSites, site)
+			for _, T := range r.ifaceList {
+				if meth := implements(r.prog, T, call); meth != nil {
+					r.edge(site, meth)
+				}
+			}
+		case dynamic(call):
+			r.dynSites = append(r.dynSites, site)
+			for _, f := range r.addrFuncs {
+				if sameSignature(f, call) {
+					r.edge(site, f)
+				}
+			}
+		default:
+			if callee := call.StaticCallee(); callee != nil {
+				r.edge(site, callee)
+			}
+		}
+	}
+}
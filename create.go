@@ -143,7 +143,6 @@ func memberFromObject(pkg *Package, obj types.Object, syntax ast.Node) {
 			Prog:      pkg.Prog,
 			Breakpoint: false,
 			Scope     : scope,
-			LocalsByName: make(map[NameScope]uint),
 		}
 		if syntax == nil {
 			fn.Synthetic = "loaded from gc object file"
@@ -243,13 +242,13 @@ func (prog *Program) CreatePackage(info *importer.PackageInfo) *Package {
 
 	scopeId := ScopeId(1)
 	AssignScopeIds(p, info.Pkg.Scope(), &scopeId)
+	setScopeNodes(p, info)
 
 	// Add init() function.
 	p.Init = &Function{
 		name:      "init",
 		Signature: new(types.Signature),
 		Synthetic: "package initializer",
-		LocalsByName: make(map[NameScope]uint),
 		Breakpoint: false,
 		Scope:     scope,
 		Pkg:       p,
@@ -317,11 +316,13 @@ func (prog *Program) CreatePackage(info *importer.PackageInfo) *Package {
 //
 func (prog *Program) CreatePackages(imp *importer.Importer) error {
 	var errpkgs []string
+	prog.imp = imp
 	for _, info := range imp.AllPackages() {
 		if info.Err != nil {
 			errpkgs = append(errpkgs, info.Pkg.Path())
 		} else {
 			prog.CreatePackage(info)
+			prog.noteModTimes(info)
 		}
 	}
 	if errpkgs != nil {
@@ -336,8 +337,14 @@ func (prog *Program) CreatePackages(imp *importer.Importer) error {
 //
 func (prog *Program) AllPackages() []*Package {
 	pkgs := make([]*Package, 0, len(prog.packages))
+	seen := make(map[*Package]bool)
 	for _, pkg := range prog.packages {
-		pkgs = append(pkgs, pkg)
+		// A reloaded package is there under each of its
+		// package objects.
+		if !seen[pkg] {
+			seen[pkg] = true
+			pkgs = append(pkgs, pkg)
+		}
 	}
 	return pkgs
 }
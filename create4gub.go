@@ -1,7 +1,11 @@
 package ssa2
 
 import (
+	"go/ast"
+	"go/token"
+
 	"code.google.com/p/go.tools/go/types"
+	"code.google.com/p/go.tools/importer"
 )
 
 func assignScopeId(typesScope *types.Scope, scopeId ScopeId) *Scope{
@@ -27,3 +31,23 @@ func AssignScopeIds(pkg *Package, typesScope *types.Scope, scopeId *ScopeId) {
 		if child != nil { AssignScopeIds(pkg, child, scopeId) }
 	}
 }
+
+// setScopeNodes records the syntax of each of pkg's scopes, so that
+// we can tell which scopes a source position is in.
+func setScopeNodes(pkg *Package, info *importer.PackageInfo) {
+	for node, typesScope := range info.Scopes {
+		if scope := pkg.TypeScope2Scope[typesScope]; scope != nil {
+			node := node
+			scope.node = &node
+		}
+	}
+}
+
+// scopeContains reports whether pos is within the syntax of scope.
+func scopeContains(scope *Scope, pos token.Pos) bool {
+	if scope.node == nil {
+		return false
+	}
+	var node ast.Node = *scope.node
+	return node.Pos() <= pos && pos < node.End()
+}
@@ -0,0 +1,100 @@
+package ssa2
+
+/*
+
+This file contains routines beyond ssa proper for writing graphs of
+the SSA form in AT&T GraphViz (.dot) format: the control-flow graph
+and the dominator tree of a function. Call graphs are written by
+package callgraph. The tortoise -build flags and the gub "graph"
+command use these.
+
+*/
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DotEscape quotes s for use inside a double-quoted .dot string.
+func DotEscape(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return strings.Replace(s, `"`, `\"`, -1)
+}
+
+// DotFileName gives the name of the .dot file for a graph of the
+// given kind, e.g. "cfg", of the function or package called name.
+// Characters that are awkward in file names are replaced.
+func DotFileName(name, kind string) string {
+	clean := strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+			return r
+		case r == '.' || r == '_' || r == '-' || r == '$':
+			return r
+		}
+		return '_'
+	}, name)
+	return clean + "." + kind + ".dot"
+}
+
+// instrLabel gives the text of instr as DumpTo shows it, without the
+// type.
+func instrLabel(instr Instruction) string {
+	if v, ok := instr.(Value); ok {
+		if name := v.Name(); name != "" {
+			return name + " = " + instr.String()
+		}
+	}
+	return instr.String()
+}
+
+// WriteCFGDot writes the control-flow graph of f to w in .dot format.
+// Each node is a basic block labeled with its instructions. current,
+// if not nil, is the block we are stopped in; it is highlighted.
+func (f *Function) WriteCFGDot(w io.Writer, current *BasicBlock) error {
+	if f.Blocks == nil {
+		return fmt.Errorf("%s is external and has no blocks", f)
+	}
+	fmt.Fprintln(w, "//", f)
+	fmt.Fprintln(w, "digraph cfg {")
+	fmt.Fprintf(w, "\tlabel=\"%s\";\n", DotEscape(f.String()))
+	fmt.Fprintln(w, "\tnode [shape=\"box\",fontname=\"Courier\"];")
+	for _, b := range f.Blocks {
+		label := DotEscape(b.String()) + `:\l`
+		for _, instr := range b.Instrs {
+			label += "  " + DotEscape(instrLabel(instr)) + `\l`
+		}
+		attrs := ""
+		if b == current {
+			attrs = ",style=\"filled\",fillcolor=\"yellow\""
+		}
+		fmt.Fprintf(w, "\tb%d [label=\"%s\"%s];\n", b.Index, label, attrs)
+	}
+	for _, b := range f.Blocks {
+		_, isIf := b.Instrs[len(b.Instrs)-1].(*If)
+		for i, succ := range b.Succs {
+			switch {
+			case isIf && i == 0:
+				fmt.Fprintf(w, "\tb%d -> b%d [label=\"true\"];\n", b.Index, succ.Index)
+			case isIf:
+				fmt.Fprintf(w, "\tb%d -> b%d [label=\"false\"];\n", b.Index, succ.Index)
+			default:
+				fmt.Fprintf(w, "\tb%d -> b%d;\n", b.Index, succ.Index)
+			}
+		}
+	}
+	fmt.Fprintln(w, "}")
+	return nil
+}
+
+// WriteDomTreeDot writes the dominator tree of f to w in .dot format,
+// along with the control-flow edges.
+func (f *Function) WriteDomTreeDot(w io.Writer) error {
+	if f.Blocks == nil {
+		return fmt.Errorf("%s is external and has no blocks", f)
+	}
+	f.domTree()
+	printDomTreeDot(w, f)
+	return nil
+}
@@ -0,0 +1,78 @@
+package ssa2_test
+
+import (
+	"bytes"
+	"go/parser"
+	"strings"
+	"testing"
+
+	"code.google.com/p/go.tools/importer"
+	"github.com/rocky/ssa-interp"
+)
+
+// Tests the GraphViz output for control-flow graphs and dominator
+// trees.
+func TestDot(t *testing.T) {
+	test := `
+package main
+
+type I interface{ M() int }
+
+type T int
+
+func (t T) M() int { return int(t) }
+
+func f(x int) int {
+	if x > 0 {
+		return x
+	}
+	return -x
+}
+
+func main() {
+	var i I = T(1)
+	defer f(i.M())
+	go f(2)
+}
+`
+	imp := importer.New(new(importer.Config))
+	file, err := parser.ParseFile(imp.Fset, "<input>", test, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mainInfo := imp.CreatePackage("main", file)
+
+	prog := ssa2.NewProgram(imp.Fset, ssa2.NaiveForm)
+	if err := prog.CreatePackages(imp); err != nil {
+		t.Fatal(err)
+	}
+	prog.BuildAll()
+	mainPkg := prog.Package(mainInfo.Pkg)
+	f := mainPkg.Func("f")
+
+	var buf bytes.Buffer
+	if err := f.WriteCFGDot(&buf, f.Blocks[0]); err != nil {
+		t.Fatal(err)
+	}
+	cfg := buf.String()
+	for _, want := range []string{
+		"digraph cfg {",
+		`b0 [label="0.entry:\l`,
+		`fillcolor="yellow"`,
+		`[label="true"];`,
+		`[label="false"];`,
+	} {
+		if !strings.Contains(cfg, want) {
+			t.Errorf("CFG of f lacks %q:\n%s", want, cfg)
+		}
+	}
+
+	buf.Reset()
+	if err := f.WriteDomTreeDot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if dom := buf.String(); !strings.Contains(dom, "digraph domtree {") ||
+		!strings.Contains(dom, `[style="solid",weight=100]`) {
+		t.Errorf("bad dominator tree of f:\n%s", dom)
+	}
+}
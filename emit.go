@@ -281,7 +281,8 @@ func emitIf(f *Function, cond Value, tblock, fblock *BasicBlock) {
 // So instead we make it it's own instruction.
 
 func emitTrace(f *Function, event TraceEvent, start token.Pos, end token.Pos) Value {
-	t := &Trace{Event: event, Start: start, End: end, Breakpoint: false}
+	t := &Trace{Event: event, Start: start, End: end, Breakpoint: false,
+		Scope: f.ScopeAt(start)}
 	// fmt.Printf("event %s StartPos %d EndPos %d\n", Event2Name[event])
 	fset := f.Prog.Fset
 	pkg := f.Pkg
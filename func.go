@@ -205,11 +205,6 @@ func (f *Function) addSpilledParam(obj types.Object) {
 	spill.Scope = f.Scope
 	f.objects[obj] = spill
 	f.Locals = append(f.Locals, spill)
-	nameScope := NameScope{
-		Name: obj.Name(),
-		Scope: f.Scope,
-	}
-	f.LocalsByName[nameScope] = uint(len(f.Locals))
 	f.emit(spill)
 	f.emit(&Store{Addr: spill, Val: param})
 }
@@ -334,6 +329,7 @@ func (f *Function) finishBody() {
 	f.Locals = f.Locals[:j]
 
 	optimizeBlocks(f)
+	f.panicBlocks = nil
 
 	buildReferrers(f)
 
@@ -400,12 +396,8 @@ func (f *Function) addNamedLocal(obj types.Object) *Alloc {
 	l := f.addLocal(obj.Type(), obj.Pos(), obj.Pos(), nil)
 	l.Comment = obj.Name()
 	l.Scope = f.Pkg.TypeScope2Scope[obj.Parent()]
+	l.setLiveRange(obj, f)
 	f.objects[obj] = l
-	nameScope := NameScope{
-		Name: obj.Name(),
-		Scope: l.Scope,
-	}
-	f.LocalsByName[nameScope] = uint(len(f.Locals))
 	return l
 }
 
@@ -695,7 +687,6 @@ func NewFunction(name string, sig *types.Signature, provenance string) *Function
 		Synthetic: provenance,
 		Breakpoint: false,
 		Scope: nil,
-		LocalsByName: make(map[NameScope]uint),
 	}
 }
 
@@ -1,6 +1,11 @@
 package ssa2
 
-import "fmt"
+import (
+	"fmt"
+	"go/token"
+
+	"code.google.com/p/go.tools/go/types"
+)
 
 /*
 
@@ -12,6 +17,74 @@ alike.
 */
 
 
+// ScopeAt returns the innermost scope of f that pos is in, or nil if
+// f has no scope information.
+func (f *Function) ScopeAt(pos token.Pos) *Scope {
+	scope := f.Scope
+	if scope == nil || !pos.IsValid() {
+		return scope
+	}
+outer:
+	for {
+		for i, n := 0, scope.NumChildren(); i < n; i++ {
+			child := f.Pkg.TypeScope2Scope[scope.Child(i)]
+			if child != nil && scopeContains(child, pos) {
+				scope = child
+				continue outer
+			}
+		}
+		return scope
+	}
+}
+
+// LookupObject finds what name refers to at pos in scope, a scope of
+// f or of a function enclosing it, as the Go compiler would: in scope,
+// or else in the scopes enclosing it, but skipping names declared in
+// a function after pos. It returns the object and the scope it is
+// declared in, or nil if there is none.
+func (f *Function) LookupObject(name string, scope *Scope, pos token.Pos) (types.Object, *Scope) {
+	for ; scope != nil; scope = ParentScope(f, scope) {
+		obj := scope.Lookup(name)
+		if obj == nil {
+			continue
+		}
+		// The package scope is the one just inside the universe;
+		// after a Reload, it needn't be f.Pkg.Object's.
+		isPkgScope := scope.Parent() == types.Universe
+		if !isPkgScope && pos.IsValid() && obj.Pos() >= pos {
+			// Declared later on; the name isn't in scope yet.
+			continue
+		}
+		return obj, scope
+	}
+	return nil, nil
+}
+
+// VarValue gives the SSA value in f for local variable obj, found by
+// LookupObject: an *Alloc for a variable with an address, a *Parameter,
+// or a *Capture for a variable of an enclosing function. It returns
+// nil if there is none, as for a local variable that has been lifted
+// into registers; a DebugRef for obj then gives its current value.
+func (f *Function) VarValue(obj types.Object) Value {
+	pos := obj.Pos()
+	for _, l := range f.Locals {
+		if l.Pos() == pos && l.Comment == obj.Name() {
+			return l
+		}
+	}
+	for _, p := range f.Params {
+		if p.Pos() == pos && p.Name() == obj.Name() {
+			return p
+		}
+	}
+	for _, fv := range f.FreeVars {
+		if fv.Pos() == pos && fv.Name() == obj.Name() {
+			return fv
+		}
+	}
+	return nil
+}
+
 // Return the starting position of function f or "-" if no position found
 func (f *Function) Position() string {
 	if pos := f.Pos(); pos.IsValid() {
@@ -93,3 +166,60 @@ func DisasmInst(instr Instruction, width int) string {
 	}
 	return s
 }
+
+// A DeadStmt is a statement which can't be reached because it comes
+// after a call of panic. Its code is deleted from the function.
+type DeadStmt struct {
+	Panic *Panic // the panic before it
+	Trace *Trace // the statement's trace instruction
+}
+
+// DeadStmts gives the statements of f which come after a call of
+// panic and so are never run, in the order of their blocks.
+func (f *Function) DeadStmts() []DeadStmt { return f.deadStmts }
+
+// notePanicBlock records that b is the block begun after p, to be
+// deleted if nothing jumps there.
+func (f *Function) notePanicBlock(b *BasicBlock, p *Panic) {
+	if f.panicBlocks == nil {
+		f.panicBlocks = make(map[*BasicBlock]*Panic)
+	}
+	f.panicBlocks[b] = p
+}
+
+// noteDeadStmt records the first statement of b, an unreachable block
+// about to be deleted, if b is the one begun after a panic.
+func (f *Function) noteDeadStmt(b *BasicBlock) {
+	p := f.panicBlocks[b]
+	if p == nil {
+		return
+	}
+	for _, instr := range b.Instrs {
+		if t, ok := instr.(*Trace); ok && t.Event != BLOCK_END {
+			f.deadStmts = append(f.deadStmts, DeadStmt{p, t})
+			return
+		}
+	}
+}
+
+// domTree makes sure f, which must have blocks, has its dominator
+// tree. Lifting builds it, but functions built in naive form aren't
+// lifted, so for them we build it here the first time it's needed.
+func (f *Function) domTree() {
+	if f.Blocks[0].dom == nil {
+		buildDomTree(f)
+	}
+}
+
+// Dominates reports whether b dominates c, a block of the same
+// function: whether every path from the function's entry to c goes
+// through b. The Recover block, which isn't reached from the entry,
+// dominates only itself.
+func (b *BasicBlock) Dominates(c *BasicBlock) bool {
+	f := b.parent
+	if b == f.Recover || c == f.Recover {
+		return b == c
+	}
+	f.domTree()
+	return dominates(b, c)
+}
@@ -63,7 +63,6 @@ if [[ -n $highlight_opt ]] ; then
     fi
 fi
 cmd="$tortoise -run -gub="$gub_opt" -interp="S$interp_opt" -- $@"
-export GUB_RESTART_CMD="$cmd"
 # Run tortoise setting
 # echo $cmd
 $cmd
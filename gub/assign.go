@@ -0,0 +1,112 @@
+// Copyright 2013 Rocky Bernstein.
+// Assignment to variables of the program being debugged, as in
+// "set var x.f = 5".
+package gub
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"strings"
+
+	"code.google.com/p/go.tools/go/types"
+	"github.com/rocky/ssa-interp"
+	"github.com/rocky/ssa-interp/interp"
+)
+
+// splitAssignment splits "lhs = rhs" at its assignment operator,
+// skipping ==, !=, <= and >= and anything inside quotes.
+func splitAssignment(stmt string) (lhs, rhs string, err error) {
+	var quote byte
+	for i := 0; i < len(stmt); i++ {
+		c := stmt[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '=':
+			if i+1 < len(stmt) && stmt[i+1] == '=' {
+				i++
+				continue
+			}
+			if i > 0 && strings.ContainsRune("!<>=:", rune(stmt[i-1])) {
+				continue
+			}
+			lhs = strings.TrimSpace(stmt[:i])
+			rhs = strings.TrimSpace(stmt[i+1:])
+			if lhs == "" || rhs == "" {
+				break
+			}
+			return lhs, rhs, nil
+		}
+	}
+	return "", "", errors.New("expecting an assignment: lvalue = expression")
+}
+
+// Assign evaluates stmt, an assignment "lvalue = expr", in the frame
+// we are focused on and returns the new value. lvalue may be a local
+// or global variable, a field, or an element of an array, slice or
+// map, and expr must be assignable to its type; constants are
+// converted as in Go. Locals the compiler lifted into SSA registers
+// can't be assigned to.
+func Assign(stmt string) (interp.TypedValue, error) {
+	lhsSrc, rhsSrc, err := splitAssignment(stmt)
+	if err != nil {
+		return interp.TypedValue{}, err
+	}
+	lhs, err := parser.ParseExpr(lhsSrc)
+	if err != nil {
+		return interp.TypedValue{}, err
+	}
+	rhs, err := parser.ParseExpr(rhsSrc)
+	if err != nil {
+		return interp.TypedValue{}, err
+	}
+	x, err := evalOperand(rhsSrc, rhs)
+	if err != nil {
+		return interp.TypedValue{}, err
+	}
+
+	// Most locals live in SSA registers rather than in variables.
+	// Such a lifted variable may be in several registers, and setting
+	// only the one it was last in would half-apply the assignment,
+	// so we refuse. An SSA register named directly, like t0, is set.
+	if id, ok := lhs.(*ast.Ident); ok {
+		nameVal, interpVal, _ := EnvLookup(curFrame, id.Name, curScope)
+		// An Alloc or a closure's Capture holds the variable's
+		// address, which EvalLvalue assigns through.
+		var isVariable bool
+		switch nameVal.(type) {
+		case *ssa2.Alloc, *ssa2.Capture:
+			isVariable = true
+		}
+		if nameVal != nil && interpVal != nil && !isVariable {
+			fn := curFrame.Fn()
+			if obj, _ := fn.LookupObject(id.Name, curScope, curFrame.StartP()); obj != nil {
+				if _, isVar := obj.(*types.Var); isVar {
+					return interp.TypedValue{}, fmt.Errorf(
+						"can't assign to %s: it was lifted into SSA registers, not kept in a variable",
+						id.Name)
+				}
+			}
+			v, err := interp.ConvertValue(x, nameVal.Type())
+			if err != nil {
+				return interp.TypedValue{}, err
+			}
+			curFrame.Env()[nameVal] = v
+			return interp.TypedValue{Type: nameVal.Type(), Value: v}, nil
+		}
+	}
+
+	tv, err := EvalLvalue(lhsSrc, lhs)
+	if err != nil {
+		return interp.TypedValue{}, err
+	}
+	return tv.Assign(x)
+}
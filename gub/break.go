@@ -2,14 +2,16 @@ package gub
 
 import (
 	"go/token"
+	"reflect"
 	"github.com/rocky/ssa-interp"
+	"github.com/rocky/ssa-interp/interp"
 	"fmt"
 )
 
 type BpId uint64
 
 type Breakpoint struct {
-	// condition
+	Condition string  // Go expression which must be true to stop, if not empty
 	Hits    int       // How many times hit (with a true condition)
 	Id      BpId      // Id of breakpoint. Is position inside of Breakpoints
 	Deleted bool      // Set when breakpoint is deleted
@@ -19,7 +21,11 @@ type Breakpoint struct {
 	EndP    token.Pos // End Position of breakpoint
 	Ignore  int       // Number of times to ignore before triggering
 	Kind    string    // 'Function' if function breakpoint. 'Stmt'
-	                  // if at a statement boundary
+	                  // if at a statement boundary, 'Catch' if a
+	                  // catchpoint
+	Event   ssa2.TraceEvent // Event a catchpoint stops at
+	Type    string    // For a panic catchpoint, the type of panic
+	                  // value to stop at, if not all
 }
 
 var Breakpoints []*Breakpoint
@@ -39,7 +45,9 @@ var BrkptsDeleted = 0
 
 func BreakpointAdd(bp *Breakpoint) BpId {
 	Breakpoints = append(Breakpoints, bp)
-	BrkptLocs = append(BrkptLocs, toknum{pos: bp.Pos, bpnum: bp.Id})
+	if bp.Kind != "Catch" {
+		BrkptLocs = append(BrkptLocs, toknum{pos: bp.Pos, bpnum: bp.Id})
+	}
 	return BpId(len(Breakpoints)-1)
 }
 
@@ -84,6 +92,60 @@ func BreakpointFindByPos(pos token.Pos) []BpId {
 	return results
 }
 
+// GetBpnum parses arg as the number of a breakpoint. "$bpnum" is the
+// breakpoint set last, which the scripts "save" writes use.
+func GetBpnum(arg string, what string) (BpId, error) {
+	if arg == "$bpnum" {
+		if IsBreakpointEmpty() {
+			Errmsg("No breakpoint has been set yet")
+			return 0, genericError
+		}
+		return BpId(len(Breakpoints)-1), nil
+	}
+	val, err := GetUInt(arg, what, 0, uint64(len(Breakpoints)-1))
+	return BpId(val), err
+}
+
+// stops reports whether the program, which has reached bp in frame
+// fr, should stop there: bp must be enabled, its condition, if any,
+// must hold, and its ignore count must have run out. Hits counts the
+// times the condition held.
+func (bp *Breakpoint) stops(fr *interp.Frame) bool {
+	if !bp.Enabled || !condHolds(bp.Condition, fr) {
+		return false
+	}
+	bp.Hits++
+	if bp.Ignore > 0 {
+		bp.Ignore--
+		return false
+	}
+	return true
+}
+
+// condHolds evaluates the breakpoint condition cond in frame fr. A
+// condition that can't be evaluated, or isn't a boolean, counts as
+// true, so that we stop and the user sees what is wrong.
+func condHolds(cond string, fr *interp.Frame) bool {
+	if cond == "" {
+		return true
+	}
+	saved := saveStopState()
+	defer saved.restore()
+	frameInit(fr)
+	results, err := EvalExpr(cond)
+	if err != nil || results == nil || len(*results) != 1 {
+		return true
+	}
+	v := (*results)[0]
+	if !v.IsValid() || !v.CanInterface() {
+		return true
+	}
+	if tv, ok := v.Interface().(interp.TypedValue); ok {
+		v = reflect.ValueOf(tv.Value)
+	}
+	return v.Kind() != reflect.Bool || v.Bool()
+}
+
 func BreakpointIsEnabled(bpnum BpId) bool {
 	if BreakpointExists(bpnum) {
 		return Breakpoints[bpnum].Enabled
@@ -109,10 +171,14 @@ func Bpprint(bp Breakpoint) {
 	enabled := "n "
 	if bp.Enabled { enabled = "y " }
 
-	loc  := ssa2.FmtRange(curFrame.Fn(), bp.Pos, bp.EndP)
-    mess := fmt.Sprintf("%3d breakpoint    %s  %sat %s",
-		bp.Id, disp, enabled, loc)
-	Msg(mess)
+	if bp.Kind == "Catch" {
+		Msg("%3d catchpoint    %s  %s%s", bp.Id, disp, enabled, catchWhat(bp))
+	} else {
+		loc  := ssa2.FmtRange(curFrame.Fn(), bp.Pos, bp.EndP)
+		mess := fmt.Sprintf("%3d breakpoint    %s  %sat %s",
+			bp.Id, disp, enabled, loc)
+		Msg(mess)
+	}
 
     // line_loc = '%s:%d' %
     //   [iseq.source_container.join(' '),
@@ -127,17 +193,18 @@ func Bpprint(bp Breakpoint) {
     // Msg(mess + loc)
     // Msg("\t#{other_loc}") if verbose
 
-    // if bp.condition && bp.condition != 'true'
-    //   Msg("\tstop %s %s" %
-    //       [bp.negate ? "unless" : "only if", bp.condition])
-    // end
+    if bp.Condition != "" {
+		Msg("\tstop only if %s", bp.Condition)
+	}
     if bp.Ignore > 0 {
 		Msg("\tignore next %d hits", bp.Ignore)
 	}
     if bp.Hits > 0 {
 		ss := ""
 		if bp.Hits > 1 { ss = "s" }
-		Msg("\tbreakpoint already hit %d time%s",
-			bp.Hits, ss)
+		what := "breakpoint"
+		if bp.Kind == "Catch" { what = "catchpoint" }
+		Msg("\t%s already hit %d time%s",
+			what, bp.Hits, ss)
 	}
 }
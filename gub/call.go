@@ -0,0 +1,86 @@
+// Copyright 2013 Rocky Bernstein.
+// Calling interpreted functions from the debugger, as in "call f(x)"
+// or "eval obj.Method()".
+package gub
+
+import (
+	"errors"
+
+	"code.google.com/p/go.tools/go/types"
+	"github.com/rocky/ssa-interp"
+	"github.com/rocky/ssa-interp/interp"
+)
+
+// CallBreakpoints says whether breakpoints inside functions called
+// from the debugger stop there. It is set by "set callbreak".
+var CallBreakpoints = false
+
+// callDepth is the number of debugger calls in progress, and callGoNum
+// the goroutine they run on behalf of.
+var callDepth int
+var callGoNum int
+
+// inDebuggerCall reports whether a trace event for fr comes from a
+// function called by the debugger rather than from the program
+// itself.
+func inDebuggerCall(fr *interp.Frame) bool {
+	return callDepth > 0 && fr.GoNum() == callGoNum
+}
+
+// stopState is what we know about where the program is stopped. A
+// breakpoint hit inside a call made from the debugger replaces it, so
+// it is saved around the call.
+type stopState struct {
+	topFrame, curFrame    *interp.Frame
+	curScope              *ssa2.Scope
+	stackSize, frameIndex int
+	traceEvent            ssa2.TraceEvent
+	curBpnum              BpId
+}
+
+func saveStopState() stopState {
+	return stopState{topFrame, curFrame, curScope, stackSize, frameIndex,
+		TraceEvent, curBpnum}
+}
+
+func (s stopState) restore() {
+	topFrame, curFrame, curScope = s.topFrame, s.curFrame, s.curScope
+	stackSize, frameIndex = s.stackSize, s.frameIndex
+	TraceEvent, curBpnum = s.traceEvent, s.curBpnum
+	// A "continue" at a breakpoint inside the call ended that
+	// command loop, not ours.
+	InCmdLoop = true
+}
+
+// CallFunction calls interpreted function fn, of function type, with
+// args on behalf of the frame we are focused on, and returns its
+// results. If the call panics, the error says so, and we remain
+// stopped where we were.
+func CallFunction(fn interp.TypedValue, args []interp.Value) ([]interp.TypedValue, error) {
+	sig, ok := fn.Type.Underlying().(*types.Signature)
+	if !ok {
+		return nil, errors.New("cannot call non-function of type " + fn.Type.String())
+	}
+	saved := saveStopState()
+	callDepth++
+	callGoNum = curFrame.GoNum()
+	result, err := interp.CallFunction(curFrame, fn.Value, args)
+	callDepth--
+	saved.restore()
+	if err != nil {
+		return nil, err
+	}
+	results := sig.Results()
+	switch results.Len() {
+	case 0:
+		return nil, nil
+	case 1:
+		return []interp.TypedValue{{Type: results.At(0).Type(), Value: result}}, nil
+	}
+	tuple := interp.TupleValues(result)
+	values := make([]interp.TypedValue, results.Len())
+	for i := range values {
+		values[i] = interp.TypedValue{Type: results.At(i).Type(), Value: tuple[i]}
+	}
+	return values, nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2013 Rocky Bernstein.
+// Static call graph queries for "info callers" and "info callees".
+package gub
+
+import (
+	"sort"
+
+	"github.com/rocky/ssa-interp"
+	"github.com/rocky/ssa-interp/callgraph"
+)
+
+// The call graph of the program, built the first time it is needed.
+var callGraph *callgraph.Graph
+
+// CallGraph gives the static call graph of the program we are
+// debugging. It comes from Rapid Type Analysis starting at the init
+// and main functions of the main packages, or if there are none, from
+// Class Hierarchy Analysis of the whole program.
+func CallGraph() *callgraph.Graph {
+	if callGraph != nil {
+		return callGraph
+	}
+	prog := curFrame.I().Program()
+	roots := mainRoots(prog)
+	if roots == nil {
+		callGraph = callgraph.CHA(prog)
+	} else {
+		callGraph = callgraph.RTA(prog, roots)
+	}
+	return callGraph
+}
+
+// mainRoots gives the init and main functions of the main packages of
+// prog, from which static analyses start.
+func mainRoots(prog *ssa2.Program) []*ssa2.Function {
+	var roots []*ssa2.Function
+	for _, pkg := range prog.AllPackages() {
+		if pkg.Object.Name() != "main" || pkg.Func("main") == nil {
+			continue
+		}
+		roots = append(roots, pkg.Func("init"), pkg.Func("main"))
+	}
+	return roots
+}
+
+// byCallSite sorts call graph edges by caller, then by the position of
+// the call.
+type byCallSite []*callgraph.Edge
+
+func (s byCallSite) Len() int      { return len(s) }
+func (s byCallSite) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byCallSite) Less(i, j int) bool {
+	if a, b := s[i].Caller.Func.String(), s[j].Caller.Func.String(); a != b {
+		return a < b
+	}
+	return s[i].Site.Pos() < s[j].Site.Pos()
+}
+
+// sitePosition gives the source position of the call at site, or "?"
+// if there is none.
+func sitePosition(site ssa2.CallInstruction) string {
+	position := site.Parent().Prog.Fset.Position(site.Pos())
+	if !position.IsValid() {
+		return "?"
+	}
+	return position.String()
+}
+
+// PrintCallers shows the calls of fn the call graph has.
+func PrintCallers(fn *ssa2.Function) {
+	edges := CallGraph().Callers(fn)
+	if len(edges) == 0 {
+		Msg("No calls of %s found", fn)
+		return
+	}
+	sort.Sort(byCallSite(edges))
+	Section("callers of %s", fn)
+	for _, e := range edges {
+		Msg("  %s at %s", e.Caller.Func, sitePosition(e.Site))
+	}
+}
+
+// PrintCallees shows the functions which each of sites may call.
+// Calls of built-in functions are left out.
+func PrintCallees(sites []ssa2.CallInstruction) {
+	g := CallGraph()
+	shown := false
+	for _, site := range sites {
+		if _, ok := site.Common().Value.(*ssa2.Builtin); ok {
+			continue
+		}
+		shown = true
+		Section("%s at %s", site.Common().Description(), sitePosition(site))
+		callees := g.Callees(site)
+		if len(callees) == 0 {
+			Msg("  no callees found")
+		}
+		for _, callee := range callees {
+			Msg("  %s", callee)
+		}
+	}
+	if !shown {
+		Msg("No calls found")
+	}
+}
+
+// StmtCallSites gives the calls of the statement the current frame is
+// stopped at: those from the instruction we are at up to the next
+// statement of the block.
+func StmtCallSites() []ssa2.CallInstruction {
+	fr := curFrame
+	b := fr.Block()
+	if b == nil {
+		return nil
+	}
+	var sites []ssa2.CallInstruction
+	for i := int(fr.PC()); i < len(b.Instrs); i++ {
+		instr := b.Instrs[i]
+		if _, ok := instr.(*ssa2.Trace); ok && i != int(fr.PC()) {
+			break
+		}
+		if site, ok := instr.(ssa2.CallInstruction); ok {
+			sites = append(sites, site)
+		}
+	}
+	return sites
+}
@@ -0,0 +1,99 @@
+// Copyright 2013 Rocky Bernstein.
+// Catchpoints: stopping at panics, recovers, and goroutines starting
+// or exiting, rather than at a place in the program.
+package gub
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"github.com/rocky/ssa-interp"
+	"github.com/rocky/ssa-interp/interp"
+)
+
+// CatchEvents are the events "catch" can stop at, by the name it
+// gives them.
+var CatchEvents = map[string]ssa2.TraceEvent{
+	"panic":   ssa2.PANIC,
+	"recover": ssa2.RECOVER,
+	"go":      ssa2.GO_START,
+	"exit":    ssa2.GO_EXIT,
+}
+
+// CatchpointAdd adds a catchpoint for the event "catch" calls what.
+// For panics, typ, if not empty, is the type of panic value to stop
+// at.
+func CatchpointAdd(what string, typ string) BpId {
+	bp := &Breakpoint{
+		Hits:    0,
+		Id:      BreakpointNext(),
+		Ignore:  0,
+		Kind:    "Catch",
+		Event:   CatchEvents[what],
+		Type:    typ,
+		Temp:    false,
+		Enabled: true,
+	}
+	return BreakpointAdd(bp)
+}
+
+// catchWhat describes what catchpoint bp stops at, as "info
+// breakpoints" shows it.
+func catchWhat(bp Breakpoint) string {
+	for what, event := range CatchEvents {
+		if event == bp.Event {
+			if bp.Type != "" {
+				return what + " " + bp.Type
+			}
+			return what
+		}
+	}
+	return ssa2.Event2Name[bp.Event]
+}
+
+// isCatchEvent reports whether event is one only catchpoints stop at.
+func isCatchEvent(event ssa2.TraceEvent) bool {
+	for _, e := range CatchEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// catchpointHit returns the number of a catchpoint for event in frame
+// fr which stops the program, if there is one.
+func catchpointHit(fr *interp.Frame, event ssa2.TraceEvent) (BpId, bool) {
+	for _, bp := range Breakpoints {
+		if bp.Deleted || bp.Kind != "Catch" || bp.Event != event {
+			continue
+		}
+		if bp.Type != "" && !panicTypeMatches(fr, bp.Type) {
+			continue
+		}
+		if !bp.stops(fr) {
+			continue
+		}
+		return bp.Id, true
+	}
+	return NoBp, false
+}
+
+// panicTypeMatches reports whether the value of the panic in fr has
+// type typ, given either in full, as in "main.MyError", or without
+// its package, as in "MyError".
+func panicTypeMatches(fr *interp.Frame, typ string) bool {
+	tv, ok := fr.PanicValue()
+	if !ok {
+		return false
+	}
+	dv, ok := tv.DynamicValue()
+	if !ok {
+		return false
+	}
+	if dv.Type.String() == typ {
+		return true
+	}
+	if named, ok := dv.Type.(*types.Named); ok {
+		return named.Obj().Name() == typ
+	}
+	return false
+}
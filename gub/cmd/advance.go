@@ -0,0 +1,43 @@
+// Copyright 2013 Rocky Bernstein.
+
+package gubcmd
+
+import (
+	"github.com/rocky/ssa-interp/gub"
+	"github.com/rocky/ssa-interp/interp"
+)
+
+func init() {
+	name := "advance"
+	gub.Cmds[name] = &gub.CmdInfo{
+		Fn: AdvanceCommand,
+		Help: `advance [*fn* | line [column]]
+
+Continue until the location is reached, as if there were a breakpoint
+there which is removed the next time we stop. The location is given as
+for "break". We also stop when the current function returns.
+
+Examples:
+   advance 30        # run to line 30
+   advance main.gcd  # run to the start of function gcd
+
+See also 'until' and 'break'.
+`,
+		Min_args: 1,
+		Max_args: 2,
+	}
+	gub.AddToCategory("running", name)
+}
+
+func AdvanceCommand(args []string) {
+	if err := gub.SetAdvance(args); err != nil {
+		gub.Errmsg("%s", err)
+		return
+	}
+	for fr := gub.TopFrame(); fr != nil; fr = fr.Caller(0) {
+		interp.SetStepOff(fr)
+	}
+	interp.SetStepOut(gub.TopFrame())
+	gub.Msg("Advancing...")
+	gub.InCmdLoop = false
+}
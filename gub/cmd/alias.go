@@ -0,0 +1,62 @@
+// Copyright 2013 Rocky Bernstein.
+// Debugger alias command
+package gubcmd
+
+import (
+	"sort"
+
+	"github.com/rocky/ssa-interp/gub"
+)
+
+func init() {
+	name := "alias"
+	gub.Cmds[name] = &gub.CmdInfo{
+		Fn: AliasCommand,
+		Help: `alias [*name* [*command*]]
+
+Make *name* another name for debugger command *command*. With just a
+name, show the command it stands for; without arguments, show all
+aliases.
+
+Aliases added this way are kept by "save session".
+
+Examples:
+   alias s step
+   alias s
+`,
+		Min_args: 0,
+		Max_args: 2,
+	}
+	gub.AddToCategory("support", name)
+}
+
+func AliasCommand(args []string) {
+	switch len(args) {
+	case 1:
+		var names []string
+		for alias := range gub.Aliases {
+			names = append(names, alias)
+		}
+		sort.Strings(names)
+		for _, alias := range names {
+			gub.Msg("%-10s -- %s", alias, gub.Aliases[alias])
+		}
+	case 2:
+		if cmdname := gub.Aliases[args[1]]; cmdname != "" {
+			gub.Msg("%s is an alias for %s", args[1], cmdname)
+		} else {
+			gub.Errmsg("%s is not an alias", args[1])
+		}
+	default:
+		alias, cmdname := args[1], gub.LookupCmd(args[2])
+		if gub.Cmds[cmdname] == nil {
+			gub.Errmsg("%s is not a debugger command", args[2])
+		} else if gub.Cmds[alias] != nil {
+			gub.Errmsg("%s is already a debugger command", alias)
+		} else if !gub.AddUserAlias(alias, cmdname) {
+			gub.Errmsg("%s is already an alias for %s", alias, gub.Aliases[alias])
+		} else {
+			gub.Msg("Alias %s added for %s", alias, cmdname)
+		}
+	}
+}
@@ -4,6 +4,7 @@ package gubcmd
 
 import (
 	"strconv"
+	"strings"
 	"github.com/rocky/ssa-interp"
 	"github.com/rocky/ssa-interp/interp"
 	"github.com/rocky/ssa-interp/gub"
@@ -13,12 +14,13 @@ func init() {
 	name := "breakpoint"
 	gub.Cmds[name] = &gub.CmdInfo{
 		Fn: BreakpointCommand,
-		Help: `breakpoint [*fn* | line [column]]
+		Help: `breakpoint [*fn* | [*file*:]line [column]]
 
 Set a breakpoint. The target can either be a function name as fn pkg.fn
 or a line and and optional column number. Specifying a column number
 may be useful if there is more than one statement on a line or if you
-want to distinguish parts of a compound statement`,
+want to distinguish parts of a compound statement. The line is in the
+current file unless a file name is given, as in "gcd.go:16".`,
 
 		Min_args: 0,
 		Max_args: 2,
@@ -56,7 +58,11 @@ func BreakpointCommand(args []string) {
 			ssa2.FmtRange(fn, fn.Pos(), fn.EndP()))
 		return
 	}
-	line, ok := strconv.Atoi(args[1])
+	filename, lineArg := "", args[1]
+	if i := strings.LastIndex(lineArg, ":"); i >= 0 {
+		filename, lineArg = lineArg[:i], lineArg[i+1:]
+	}
+	line, ok := strconv.Atoi(lineArg)
 	if ok != nil {
 		gub.Errmsg("Don't know yet how to deal with a break that doesn't start with a function or integer")
 		return
@@ -74,10 +80,21 @@ func BreakpointCommand(args []string) {
 
 	fn = gub.CurFrame().Fn()
 	fset := gub.CurFrame().Fset()
-	position := gub.CurFrame().Position()
-	if position.IsValid() {
-		filename := position.Filename
-		for _, l := range fn.Pkg.Locs() {
+	pkgs := []*ssa2.Package{fn.Pkg}
+	if filename == "" {
+		position := gub.CurFrame().Position()
+		if !position.IsValid() {
+			return
+		}
+		filename = position.Filename
+	} else {
+		// Saved sessions name files absolutely; the FileSet has
+		// the names tortoise was given.
+		filename = gub.FindSourceFile(filename)
+		pkgs = gub.CurFrame().I().Program().AllPackages()
+	}
+	for _, pkg := range pkgs {
+		for _, l := range pkg.Locs() {
 			try := fset.Position(l.Pos())
 			if try.Filename == filename && line == try.Line {
 				if column == -1 || column == try.Column {
@@ -107,8 +124,8 @@ func BreakpointCommand(args []string) {
 				}
 			}
 		}
-		suffix := ""
-		if column != -1 { suffix = ", column " + args[2] }
-		gub.Errmsg("Can't find statement in file %s at line %d%s", filename, line, suffix)
 	}
+	suffix := ""
+	if column != -1 { suffix = ", column " + args[2] }
+	gub.Errmsg("Can't find statement in file %s at line %d%s", filename, line, suffix)
 }
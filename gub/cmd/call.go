@@ -0,0 +1,40 @@
+// Copyright 2013 Rocky Bernstein.
+package gubcmd
+
+import (
+	"github.com/rocky/ssa-interp/gub"
+)
+
+func init() {
+	name := "call"
+	gub.Cmds[name] = &gub.CmdInfo{
+		Fn: CallCommand,
+		Help: `call *fn*(*args*...)
+
+Call function *fn* of the program with arguments *args* and show
+what it returns. *fn* may also be a method, e.g. "call p.Reset()", or
+a variable holding a function.
+
+The call runs in a new frame on top of the frame we are stopped in,
+which is left unchanged. Breakpoints inside the call are ignored
+unless "set callbreak" is on. If the call panics, the panic unwinds
+only the frames of the call, and is reported.
+
+Unlike "eval", nothing is shown for a function without results.
+`,
+
+		Min_args: 1,
+		Max_args: -1,
+	}
+	gub.AddToCategory("data", name)
+}
+
+func CallCommand(args []string) {
+	if expr, err := gub.EvalExpr(gub.CmdArgstr); err == nil {
+		if expr == nil {
+			gub.Msg("nil")
+		} else if len(*expr) > 0 {
+			printResults(*expr)
+		}
+	}
+}
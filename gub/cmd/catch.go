@@ -0,0 +1,58 @@
+// Copyright 2013 Rocky Bernstein.
+// Debugger catch command
+package gubcmd
+
+import (
+	"github.com/rocky/ssa-interp/gub"
+)
+
+func init() {
+	name := "catch"
+	gub.Cmds[name] = &gub.CmdInfo{
+		Fn: CatchCommand,
+		Help: `catch panic [*type*] | recover | go | exit
+
+Set a catchpoint, which stops the program at an event rather than at
+a place in it:
+
+   panic    when a panic is raised, by panic() or by a run-time error.
+            If *type* is given, only when the panic value has that
+            type, e.g. "string" or "main.MyError"
+   recover  when recover() stops a panic
+   go       when a goroutine starts
+   exit     when a goroutine exits, by returning or by a panic it
+            didn't recover
+
+At a panic or recover the panicking frame can still be inspected, and
+the panic value is shown. Without a "panic" catchpoint, a panic stops
+the program only when stepping.
+
+Catchpoints are numbered along with breakpoints, and are shown by
+"info breakpoint" and removed by "delete".`,
+		Min_args: 1,
+		Max_args: 2,
+	}
+	gub.AddToCategory("breakpoints", name)
+}
+
+func CatchCommand(args []string) {
+	what := args[1]
+	if _, ok := gub.CatchEvents[what]; !ok {
+		gub.Errmsg("Expecting 'panic', 'recover', 'go' or 'exit'; got '%s'", what)
+		return
+	}
+	typ := ""
+	if len(args) == 3 {
+		if what != "panic" {
+			gub.Errmsg("Only 'catch panic' takes a type")
+			return
+		}
+		typ = args[2]
+	}
+	bpnum := gub.CatchpointAdd(what, typ)
+	if typ != "" {
+		gub.Msg("Catchpoint %d set for %s of type %s", bpnum, what, typ)
+	} else {
+		gub.Msg("Catchpoint %d set for %s", bpnum, what)
+	}
+}
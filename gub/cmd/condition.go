@@ -0,0 +1,55 @@
+// Copyright 2013 Rocky Bernstein.
+// Debugger condition command
+package gubcmd
+
+import (
+	"go/parser"
+	"strings"
+
+	"github.com/rocky/ssa-interp/gub"
+)
+
+func init() {
+	name := "condition"
+	gub.Cmds[name] = &gub.CmdInfo{
+		Fn: ConditionCommand,
+		Help: `condition *bpnum* [*expr*]
+
+Make breakpoint or catchpoint *bpnum* stop the program only when go
+expression *expr* is true where it is reached. Without an expression,
+the breakpoint stops the program every time again. *bpnum* can be
+"$bpnum", the breakpoint set last.
+
+Examples:
+   condition 1 a > b
+   condition 1          # remove the condition
+`,
+		Min_args: 1,
+		Max_args: -1,
+	}
+	gub.AddToCategory("breakpoints", name)
+}
+
+func ConditionCommand(args []string) {
+	bpnum, err := gub.GetBpnum(args[1], "breakpoint number")
+	if err != nil {
+		return
+	}
+	if !gub.BreakpointExists(bpnum) {
+		gub.Errmsg("Breakpoint %d doesn't exist", bpnum)
+		return
+	}
+	// Use gub.CmdArgstr to preserve blanks inside quotes
+	cond := strings.TrimSpace(strings.TrimPrefix(gub.CmdArgstr, args[1]))
+	if cond == "" {
+		gub.Breakpoints[bpnum].Condition = ""
+		gub.Msg("Breakpoint %d now unconditional", bpnum)
+		return
+	}
+	if _, err := parser.ParseExpr(cond); err != nil {
+		gub.Errmsg("Failed to parse expression '%s' (%v)", cond, err)
+		return
+	}
+	gub.Breakpoints[bpnum].Condition = cond
+	gub.Msg("Breakpoint %d stops only if %s", bpnum, cond)
+}
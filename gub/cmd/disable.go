@@ -23,9 +23,8 @@ Disable a breakpoint by the number assigned to it.`,
 func DisableCommand(args []string) {
 	for i:=1; i<len(args); i++ {
 		msg := fmt.Sprintf("breakpoint number for argument %d", i)
-		val, err := gub.GetUInt(args[i], msg, 0, uint64(len(gub.Breakpoints)-1))
+		bpnum, err := gub.GetBpnum(args[i], msg)
 		if err != nil { continue }
-		bpnum := gub.BpId(val)
 		if gub.BreakpointExists(bpnum) {
 			if !gub.BreakpointIsEnabled(bpnum) {
 				gub.Msg("Breakpoint %d is already disabled", bpnum)
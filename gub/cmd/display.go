@@ -0,0 +1,94 @@
+// Copyright 2013 Rocky Bernstein.
+// Debugger display command
+package gubcmd
+
+import (
+	"strings"
+
+	"github.com/rocky/ssa-interp"
+	"github.com/rocky/ssa-interp/gub"
+)
+
+func init() {
+	name := "display"
+	gub.Cmds[name] = &gub.CmdInfo{
+		Fn: DisplayCommand,
+		Help: `display [[*fn*:] *expr*]
+
+Evaluate go expression *expr* and show its value each time the
+program stops. If a function name *fn* followed by a colon is given,
+*expr* is shown only when we stop in that function. Without an
+expression, the values of all auto-display expressions are shown now.
+
+Examples:
+   display a          # show a at every stop
+   display gcd: b-a   # show b-a when stopped in gcd
+
+See also 'undisplay' and 'info display'.
+`,
+		Min_args: 0,
+		Max_args: -1,
+	}
+	gub.AddToCategory("data", name)
+}
+
+func DisplayCommand(args []string) {
+	if len(args) == 1 {
+		for _, d := range gub.Displays {
+			d.Show()
+		}
+		return
+	}
+	// Use gub.CmdArgstr to preserve blanks inside quotes
+	fn, expr := splitDisplayScope(gub.CmdArgstr)
+	if fn == nil && expr == "" {
+		return
+	}
+	d, err := gub.DisplayAdd(expr, fn)
+	if err != nil {
+		gub.Errmsg("Failed to parse expression '%s' (%v)", expr, err)
+		return
+	}
+	if fn == nil || fn == gub.CurFrame().Fn() {
+		d.Show()
+	}
+}
+
+// splitDisplayScope splits "fn: expr" into the function and the
+// expression. An expression without such a prefix has a nil
+// function. If the prefix names no function, we complain and return
+// an empty expression.
+func splitDisplayScope(argstr string) (*ssa2.Function, string) {
+	i := strings.Index(argstr, ":")
+	if i < 0 {
+		return nil, argstr
+	}
+	name := strings.TrimSpace(argstr[:i])
+	if !isQualifiedIdent(name) {
+		// The colon is part of the expression, as in a[1:2].
+		return nil, argstr
+	}
+	fn := gub.GetFunction(name)
+	if fn == nil {
+		gub.Errmsg("Can't find function %s", name)
+		return nil, ""
+	}
+	return fn, strings.TrimSpace(argstr[i+1:])
+}
+
+// isQualifiedIdent reports whether s has the form of a name or
+// pkg.name.
+func isQualifiedIdent(s string) bool {
+	for _, part := range strings.Split(s, ".") {
+		if part == "" {
+			return false
+		}
+		for i, c := range part {
+			if !(c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' ||
+				i > 0 && '0' <= c && c <= '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -34,11 +34,12 @@ func EnvironmentCommand(args []string) {
 	for k, v := range gub.CurFrame().Env() {
 		switch k := k.(type) {
 		case *ssa2.Alloc:
+			note := gub.LiveNote(gub.CurFrame(), k)
 			if scope := k.Scope; scope != nil {
-				gub.Msg("%s: %s = %s (scope %d)", k.Name(), k, gub.Deref2Str(v),
-					scope.ScopeId())
+				gub.Msg("%s: %s = %s (scope %d)%s", k.Name(), k, gub.Deref2Str(v),
+					scope.ScopeId(), note)
 			} else {
-				gub.Msg("%s: %s = %s", k.Name(), k, gub.Deref2Str(v))
+				gub.Msg("%s: %s = %s%s", k.Name(), k, gub.Deref2Str(v), note)
 			}
 		default:
 			gub.Msg("%s: %s = %s", k.Name(), k, gub.Deref2Str(v))
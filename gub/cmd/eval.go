@@ -2,6 +2,8 @@
 package gubcmd
 
 import (
+	"reflect"
+
 	"github.com/rocky/ssa-interp/gub"
 	"github.com/rocky/ssa-interp/interp"
 )
@@ -13,6 +15,9 @@ func init() {
 		Help: `eval *expr*
 
 Evaluate go expression *expr*.
+
+*expr* may call functions of the program, e.g. "eval gcd(a, 5)" or
+"eval p.String()". See "call" for how such calls are run.
 `,
 
 		Min_args: 1,
@@ -27,23 +32,24 @@ func EvalCommand(args []string) {
 	if expr, err := gub.EvalExpr(gub.CmdArgstr); err == nil {
 		if expr == nil {
 			gub.Msg("nil")
+		} else if len(*expr) == 0 {
+			gub.Errmsg("Something is weird. Result has length 0")
 		} else {
-			expr := *expr
-			if len(expr) == 1 {
-				gub.Msg("%s", interp.ToInspect(expr[0].Interface()))
-			} else {
-				if len(expr) == 0 {
-					gub.Errmsg("Something is weird. Result has length 0")
-				} else {
-					gub.MsgNoCr("(")
-					size := len(expr)
-					for i, v := range expr {
-						gub.MsgNoCr("%v", v.Interface())
-						if i < size-1 { gub.MsgNoCr(", ") }
-					}
-					gub.Msg(")")
-				}
-			}
+			printResults(*expr)
+		}
+	}
+}
+
+func printResults(expr []reflect.Value) {
+	if len(expr) == 1 {
+		gub.Msg("%s", interp.ToInspect(expr[0].Interface()))
+	} else {
+		gub.MsgNoCr("(")
+		size := len(expr)
+		for i, v := range expr {
+			gub.MsgNoCr("%v", v.Interface())
+			if i < size-1 { gub.MsgNoCr(", ") }
 		}
+		gub.Msg(")")
 	}
 }
@@ -0,0 +1,141 @@
+// Copyright 2013 Rocky Bernstein.
+// graph command
+
+package gubcmd
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rocky/ssa-interp"
+	"github.com/rocky/ssa-interp/gub"
+)
+
+func init() {
+	name := "graph"
+	gub.Cmds[name] = &gub.CmdInfo{
+		Fn: GraphCommand,
+		Help: `graph cfg|dom [*fn*] [*file*.dot]
+graph calls [*package*] [*file*.dot]
+graph program [*file*.dot]
+
+Writes a graph in AT&T GraphViz (.dot) format, which "dot -Tpng" and
+friends turn into a picture:
+
+   cfg      the control-flow graph of *fn*, the current function by
+            default, with blocks labeled with their instructions. When
+            it is the current function, the block we are stopped in
+            is highlighted.
+   dom      the dominator tree of *fn*, along with its control-flow
+            edges as dotted lines.
+   calls    the static call graph of *package*, given by name or import
+            path, the current function's package by default.
+   program  the static call graph of the whole program.
+
+The call graphs are those "info callers" and "info callees" use;
+see "info callers" for how they are found. Calls by go statements are
+dashed and by defer statements dotted.
+
+Unless a file name ending in ".dot" is given, the graph goes in the
+current directory in a file named after the function or package and
+the kind of graph, e.g. main.gcd.cfg.dot.
+`,
+		Min_args: 1,
+		Max_args: 3,
+	}
+	gub.AddToCategory("files", name)
+}
+
+func GraphCommand(args []string) {
+	fr := gub.CurFrame()
+	myfn := fr.Fn()
+	prog := myfn.Prog
+
+	kind := args[1]
+	rest := args[2:]
+	file := ""
+	if n := len(rest); n > 0 && strings.HasSuffix(rest[n-1], ".dot") {
+		file = rest[n-1]
+		rest = rest[:n-1]
+	}
+
+	var write func(w io.Writer) error
+	var what string
+	switch kind {
+	case "cfg", "dom":
+		fn := myfn
+		if len(rest) > 0 {
+			if fn = gub.GetFunction(rest[0]); fn == nil {
+				gub.Errmsg("Can't find function %s", rest[0])
+				return
+			}
+		}
+		if kind == "cfg" {
+			var current *ssa2.BasicBlock
+			if fn == myfn {
+				current = fr.Block()
+			}
+			write = func(w io.Writer) error { return fn.WriteCFGDot(w, current) }
+			what = "control-flow graph of " + fn.String()
+		} else {
+			write = fn.WriteDomTreeDot
+			what = "dominator tree of " + fn.String()
+		}
+		if file == "" {
+			file = ssa2.DotFileName(fn.String(), kind)
+		}
+	case "calls":
+		pkg := myfn.Pkg
+		if len(rest) > 0 {
+			if pkg = prog.PackagesByPath[rest[0]]; pkg == nil {
+				pkg = prog.PackagesByName[rest[0]]
+			}
+			if pkg == nil {
+				gub.Errmsg("Can't find package %s", rest[0])
+				return
+			}
+		}
+		write = func(w io.Writer) error {
+			gub.CallGraph().WriteDot(w, pkg)
+			return nil
+		}
+		what = "call graph of package " + pkg.Object.Path()
+		if file == "" {
+			file = ssa2.DotFileName(pkg.Object.Path(), "calls")
+		}
+	case "program":
+		if len(rest) > 0 {
+			gub.Errmsg("graph program takes only a file name")
+			return
+		}
+		write = func(w io.Writer) error {
+			gub.CallGraph().WriteDot(w, nil)
+			return nil
+		}
+		what = "call graph of the program"
+		if file == "" {
+			file = ssa2.DotFileName("program", "calls")
+		}
+	default:
+		gub.Errmsg("Expecting cfg, dom, calls or program; got '%s'", kind)
+		return
+	}
+	if len(rest) > 1 {
+		gub.Errmsg("Too many arguments for graph %s", kind)
+		return
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		gub.Errmsg("%s", err)
+		return
+	}
+	defer f.Close()
+	if err := write(f); err != nil {
+		os.Remove(file)
+		gub.Errmsg("%s", err)
+		return
+	}
+	gub.Msg("Wrote %s to %s", what, file)
+}
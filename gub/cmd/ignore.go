@@ -0,0 +1,45 @@
+// Copyright 2013 Rocky Bernstein.
+// Debugger ignore command
+package gubcmd
+
+import (
+	"github.com/rocky/ssa-interp/gub"
+)
+
+func init() {
+	name := "ignore"
+	gub.Cmds[name] = &gub.CmdInfo{
+		Fn: IgnoreCommand,
+		Help: `ignore *bpnum* *count*
+
+Don't stop the program the next *count* times breakpoint or catchpoint
+*bpnum* is hit. Hits where its condition is false don't count. A count
+of 0 makes it stop the next time. *bpnum* can be "$bpnum", the
+breakpoint set last.
+`,
+		Min_args: 2,
+		Max_args: 2,
+	}
+	gub.AddToCategory("breakpoints", name)
+}
+
+func IgnoreCommand(args []string) {
+	bpnum, err := gub.GetBpnum(args[1], "breakpoint number")
+	if err != nil {
+		return
+	}
+	if !gub.BreakpointExists(bpnum) {
+		gub.Errmsg("Breakpoint %d doesn't exist", bpnum)
+		return
+	}
+	count, err := gub.GetInt(args[2], "ignore count", 0, 0)
+	if err != nil {
+		return
+	}
+	gub.Breakpoints[bpnum].Ignore = count
+	if count == 0 {
+		gub.Msg("Breakpoint %d stops the next time it is hit", bpnum)
+	} else {
+		gub.Msg("Breakpoint %d ignores its next %d hits", bpnum, count)
+	}
+}
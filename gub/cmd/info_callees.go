@@ -0,0 +1,47 @@
+// Copyright 2013 Rocky Bernstein.
+
+// info callees [fn]
+//
+// Prints what calls may go to in the static call graph
+
+package gubcmd
+
+import (
+	"github.com/rocky/ssa-interp"
+	"github.com/rocky/ssa-interp/callgraph"
+	"github.com/rocky/ssa-interp/gub"
+)
+
+func init() {
+	parent := "info"
+	gub.AddSubCommand(parent, &gub.SubcmdInfo{
+		Fn: InfoCalleesSubcmd,
+		Help: `info callees [*fn*]
+
+Prints the functions which each call of the statement we are stopped
+at may go to, according to a static call graph of the program. If a
+function is given, the calls are all those of the function.
+
+See "info callers" for how the call graph is found.
+`,
+		Min_args: 0,
+		Max_args: 1,
+		Short_help: "Callees of calls in the static call graph",
+		Name: "callees",
+	})
+}
+
+func InfoCalleesSubcmd(args []string) {
+	var sites []ssa2.CallInstruction
+	if len(args) == 3 {
+		fn := gub.GetFunction(args[2])
+		if fn == nil {
+			gub.Errmsg("Can't find function %s", args[2])
+			return
+		}
+		sites = callgraph.CallSites(fn)
+	} else {
+		sites = gub.StmtCallSites()
+	}
+	gub.PrintCallees(sites)
+}
@@ -0,0 +1,47 @@
+// Copyright 2013 Rocky Bernstein.
+
+// info callers [fn]
+//
+// Prints the calls of a function in the static call graph
+
+package gubcmd
+
+import (
+	"github.com/rocky/ssa-interp/gub"
+)
+
+func init() {
+	parent := "info"
+	gub.AddSubCommand(parent, &gub.SubcmdInfo{
+		Fn: InfoCallersSubcmd,
+		Help: `info callers [*fn*]
+
+Prints the calls of *fn*, the current function by default, according
+to a static call graph of the program, with the function each is in
+and its position.
+
+The call graph comes from Rapid Type Analysis, starting at main and
+init: calls through an interface can go to the methods of the types
+converted to an interface in code reachable from there. So unlike
+"backtrace", this shows calls which may happen rather than the ones
+that did.
+
+See also "info callees".
+`,
+		Min_args: 0,
+		Max_args: 1,
+		Short_help: "Callers of a function in the static call graph",
+		Name: "callers",
+	})
+}
+
+func InfoCallersSubcmd(args []string) {
+	fn := gub.CurFrame().Fn()
+	if len(args) == 3 {
+		if fn = gub.GetFunction(args[2]); fn == nil {
+			gub.Errmsg("Can't find function %s", args[2])
+			return
+		}
+	}
+	gub.PrintCallers(fn)
+}
@@ -0,0 +1,35 @@
+// Copyright 2013 Rocky Bernstein.
+// Debugger info display command
+
+package gubcmd
+
+import "github.com/rocky/ssa-interp/gub"
+
+func init() {
+	parent := "info"
+	gub.AddSubCommand(parent, &gub.SubcmdInfo{
+		Fn: InfoDisplaySubcmd,
+		Help: `info display
+
+Show the auto-display expressions, their numbers, and the function
+each is limited to, if any.
+
+See also 'display' and 'undisplay'.
+`,
+		Min_args: 0,
+		Max_args: 0,
+		Short_help: "Expressions to show when the program stops",
+		Name: "display",
+	})
+}
+
+func InfoDisplaySubcmd(args []string) {
+	if len(gub.Displays) == 0 {
+		gub.Msg("There are no auto-display expressions now.")
+		return
+	}
+	gub.Section("Auto-display expressions now in effect:")
+	for _, d := range gub.Displays {
+		gub.Msg("%3d: %s", d.Id, d.String())
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2013 Rocky Bernstein.
+
+// info pointsto expr
+//
+// Prints what an expression may point to according to points-to analysis
+
+package gubcmd
+
+import (
+	"strings"
+
+	"github.com/rocky/ssa-interp/gub"
+)
+
+func init() {
+	parent := "info"
+	gub.AddSubCommand(parent, &gub.SubcmdInfo{
+		Fn: InfoPointsToSubcmd,
+		Help: `info pointsto *expr*
+
+Prints the objects that *expr* may point to according to a points-to
+analysis of the whole program. *expr* is built from variables visible
+where we are stopped and SSA registers such as t3, using &, *, field
+selectors and indexing. Examples:
+
+   info pointsto p
+   info pointsto *pp
+   info pointsto node.next
+   info pointsto table["go"]
+
+Objects are named by what creates them: a local or new variable, a
+global, a make of a slice, map or channel, a closure, a function, or,
+for interface values, the conversion to the interface.
+
+Unlike "print", which shows the value *expr* has now, this gives
+every object it may point to in any run. The analysis starts at main
+and init; it is flow-insensitive and doesn't distinguish the fields
+of a struct or the elements of an array.
+`,
+		Min_args: 1,
+		Max_args: -1,
+		Short_help: "Points-to set of an expression",
+		Name: "pointsto",
+	})
+}
+
+func InfoPointsToSubcmd(args []string) {
+	// Use gub.CmdArgstr, which is "pointsto ..." and preserves blanks
+	// inside quotes.
+	gub.PrintPointsTo(strings.TrimSpace(strings.TrimPrefix(gub.CmdArgstr, args[1])))
+}
@@ -7,6 +7,7 @@
 package gubcmd
 
 import (
+	"github.com/rocky/ssa-interp"
 	"github.com/rocky/ssa-interp/gub"
 )
 
@@ -16,8 +17,10 @@ func init() {
 		Fn: InfoScopeSubcmd,
 		Help: `info scope [level]
 
-Prints information about the scope for the current stack frame.
-If a level is given, we go up that many levels.
+Prints the variables of the innermost scope where the current stack
+frame is stopped, leaving out those not yet declared and marking those
+shadowed by an inner scope. If a level is given, we go up that many
+scopes.
 `,
 		Min_args: 0,
 		Max_args: 1,
@@ -28,7 +31,7 @@ If a level is given, we go up that many levels.
 
 func InfoScopeSubcmd(args []string) {
 	fr    := gub.CurFrame()
-	scope := fr.Scope()
+	scope := gub.CurScope()
 	if scope == nil {
 		gub.Errmsg("No scope recorded here")
 		return
@@ -41,19 +44,14 @@ func InfoScopeSubcmd(args []string) {
 		if err != nil { return }
 	}
 
-	typescope := scope.Scope
+	inner := scope
 	for i := 0; i < count; i++ {
-		typescope = typescope.Parent()
-		if typescope == nil {
-			gub.Errmsg("There are only %d nested scopes", i)
-			return
-		}
-		scope = fr.Fn().Pkg.TypeScope2Scope[typescope]
+		scope = ssa2.ParentScope(fr.Fn(), scope)
 		if scope == nil {
 			gub.Errmsg("No parent scope; There are only %d nested scopes", i)
 			return
 		}
 	}
 	gub.Section("scope number %d", scope.ScopeId())
-	gub.Msg("%s", typescope)
+	gub.PrintScopeVars(fr, scope, inner)
 }
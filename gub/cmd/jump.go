@@ -9,23 +9,38 @@ func init() {
 	name := "jump"
 	gub.Cmds[name] = &gub.CmdInfo{
 		Fn: JumpCommand,
-		Help: `jump *num*
+		Help: `jump *line* [*column*]
 
-Jumps to instruction *num* inside the current basic block.
+Continue execution at the statement on *line* of the current function
+rather than at the current one; a column picks one of several
+statements on the line. This is useful to run a statement again after
+changing a variable with "set var".
+
+We can only jump within the current scope or into a scope nested in
+it, and not to a statement whose basic block starts with phi-nodes,
+as their values depend on the block we came from.
+
+See also 'jumpi'.
 `,
 		Min_args: 1,
-		Max_args: 1,
+		Max_args: 2,
 	}
 	gub.AddToCategory("running", name)
 }
 
 func JumpCommand(args []string) {
-	fr := gub.CurFrame()
-	b := fr.Block()
-	ic, err := gub.GetUInt(args[1],
-		"instruction number", 0, uint64(len(b.Instrs)-1))
+	line, err := gub.GetInt(args[1], "line number", 1, 0)
 	if err != nil { return }
-	// compensate for interpreter loop which does ic++ at end of loop body
-	fr.SetPC(uint(ic-1))
+	column := -1
+	if len(args) == 3 {
+		if column, err = gub.GetInt(args[2], "column number", 1, 0); err != nil {
+			return
+		}
+	}
+	if err := gub.JumpToLine(line, column); err != nil {
+		gub.Errmsg("%s", err)
+		return
+	}
+	gub.Msg("Jumping to line %d...", line)
 	gub.InCmdLoop = false
 }
@@ -0,0 +1,48 @@
+// Copyright 2013 Rocky Bernstein.
+// list command
+
+package gubcmd
+
+import "github.com/rocky/ssa-interp/gub"
+
+func init() {
+	name := "list"
+	gub.Cmds[name] = &gub.CmdInfo{
+		Fn: ListCommand,
+		Help: `list [*fn* | *line* | *file*:*line* | - | + ]
+
+List source code. Without a location, or with "+", we list lines
+around where we are stopped the first time, and after that continue
+from the last listing. With "-" we list the lines before the last
+listing. A function name lists around the start of that function; a
+line number lists around that line of the file last listed.
+
+On the line we are stopped at "->" is shown, and the line under it
+marks the columns of the statement being run. Lines with enabled
+breakpoints are marked "B", those with only disabled breakpoints "b".
+
+Examples:
+   list            # list around where we are stopped
+   list -          # list the lines before
+   list main.foo   # list around function foo of package main
+   list 30         # list around line 30
+   list gcd.go:10  # list around line 10 of gcd.go
+
+See also 'set highlight'.
+`,
+		Min_args: 0,
+		Max_args: 1,
+	}
+	gub.AddToCategory("files", name)
+	gub.AddAlias("l", name)
+}
+
+func ListCommand(args []string) {
+	if len(args) == 1 || args[1] == "+" {
+		gub.ListContinue()
+	} else if args[1] == "-" {
+		gub.ListBefore()
+	} else {
+		gub.ListLocation(args[1])
+	}
+}
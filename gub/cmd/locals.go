@@ -3,14 +3,20 @@ package gubcmd
 
 import (
 	"github.com/rocky/ssa-interp/gub"
-	"github.com/rocky/ssa-interp/interp"
 )
 
 func init() {
 	name := "locals"
 	gub.Cmds[name] = &gub.CmdInfo{
 		Fn: LocalsCommand,
-		Help: "locals [*name*]: show local variable info",
+		Help: `locals [*name*]
+
+Without a name, show the local variables in scope where we are
+stopped, innermost scope first. Variables hidden by ones of the same
+name in an inner scope, and those not yet declared, are left out.
+
+With a name, show the local variable it refers to here.
+`,
 		Min_args: 0,
 		Max_args: 2,
 	}
@@ -24,25 +30,16 @@ func LocalsCommand(args []string) {
 	argc := len(args) - 1
 	fr := gub.CurFrame()
 	if argc == 0 {
-		for i, _ := range fr.Locals() {
-			gub.PrintLocal(fr, uint(i))
-		}
-		for reg, v := range fr.Reg2Var {
-			gub.Msg("reg %s, var %s", reg, v)
+		if gub.CurScope() == nil {
+			gub.Errmsg("No scope recorded here")
+			return
 		}
+		gub.PrintVisibleVars(fr, gub.CurScope())
 	} else {
 		varname := args[1]
 		if gub.PrintIfLocal(fr, varname) {
 			return
 		}
-		// FIXME: This really shouldn't be needed.
-		for i, v := range fr.Locals() {
-			if varname == fr.Fn().Locals[i].Name() {
-				gub.Msg("fixme %s %s: %s",
-					varname, fr.Fn().Locals[i], interp.ToInspect(v))
-				break
-			}
-		}
-
+		gub.PrintInEnvironment(fr, varname)
 	}
 }
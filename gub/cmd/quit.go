@@ -45,6 +45,7 @@ func QuitCommand(args []string) {
 		}
 	}
 	gub.Msg("gub: That's all folks...")
+	gub.SaveAutoSession()
 
 	// FIXME: determine under which conditions we've used term
 	gnureadline.Rl_reset_terminal(gub.Term)
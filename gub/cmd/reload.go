@@ -0,0 +1,39 @@
+// Copyright 2013 Rocky Bernstein.
+// reload command
+
+package gubcmd
+
+import (
+	"github.com/rocky/ssa-interp/gub"
+)
+
+func init() {
+	name := "reload"
+	gub.Cmds[name] = &gub.CmdInfo{
+		Fn: ReloadCommand,
+		Help: `reload
+
+Rebuilds the packages whose source files have changed since they were
+loaded, or last reloaded, without restarting the program.
+
+Each function of a rebuilt package runs its new code from its next
+call on. A function that is on the stack of some goroutine keeps
+running its old code in those frames; they are listed if its code
+changed. So does a function value made from such a function before
+the reload, such as a closure or a method value. Breakpoints in the
+files are set again at the same lines.
+
+Only the bodies of functions can change: adding or removing functions,
+methods, types, variables or constants, or changing their
+declarations, needs a restart. The initializers of package-level
+variables aren't run again.
+`,
+		Min_args: 0,
+		Max_args: 0,
+	}
+	gub.AddToCategory("running", name)
+}
+
+func ReloadCommand(args []string) {
+	gub.Reload()
+}
@@ -0,0 +1,57 @@
+// Copyright 2013 Rocky Bernstein.
+
+package gubcmd
+
+import (
+	"github.com/rocky/ssa-interp/gub"
+	"github.com/rocky/ssa-interp/interp"
+)
+
+func init() {
+	name := "return"
+	gub.Cmds[name] = &gub.CmdInfo{
+		Fn: ReturnCommand,
+		Help: `return [*expr* [, *expr*...]]
+
+Make the current function return now, with the given results, rather
+than running the rest of it. Its deferred calls are still run. Without
+expressions, the results are the zero values of their types. When
+stopped at a return, the results given replace the ones being
+returned.
+
+Execution continues as for "next" in the caller.
+
+Examples:
+   return            # return zero values
+   return a % b      # return the value of a % b
+   return 1, nil     # return two values
+`,
+		Min_args: 0,
+		Max_args: -1,
+	}
+	gub.AddToCategory("running", name)
+}
+
+func ReturnCommand(args []string) {
+	fr := gub.TopFrame()
+	if gub.CurFrame() != fr {
+		gub.Errmsg("Can only return from the innermost frame; use 'frame 0' first")
+		return
+	}
+	if fr.Status() == interp.StPanic {
+		gub.Errmsg("Can't return from a frame that is panicking")
+		return
+	}
+	result, err := gub.ReturnValue(fr, gub.CmdArgstr)
+	if err != nil {
+		gub.Errmsg("%s", err)
+		return
+	}
+	fr.ForceReturn(result)
+	interp.SetStepOff(fr)
+	if caller := fr.Caller(0); caller != nil {
+		interp.SetStepOver(caller)
+	}
+	gub.Msg("Returning from %s...", fr.Fn().Name())
+	gub.InCmdLoop = false
+}
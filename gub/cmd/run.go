@@ -1,28 +1,28 @@
 // Copyright 2013 Rocky Bernstein.
 
-// Restarts program..
+// run command
 
 package gubcmd
 
 import (
 	"github.com/rocky/ssa-interp/gub"
-	"os"
-	"strings"
-	"syscall"
 )
 
 func init() {
 	name := "run"
 	gub.Cmds[name] = &gub.CmdInfo{
 		Fn: RunCommand,
-		Help: `run
+		Help: `run [args...]
 
-Terminates program. If an exit code is given, that is the exit code
-for the program. Zero (normal termination) is used if no
-termintation code.
+Runs the program again from the start, without leaving the debugger.
+If args are given, they are the program's arguments, os.Args[1:];
+otherwise it gets the same ones as before.
+
+Breakpoints, displays, settings and command history are kept. None of
+the program's deferred calls are run, and its goroutines are abandoned.
 `,
 		Min_args: 0,
-		Max_args: 0,
+		Max_args: -1,
 	}
 	gub.AddToCategory("running", name)
 	gub.Aliases["R"] = name
@@ -30,11 +30,6 @@ termintation code.
 }
 
 func RunCommand(args []string) {
-	if gub.GUB_RESTART_CMD == "" {
-		gub.Errmsg("restart string in environment GUB_RESTART_CMD has nothing")
-		return
-	}
-	gub.Msg("gub: restarting: %s", gub.GUB_RESTART_CMD)
-	restartCmd := strings.Split(gub.GUB_RESTART_CMD, " ")
-	syscall.Exec(restartCmd[0], restartCmd, os.Environ());
+	gub.Msg("gub: restarting")
+	gub.Restart(args[1:])
 }
@@ -0,0 +1,68 @@
+// Copyright 2013 Rocky Bernstein.
+
+// save command
+//
+
+package gubcmd
+
+import (
+	"github.com/rocky/ssa-interp/gub"
+)
+
+func init() {
+	name := "save"
+	gub.Cmds[name] = &gub.CmdInfo{
+		SubcmdMgr: &gub.SubcmdMgr{
+			Name   : name,
+			Subcmds: make(gub.SubcmdMap),
+		},
+		Fn: SaveCommand,
+		Help: `Generic command for saving debugger state as a script of gub commands.
+
+The script can be read back with "source", or given to gub with
+-cmdfile. Without a file name, the commands are shown instead.
+
+Type "save" for a list of "save" subcommands and what they do.
+Type "help save *" for just a list of "save" subcommands.
+`,
+		Min_args: 0,
+		Max_args: 2,
+	}
+	gub.AddToCategory("support", name)
+}
+
+func SaveCommand(args []string) {
+	if len(args) == 1 {
+		gub.ListSubCommandArgs(gub.Cmds["save"].SubcmdMgr)
+		return
+	}
+
+	subcmd_name := args[1]
+	subcmds     := gub.Cmds["save"].SubcmdMgr.Subcmds
+	subcmd_info := subcmds[subcmd_name]
+
+	if subcmd_info != nil {
+		if gub.ArgCountOK(subcmd_info.Min_args+1, subcmd_info.Max_args+1, args) {
+			subcmds[subcmd_name].Fn(args)
+		}
+		return
+	}
+
+	gub.Errmsg("Unknown 'save' subcommand '%s'", args[1])
+}
+
+// saveCommands writes cmds to the file named by args[2], or shows
+// them if no file is given. what says what they restore.
+func saveCommands(args []string, cmds []string, what string) {
+	if len(args) == 2 {
+		for _, cmd := range cmds {
+			gub.Msg(cmd)
+		}
+		return
+	}
+	if err := gub.WriteCommands(args[2], cmds); err != nil {
+		gub.Errmsg("Can't save %s: %s", what, err)
+		return
+	}
+	gub.Msg("Saved %s to %s", what, args[2])
+}
@@ -0,0 +1,30 @@
+// Copyright 2013 Rocky Bernstein.
+
+// save breakpoints - write commands which set the breakpoints again
+
+package gubcmd
+
+import (
+	"github.com/rocky/ssa-interp/gub"
+)
+
+func init() {
+	parent := "save"
+	gub.AddSubCommand(parent, &gub.SubcmdInfo{
+		Fn: SaveBreakpointsSubcmd,
+		Help: `save breakpoints [*file*]
+
+Save the breakpoints and catchpoints, with their conditions, ignore
+counts and whether they are enabled, as gub commands in *file*.
+Breakpoints are renumbered from the next free number when the file is
+read back.`,
+		Min_args: 0,
+		Max_args: 1,
+		Short_help: "save breakpoints as gub commands",
+		Name: "breakpoints",
+	})
+}
+
+func SaveBreakpointsSubcmd(args []string) {
+	saveCommands(args, gub.BreakpointCommands(), "breakpoints")
+}
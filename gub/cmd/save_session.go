@@ -0,0 +1,32 @@
+// Copyright 2013 Rocky Bernstein.
+
+// save session - write commands which restore the debugger session
+
+package gubcmd
+
+import (
+	"github.com/rocky/ssa-interp/gub"
+)
+
+func init() {
+	parent := "save"
+	gub.AddSubCommand(parent, &gub.SubcmdInfo{
+		Fn: SaveSessionSubcmd,
+		Help: `save session [*file*]
+
+Save the settings, aliases, breakpoints and auto-display expressions
+of this session as gub commands in *file*.
+
+When gub is used interactively, the session is also saved in
+$HOME/.gub.d when gub quits or restarts the program, and restored the next
+time gub runs the same program.`,
+		Min_args: 0,
+		Max_args: 1,
+		Short_help: "save the debugger session as gub commands",
+		Name: "session",
+	})
+}
+
+func SaveSessionSubcmd(args []string) {
+	saveCommands(args, gub.SessionCommands(), "session")
+}
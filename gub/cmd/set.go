@@ -23,7 +23,7 @@ Type "set" for a list of "set" subcommands and what they do.
 Type "help set *" for just a list of "info" subcommands.
 `,
 		Min_args: 0,
-		Max_args: 3,
+		Max_args: -1,
 	}
 	gub.AddToCategory("support", name)
 }
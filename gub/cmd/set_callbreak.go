@@ -0,0 +1,50 @@
+// Copyright 2013 Rocky Bernstein.
+
+// set callbreak - stop at breakpoints in functions called by the debugger?
+
+package gubcmd
+
+import (
+	"github.com/rocky/ssa-interp/gub"
+)
+
+func init() {
+	parent := "set"
+	gub.AddSubCommand(parent, &gub.SubcmdInfo{
+		Fn: SetCallbreakSubcmd,
+		Help: `set callbreak [on|off]
+
+Sets whether breakpoints are honored inside functions called from the
+debugger, as by "call" or "eval". When off, the default, such calls
+run to completion.`,
+		Min_args: 0,
+		Max_args: 1,
+		Short_help: "stop at breakpoints in debugger calls",
+		Name: "callbreak",
+	})
+}
+
+func SetCallbreakSubcmd(args []string) {
+	onoff := "on"
+	if len(args) == 3 {
+		onoff = args[2]
+	}
+	switch ParseOnOff(onoff) {
+	case ONOFF_ON:
+		if gub.CallBreakpoints {
+			gub.Errmsg("callbreak is already on")
+		} else {
+			gub.Msg("Setting callbreak on")
+			gub.CallBreakpoints = true
+		}
+	case ONOFF_OFF:
+		if !gub.CallBreakpoints {
+			gub.Errmsg("callbreak is already off")
+		} else {
+			gub.Msg("Setting callbreak off")
+			gub.CallBreakpoints = false
+		}
+	case ONOFF_UNKNOWN:
+		gub.Msg("Expecting 'on' or 'off', got '%s'; nothing done", onoff)
+	}
+}
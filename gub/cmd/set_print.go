@@ -0,0 +1,61 @@
+// Copyright 2013 Rocky Bernstein.
+
+// set print - how values are shown
+
+package gubcmd
+
+import (
+	"github.com/rocky/ssa-interp/gub"
+	"github.com/rocky/ssa-interp/interp"
+)
+
+func init() {
+	parent := "set"
+	gub.AddSubCommand(parent, &gub.SubcmdInfo{
+		Fn: SetPrintSubcmd,
+		Help: `set print [depth|elements *num*]
+
+Sets limits on how much of a value "eval", "call" and "display" show.
+
+"set print depth *num*" sets how deeply nested structs, arrays, slices
+and maps are shown; deeper ones are shown as "{...}" or "[...]".
+
+"set print elements *num*" sets how many elements of an array, slice
+or map are shown; the rest are shown as "...".
+
+A limit of 0 means no limit. Without a setting, the limits are shown.`,
+		Min_args: 0,
+		Max_args: 2,
+		Short_help: "limits on how values are shown",
+		Name: "print",
+	})
+}
+
+func SetPrintSubcmd(args []string) {
+	if len(args) == 2 {
+		gub.Msg("print depth is %d", interp.PrintDepth)
+		gub.Msg("print elements is %d", interp.PrintElements)
+		return
+	}
+	if len(args) != 4 {
+		gub.Errmsg("Expecting 'depth' or 'elements' and a number")
+		return
+	}
+	what := args[2]
+	var setting *int
+	switch what {
+	case "depth":
+		setting = &interp.PrintDepth
+	case "elements":
+		setting = &interp.PrintElements
+	default:
+		gub.Errmsg("Expecting 'depth' or 'elements', got '%s'; nothing done", what)
+		return
+	}
+	n, err := gub.GetInt(args[3], "print "+what, 0, 0)
+	if err != nil {
+		return
+	}
+	*setting = n
+	gub.Msg("Setting print %s to %d", what, n)
+}
@@ -0,0 +1,53 @@
+// Copyright 2013 Rocky Bernstein.
+
+// set var - assign to a program variable
+
+package gubcmd
+
+import (
+	"strings"
+
+	"github.com/rocky/ssa-interp/gub"
+)
+
+func init() {
+	parent := "set"
+	gub.AddSubCommand(parent, &gub.SubcmdInfo{
+		Fn: SetVarSubcmd,
+		Help: `set var *lvalue* = *expr*
+
+Assign the value of *expr* to *lvalue*, which can be a local or global
+variable, a field, or an element of an array, slice or map. Examples:
+
+   set var x = 5
+   set var p.name = "gub"
+   set var counts["go"] = n + 1
+
+*expr* must be assignable to the type of *lvalue*. Constants are
+converted as in Go, so "set var f = 1" works for a float64 f, but
+"set var i = 1.5" fails for an int i.
+
+A local variable whose address is never taken is usually lifted by
+the SSA builder into registers, one for each assignment in the
+source, rather than kept in a variable. Assigning to such a variable
+is refused, since only some of its uses would see the new value; see
+"environment" for the registers. A register can still be set by its
+SSA name, as in "set var t3 = 5".
+`,
+		Min_args: 1,
+		Max_args: -1,
+		Short_help: "Assign to a program variable",
+		Name: "var",
+	})
+}
+
+func SetVarSubcmd(args []string) {
+	// Use gub.CmdArgstr, which is "var ..." and preserves blanks
+	// inside quotes.
+	stmt := strings.TrimSpace(strings.TrimPrefix(gub.CmdArgstr, args[1]))
+	if tv, err := gub.Assign(stmt); err != nil {
+		gub.Errmsg("%s", err)
+	} else {
+		gub.Msg("%s", tv)
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright 2013 Rocky Bernstein.
+// Debugger source command
+package gubcmd
+
+import (
+	"github.com/rocky/ssa-interp/gub"
+)
+
+func init() {
+	name := "source"
+	gub.Cmds[name] = &gub.CmdInfo{
+		Fn: SourceCommand,
+		Help: `source *file*
+
+Run the debugger commands in *file*, one per line, as if they were
+typed. Lines starting with # are comments. A command which resumes the
+program, like "continue", ends the script.
+
+Scripts written by "save" restore breakpoints or a whole session this
+way; they can also be given to gub with -cmdfile.
+`,
+		Min_args: 1,
+		Max_args: 1,
+	}
+	gub.AddToCategory("support", name)
+}
+
+func SourceCommand(args []string) {
+	if err := gub.Source(args[1]); err != nil {
+		gub.Errmsg("Can't read debugger commands from %s: %s", args[1], err)
+	}
+}
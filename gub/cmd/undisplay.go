@@ -0,0 +1,45 @@
+// Copyright 2013 Rocky Bernstein.
+// Debugger undisplay command
+package gubcmd
+
+import (
+	"fmt"
+
+	"github.com/rocky/ssa-interp/gub"
+)
+
+func init() {
+	name := "undisplay"
+	gub.Cmds[name] = &gub.CmdInfo{
+		Fn: UndisplayCommand,
+		Help: `undisplay [*num* ...]
+
+Cancel the auto-display expressions with the numbers given by
+"display" or "info display". Without numbers, all auto-display
+expressions are cancelled.
+`,
+		Min_args: 0,
+		Max_args: -1,
+	}
+	gub.AddToCategory("data", name)
+}
+
+func UndisplayCommand(args []string) {
+	if len(args) == 1 {
+		gub.DisplayDeleteAll()
+		gub.Msg("All auto-display expressions deleted")
+		return
+	}
+	for i := 1; i < len(args); i++ {
+		msg := fmt.Sprintf("display number for argument %d", i)
+		num, err := gub.GetInt(args[i], msg, 1, 0)
+		if err != nil {
+			continue
+		}
+		if gub.DisplayDelete(num) {
+			gub.Msg(" Deleted display %d", num)
+		} else {
+			gub.Errmsg("Display %d doesn't exist", num)
+		}
+	}
+}
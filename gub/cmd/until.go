@@ -0,0 +1,31 @@
+// Copyright 2013 Rocky Bernstein.
+
+package gubcmd
+
+import "github.com/rocky/ssa-interp/gub"
+
+func init() {
+	name := "until"
+	gub.Cmds[name] = &gub.CmdInfo{
+		Fn: UntilCommand,
+		Help: `until
+
+Continue until a statement on a line after the current one in the
+current function is reached, or the function returns. This is like
+"next", but at the end of a loop it runs the loop to completion rather
+than going back to its start.
+
+See also 'next' and 'advance'.
+`,
+		Min_args: 0,
+		Max_args: 0,
+	}
+	gub.AddToCategory("running", name)
+	gub.AddAlias("u", name)
+}
+
+func UntilCommand(args []string) {
+	gub.SetUntil(gub.TopFrame())
+	gub.Msg("Continuing until a line after %d...", gub.TopFrame().Position().Line)
+	gub.InCmdLoop = false
+}
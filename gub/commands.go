@@ -27,6 +27,19 @@ func AddAlias(alias string, cmdname string) bool {
 	return true
 }
 
+// userAliases are the aliases added by the "alias" command, in the
+// order they were added, which "save session" saves.
+var userAliases []string
+
+// AddUserAlias adds alias, as the "alias" command does.
+func AddUserAlias(alias string, cmdname string) bool {
+	if !AddAlias(alias, cmdname) {
+		return false
+	}
+	userAliases = append(userAliases, alias)
+	return true
+}
+
 func AddToCategory(category string, cmdname string) {
 	Categories[category] = append(Categories[category], cmdname)
 	// Cmds[cmdname].category = category
@@ -0,0 +1,432 @@
+// Copyright 2013 Rocky Bernstein.
+// Support for the execution-control commands "until", "advance",
+// "return" and "jump", built on the interpreter's stepping modes and
+// frame state.
+package gub
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"strconv"
+
+	"github.com/rocky/ssa-interp"
+	"github.com/rocky/ssa-interp/interp"
+)
+
+// While "until" is in effect, statements of untilFrame on lines up
+// to untilLine are stepped over.
+var (
+	untilFrame *interp.Frame
+	untilLine  int
+)
+
+// SetUntil steps over statements in fr until one on a line after the
+// current one is reached, or fr returns. This gets us out of a loop
+// whose end we are at.
+func SetUntil(fr *interp.Frame) {
+	untilFrame = fr
+	untilLine = fr.Position().Line
+	interp.SetStepOver(fr)
+}
+
+// skipUntil reports whether the stop at instr should be skipped
+// because "until" is in effect. Once we stop somewhere,
+// "until" is over.
+func skipUntil(fr *interp.Frame, instr *ssa2.Instruction) bool {
+	if untilFrame == nil {
+		return false
+	}
+	if fr == untilFrame && instr != nil {
+		if trace, ok := (*instr).(*ssa2.Trace); ok && !trace.Breakpoint &&
+			fr.Position().Line <= untilLine {
+			return true
+		}
+	}
+	untilFrame = nil
+	return false
+}
+
+// The one-shot breakpoint "advance" sets, and whether there was a
+// breakpoint there already, which we must leave in place.
+var (
+	advanceTrace  *ssa2.Trace
+	advanceFn     *ssa2.Function
+	advanceWasSet bool
+)
+
+// SetAdvance sets a one-shot breakpoint at location, given as for
+// "break": a function name or a line and optional column in the
+// current file. It is removed the next time we stop, wherever that
+// is.
+func SetAdvance(args []string) error {
+	trace, fn, err := findLocation(args)
+	if err != nil {
+		return err
+	}
+	advanceDone()
+	if trace != nil {
+		advanceTrace, advanceWasSet = trace, trace.Breakpoint
+		trace.Breakpoint = true
+	} else {
+		advanceFn, advanceWasSet = fn, interp.IsFnBreakpoint(fn)
+		interp.SetFnBreakpoint(fn)
+	}
+	return nil
+}
+
+// advanceDone removes the breakpoint "advance" set, if any.
+func advanceDone() {
+	if advanceTrace != nil {
+		advanceTrace.Breakpoint = advanceWasSet
+	} else if advanceFn != nil && !advanceWasSet {
+		interp.ClearFnBreakpoint(advanceFn)
+	}
+	advanceTrace, advanceFn = nil, nil
+}
+
+// Restart runs the program again from the start, with args as its
+// arguments, or the same ones as before if args is empty.
+// Breakpoints, displays and settings stay as they are, and are saved
+// as the session for the next run of gub.
+func Restart(args []string) {
+	SaveAutoSession()
+	untilFrame = nil
+	advanceDone()
+	if len(args) == 0 {
+		args = nil
+	}
+	curFrame.I().Restart(args)
+}
+
+// findLocation gives the statement or function that args, as for
+// "break", refer to. args[0] is the command name.
+func findLocation(args []string) (*ssa2.Trace, *ssa2.Function, error) {
+	if fn := GetFunction(args[1]); fn != nil {
+		if ext := interp.Externals()[args[1]]; ext != nil {
+			return nil, nil, fmt.Errorf("%s is a built-in external function", args[1])
+		}
+		return nil, fn, nil
+	}
+	line, err := strconv.Atoi(args[1])
+	if err != nil {
+		return nil, nil, fmt.Errorf("Expecting a function name or line number; got '%s'", args[1])
+	}
+	column := -1
+	if len(args) == 3 {
+		if column, err = strconv.Atoi(args[2]); err != nil {
+			return nil, nil, fmt.Errorf("Expecting a column number; got '%s'", args[2])
+		}
+	}
+	fset := curFrame.Fset()
+	position := curFrame.Position()
+	if !position.IsValid() {
+		return nil, nil, errors.New("No current source position")
+	}
+	for _, l := range curFrame.Fn().Pkg.Locs() {
+		try := fset.Position(l.Pos())
+		if try.Filename != position.Filename || try.Line != line ||
+			(column != -1 && try.Column != column) {
+			continue
+		}
+		if l.Trace != nil {
+			return l.Trace, nil, nil
+		}
+		if l.Fn != nil {
+			return nil, l.Fn, nil
+		}
+	}
+	suffix := ""
+	if column != -1 {
+		suffix = fmt.Sprintf(", column %d", column)
+	}
+	return nil, nil, fmt.Errorf("Can't find statement in file %s at line %d%s",
+		position.Filename, line, suffix)
+}
+
+// ReturnValue evaluates exprs, a comma-separated list of expressions,
+// as the results of the function of fr, giving what the interpreter
+// needs to return them. Without expressions, the results are zero.
+func ReturnValue(fr *interp.Frame, exprs string) (interp.Value, error) {
+	results := fr.Fn().Signature.Results()
+	var args []ast.Expr
+	// Parse the list as the arguments of a call.
+	src := "_(" + exprs + ")"
+	if exprs != "" {
+		e, err := parser.ParseExpr(src)
+		if err != nil {
+			return nil, err
+		}
+		call, ok := e.(*ast.CallExpr)
+		if !ok {
+			return nil, fmt.Errorf("Can't parse '%s' as a list of expressions", exprs)
+		}
+		args = call.Args
+		if len(args) != results.Len() {
+			return nil, fmt.Errorf("%s returns %d values; got %d",
+				fr.Fn().Name(), results.Len(), len(args))
+		}
+	}
+	values := make([]interp.Value, results.Len())
+	for i := range values {
+		t := results.At(i).Type()
+		if args == nil {
+			values[i] = interp.Zero(t)
+			continue
+		}
+		x, err := evalOperand(src, args[i])
+		if err != nil {
+			return nil, err
+		}
+		if values[i], err = interp.ConvertValue(x, t); err != nil {
+			return nil, err
+		}
+	}
+	switch len(values) {
+	case 0:
+		return nil, nil
+	case 1:
+		return values[0], nil
+	}
+	return interp.Tuple(values), nil
+}
+
+// JumpToLine makes the frame we are stopped in continue at the
+// statement on line of its function, or at the one starting at column
+// if column isn't -1. It refuses to jump out of the current scope,
+// into a block other than the current one which starts with
+// phi-nodes, since we don't come from one of its predecessors, or to
+// code using SSA registers that may not have been computed on the way
+// to where we are stopped.
+func JumpToLine(line, column int) error {
+	fr := topFrame
+	if curFrame != fr {
+		return errors.New("Can only jump in the innermost frame; use 'frame 0' first")
+	}
+	if fr.Block() == nil || TraceEvent == ssa2.CALL_ENTER {
+		return errors.New("Can only jump when stopped at a statement")
+	}
+	if _, ok := fr.Block().Instrs[fr.PC()].(*ssa2.Trace); !ok {
+		return errors.New("Can only jump when stopped at a statement")
+	}
+	b, pc, trace := findTrace(fr.Fn(), line, column)
+	if trace == nil {
+		suffix := ""
+		if column != -1 {
+			suffix = fmt.Sprintf(", column %d", column)
+		}
+		return fmt.Errorf("Can't find a statement of %s at line %d%s",
+			fr.Fn().Name(), line, suffix)
+	}
+	if b != fr.Block() {
+		if _, ok := b.Instrs[0].(*ssa2.Phi); ok {
+			return fmt.Errorf("Can't jump to line %d: its block %d starts with "+
+				"phi-nodes, whose values depend on the block we come from",
+				line, b.Index)
+		}
+	}
+	if !operandsComputed(b, pc, fr.Block(), fr.PC()) {
+		return fmt.Errorf("Can't jump to line %d: its code uses values "+
+			"which may not have been computed on the way here", line)
+	}
+	scope := trace.Scope
+	if scope == nil {
+		scope = b.Scope
+	}
+	fn := fr.Fn()
+	if !scopeWithin(stmtScope(fn, scope), stmtScope(fn, fr.Scope())) {
+		return fmt.Errorf("Can't jump to line %d: it is outside the current scope", line)
+	}
+	fr.Jump(b, pc)
+	return nil
+}
+
+// A jumpPoint is where execution of a block starts: at instruction
+// pc, for the block jumped into, or else at its first instruction.
+type jumpPoint struct {
+	b  *ssa2.BasicBlock
+	pc uint
+}
+
+// operandsComputed reports whether the SSA registers used by the code
+// that may run after a jump to instruction pc of block b, up to the
+// function's return, are sure to have been computed, when we are
+// stopped at instruction curPC of block cur. A register is computed
+// if it was before the jump, because its block dominates cur or it
+// comes before curPC in cur, or if it is on every path from the jump
+// to its use. For phi-nodes, the operand of each edge must be
+// computed at the end of the block the edge leaves.
+func operandsComputed(b *ssa2.BasicBlock, pc uint, cur *ssa2.BasicBlock, curPC uint) bool {
+	index := func(instr ssa2.Instruction) uint {
+		for i, in := range instr.Block().Instrs {
+			if in == instr {
+				return uint(i)
+			}
+		}
+		return 0
+	}
+	computed := func(v ssa2.Value, defined map[ssa2.Value]bool) bool {
+		// Constants, parameters, globals and so on are always
+		// there.
+		def, ok := v.(ssa2.Instruction)
+		if !ok || defined[v] {
+			return true
+		}
+		db := def.Block()
+		if db == cur {
+			return index(def) < curPC
+		}
+		return db.Dominates(cur)
+	}
+
+	// The points execution may go through after the jump, and the
+	// points leading to each.
+	start := jumpPoint{b, pc}
+	points := []jumpPoint{start}
+	preds := make(map[jumpPoint][]jumpPoint)
+	seen := map[jumpPoint]bool{start: true}
+	for i := 0; i < len(points); i++ {
+		p := points[i]
+		for _, succ := range p.b.Succs {
+			q := jumpPoint{succ, 0}
+			preds[q] = append(preds[q], p)
+			if !seen[q] {
+				seen[q] = true
+				points = append(points, q)
+			}
+		}
+	}
+
+	// out[p] holds the registers sure to be computed by the end of
+	// the block started at p; a missing entry holds them all, as
+	// nothing is known yet.
+	out := make(map[jumpPoint]map[ssa2.Value]bool)
+	in := func(p jumpPoint) map[ssa2.Value]bool {
+		defined := make(map[ssa2.Value]bool)
+		if p == start {
+			return defined
+		}
+		first := true
+		for _, q := range preds[p] {
+			o, ok := out[q]
+			if !ok {
+				continue
+			}
+			for v := range defined {
+				if !o[v] {
+					delete(defined, v)
+				}
+			}
+			if first {
+				for v := range o {
+					defined[v] = true
+				}
+				first = false
+			}
+		}
+		return defined
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, p := range points {
+			defined := in(p)
+			for _, instr := range p.b.Instrs[p.pc:] {
+				if v, ok := instr.(ssa2.Value); ok {
+					defined[v] = true
+				}
+			}
+			if old, ok := out[p]; !ok || len(old) != len(defined) {
+				out[p] = defined
+				changed = true
+			}
+		}
+	}
+
+	var rands []*ssa2.Value
+	for _, p := range points {
+		defined := in(p)
+		for _, instr := range p.b.Instrs[p.pc:] {
+			if phi, ok := instr.(*ssa2.Phi); ok {
+				for i, edge := range phi.Edges {
+					for _, q := range preds[p] {
+						if q.b == p.b.Preds[i] && !computed(edge, out[q]) {
+							return false
+						}
+					}
+				}
+			} else {
+				rands = instr.Operands(rands[:0])
+				for _, rand := range rands {
+					if *rand != nil && !computed(*rand, defined) {
+						return false
+					}
+				}
+			}
+			if v, ok := instr.(ssa2.Value); ok {
+				defined[v] = true
+			}
+		}
+	}
+	return true
+}
+
+// findTrace finds the Trace instruction in fn for the statement which
+// starts first on line, or which starts at column if column isn't -1.
+// It gives the instruction's block and index as well.
+func findTrace(fn *ssa2.Function, line, column int) (*ssa2.BasicBlock, uint, *ssa2.Trace) {
+	fset := fn.Prog.Fset
+	var (
+		found   *ssa2.Trace
+		foundB  *ssa2.BasicBlock
+		foundPC uint
+	)
+	for _, b := range fn.Blocks {
+		for pc, instr := range b.Instrs {
+			trace, ok := instr.(*ssa2.Trace)
+			if !ok {
+				continue
+			}
+			position := fset.Position(trace.Start)
+			if position.Line != line || (column != -1 && position.Column != column) {
+				continue
+			}
+			if found == nil || trace.Start < found.Start {
+				found, foundB, foundPC = trace, b, uint(pc)
+			}
+		}
+	}
+	return foundB, foundPC, found
+}
+
+// scopeWithin reports whether scope is outer or nested inside it.
+func scopeWithin(scope, outer *ssa2.Scope) bool {
+	if scope == nil || outer == nil {
+		return scope == outer
+	}
+	for s := scope.Scope; s != nil; s = s.Parent() {
+		if s == outer.Scope {
+			return true
+		}
+	}
+	return false
+}
+
+// stmtScope returns the scope a statement whose innermost scope is
+// scope is in. That of an if, for, switch or select statement's
+// header is the statement's own implicit scope, which holds the
+// variables declared in the header; the statement itself is in the
+// scope around that.
+func stmtScope(fn *ssa2.Function, scope *ssa2.Scope) *ssa2.Scope {
+	if scope == nil || scope.Node() == nil {
+		return scope
+	}
+	switch (*scope.Node()).(type) {
+	case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt,
+		*ast.TypeSwitchStmt:
+		if parent := ssa2.ParentScope(fn, scope); parent != nil {
+			return parent
+		}
+	}
+	return scope
+}
@@ -0,0 +1,109 @@
+// Copyright 2013 Rocky Bernstein.
+// Auto-display expressions, shown each time the program stops.
+package gub
+
+import (
+	"fmt"
+	"go/parser"
+	"reflect"
+	"strings"
+
+	"github.com/rocky/ssa-interp"
+	"github.com/rocky/ssa-interp/interp"
+)
+
+// A Display is an expression to be evaluated and shown whenever we
+// stop. If Fn is not nil, it is shown only when we stop in Fn.
+type Display struct {
+	Id   int
+	Expr string
+	Fn   *ssa2.Function
+}
+
+// Displays are the auto-display expressions in the order they were
+// added.
+var Displays []*Display
+
+var displayNext = 1
+
+// DisplayAdd adds expr as an auto-display expression, shown only in
+// fn if fn is not nil. expr must parse as a Go expression.
+func DisplayAdd(expr string, fn *ssa2.Function) (*Display, error) {
+	if _, err := parser.ParseExpr(expr); err != nil {
+		return nil, err
+	}
+	d := &Display{Id: displayNext, Expr: expr, Fn: fn}
+	displayNext++
+	Displays = append(Displays, d)
+	return d, nil
+}
+
+// DisplayDelete removes the display numbered id and reports whether
+// there was one.
+func DisplayDelete(id int) bool {
+	for i, d := range Displays {
+		if d.Id == id {
+			Displays = append(Displays[:i], Displays[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// DisplayDeleteAll removes all auto-display expressions.
+func DisplayDeleteAll() {
+	Displays = nil
+}
+
+// inScope reports whether d is shown when stopped in the frame we are
+// focused on.
+func (d *Display) inScope() bool {
+	return d.Fn == nil || d.Fn == curFrame.Fn()
+}
+
+// Show evaluates d in the frame we are focused on and shows its
+// value as "num: expr = value".
+func (d *Display) Show() {
+	results, err := EvalExpr(d.Expr)
+	if err != nil {
+		// EvalExpr has already said what went wrong.
+		return
+	}
+	if results == nil {
+		Msg("%d: %s = nil", d.Id, d.Expr)
+		return
+	}
+	Msg("%d: %s = %s", d.Id, d.Expr, fmtResults(*results))
+}
+
+// String gives the expression of d, with the function it is scoped
+// to, if any, as in "info display".
+func (d *Display) String() string {
+	if d.Fn == nil {
+		return d.Expr
+	}
+	return fmt.Sprintf("%s (in %s)", d.Expr, d.Fn.Name())
+}
+
+// fmtResults formats the values of an evaluated expression, putting
+// several in parentheses.
+func fmtResults(results []reflect.Value) string {
+	if len(results) == 1 {
+		return interp.ToInspect(results[0].Interface())
+	}
+	strs := make([]string, len(results))
+	for i, v := range results {
+		strs[i] = interp.ToInspect(v.Interface())
+	}
+	return "(" + strings.Join(strs, ", ") + ")"
+}
+
+// showDisplays shows the auto-display expressions in scope where we
+// have stopped.
+func showDisplays() {
+	for _, d := range Displays {
+		if d.inScope() {
+			d.Show()
+		}
+	}
+}
@@ -5,6 +5,7 @@ package gub
 import (
 	"fmt"
 	"strings"
+	"code.google.com/p/go.tools/go/types"
 	"github.com/rocky/ssa-interp"
 	"github.com/rocky/ssa-interp/interp"
 )
@@ -39,27 +40,60 @@ func PrintInEnvironment(fr *interp.Frame, name string) bool {
 	}
 }
 
+// EnvLookup finds the variable that name refers to in frame fr,
+// stopped in scope, resolving it as the Go compiler would there:
+// inner variables shadow outer ones, a variable isn't visible before
+// its declaration, and variables of enclosing functions are reached
+// through the closure's free variables. It gives the SSA value for the
+// variable, its value in fr, and, for a variable with an address, the
+// scope it is declared in.
+//
+// Without scope information, we go by name alone.
 func EnvLookup(fr *interp.Frame, name string,
 	scope *ssa2.Scope) (ssa2.Value, interp.Value, *ssa2.Scope) {
 	fn := fr.Fn()
-	reg := fr.Var2Reg[name]
-	for ; scope != nil;  scope = ssa2.ParentScope(fn, scope) {
-		nameScope := ssa2.NameScope{
-			Name: name,
-			Scope: scope,
+	if obj, declScope := fn.LookupObject(name, scope, fr.StartP()); obj != nil {
+		if declScope.Scope == fn.Pkg.Object.Scope() {
+			if val := fn.Pkg.Var(name); val != nil {
+				return val, nil, nil
+			}
+			return nil, nil, nil
+		}
+		v, ok := obj.(*types.Var)
+		if !ok {
+			// A local constant or type hides any variable outside.
+			return nil, nil, nil
+		}
+		nameVal := fn.VarValue(v)
+		if _, isAlloc := nameVal.(*ssa2.Alloc); !isAlloc {
+			// A lifted variable is in whatever register it was
+			// last referred to by.
+			if ref := fr.ObjRefs[v]; ref != nil {
+				nameVal = ref
+			}
 		}
-		if i := fn.LocalsByName[nameScope]; i > 0 {
-			nameVal := fn.Locals[i-1]
-			val     := fr.Env()[nameVal]
-			return nameVal, val, nameVal.Scope
+		switch nameVal := nameVal.(type) {
+		case nil:
+			return nil, nil, nil
+		case *ssa2.Alloc:
+			return nameVal, fr.Env()[nameVal], declScope
 		}
+		return nameVal, frameValue(fr, nameVal), nil
+	}
+	// Not a source variable in scope: try SSA registers, such as
+	// t0, and without scope information, variables by name.
+	names := []string{name}
+	if scope == nil {
+		names = append(names, fr.Var2Reg[name])
 	}
-	names := []string{name, reg}
 	for _, name := range names {
 		for nameVal, val := range fr.Env() {
 			if name == nameVal.Name() {
 				switch nameVal := nameVal.(type) {
 				case *ssa2.Alloc:
+					if !nameVal.Live(fr.StartP()) {
+						continue
+					}
 					return nameVal, val, nameVal.Scope
 				default:
 					return nameVal, val, nil
@@ -67,14 +101,21 @@ func EnvLookup(fr *interp.Frame, name string,
 			}
 		}
 	}
-	// FIXME: Why we would find things here and not by the
-	// above scope lookup?
 	if val := fn.Pkg.Var(name); val != nil {
 		return val, nil, nil
 	}
 	return nil, nil, nil
 }
 
+// frameValue gives the value of v in fr. v may be a constant, which
+// DebugRefs can refer to, as well as a register.
+func frameValue(fr *interp.Frame, v ssa2.Value) interp.Value {
+	if c, ok := v.(*ssa2.Const); ok {
+		return fr.Get(c)
+	}
+	return fr.Env()[v]
+}
+
 // Could something like this go into interp-ssa?
 func GetFunction(name string) *ssa2.Function {
 	pkg := curFrame.Fn().Pkg
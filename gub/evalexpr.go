@@ -7,8 +7,10 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"go/ast"
 	"go/parser"
 	"code.google.com/p/go.tools/go/exact"
+	"code.google.com/p/go.tools/go/types"
 	"github.com/rocky/ssa-interp"
 	"github.com/rocky/ssa-interp/interp"
 	"github.com/rocky/go-fish"
@@ -18,10 +20,20 @@ import (
 // Convert between an interp.Value which the interpreter uses and reflect.Value which
 // eval uses. nameVal is used to get type information.
 func interp2reflectVal(interpVal interp.Value, nameVal ssa2.Value) reflect.Value {
-	v := DerefValue(interpVal)
-	// println("XXX 1 type",  interp.Type(v))
-	// println("XXX 1 value", interp.ToInspect(v))
-	return reflect.ValueOf(v)
+	return typed2reflectVal(typedValue(interpVal, nameVal))
+}
+
+// Structs, pointers and interfaces are handed to eval as
+// interp.TypedValues, which our selector callback understands. Other
+// values, such as numbers, strings and slices, are handed over as
+// themselves, so that eval's arithmetic, comparisons and indexing
+// work on them.
+func typed2reflectVal(tv interp.TypedValue) reflect.Value {
+	switch tv.Type.Underlying().(type) {
+	case *types.Struct, *types.Pointer, *types.Interface:
+		return reflect.ValueOf(tv)
+	}
+	return reflect.ValueOf(tv.Value)
 }
 
 func EvalIdentExpr(ctx *eval.Ctx, ident *eval.Ident, env *eval.Env) (
@@ -32,9 +44,6 @@ func EvalIdentExpr(ctx *eval.Ctx, ident *eval.Ident, env *eval.Env) (
 		return nil, false, nil
 	} else  {
 		if nameVal, interpVal, _ := EnvLookup(curFrame, name, curScope); interpVal != nil {
-			// FIXME for structures the interpreter has turned this into a slice
-			// we need to somehow undo that or keep track of the type name that this
-			// came from so we can get record selection correct.
 			reflectVal := interp2reflectVal(interpVal, nameVal)
 			return &reflectVal, false, nil
 		} else {
@@ -64,22 +73,33 @@ func EvalSelectorExpr(ctx *eval.Ctx, selector *eval.SelectorExpr,
 	x0    := (*x)[0]
 	xname := x0.Type().Name()
 
+	if tv, ok := x0.Interface().(interp.TypedValue); ok {
+		v, err := tv.Select(curFrame.I().Program(), curFrame.Fn().Pkg.Object, sel)
+		if err != nil {
+			return nil, true, err
+		}
+		val := typed2reflectVal(v)
+		return &val, true, nil
+	}
+
 	if x0.Kind() == reflect.Ptr {
 		// Special case for handling packages
 		if x0.Type() == reflect.TypeOf(curFrame.Fn().Pkg) {
 			pkg := x0.Interface().(*ssa2.Package)
 
 			if fn := pkg.Func(sel); fn != nil {
-				// Can't handle interp functions yet, but perhaps it happens
-				// to be a function in the static eval environment
+				// Prefer a function in the static eval environment, which
+				// eval can call itself
 				pkg_name := pkg.Object.Name()
 				pkg_env := env.Pkgs[pkg_name]
 				if fn, ok := pkg_env.Funcs[sel]; ok {
 					return &fn, true, nil
 				} else {
-					return nil, true,
-					errors.New("Can't handle functions that are not in 0xfaded/eval yet")
-
+					// eval can't call this. Calls of interpreted functions
+					// are handled in evalCallExpr, but only as a whole
+					// expression or as an argument of another such call.
+					val := reflect.ValueOf(interp.TypedValue{Type: fn.Signature, Value: fn})
+					return &val, true, nil
 				}
 			} else if v := pkg.Var(sel); v != nil {
 				if g, ok := curFrame.I().Global(sel, pkg); ok {
@@ -114,13 +134,8 @@ func EvalSelectorExpr(ctx *eval.Ctx, selector *eval.SelectorExpr,
 		}
 	}
 
-	if x0.Type().String() == "interp.structure" {
-		err = errors.New("selection for structures and interfaces not supported yet")
-	} else {
-		// println("XXX", x0.Type().Kind().String(), x0.Type().String())
-		err = errors.New(fmt.Sprintf("%s.%s undefined (%s has no field or method %s)",
-			xname, sel, xname, sel))
-	}
+	err = errors.New(fmt.Sprintf("%s.%s undefined (%s has no field or method %s)",
+		xname, sel, xname, sel))
 	return nil, true, err
 }
 
@@ -141,6 +156,25 @@ func EvalExpr(expr string) (*[]reflect.Value, error) {
 	if e, err := parser.ParseExpr(expr); err != nil {
 		Errmsg("Failed to parse expression '%s' (%v)\n", expr, err)
 		return nil, err
+	} else if tv, ok, err := evalPathExpr(e); ok {
+		if err != nil {
+			Errmsg("Error evaluating expression '%s' (%v)\n", expr, err)
+			return nil, err
+		}
+		// The results are only shown, so they keep their types for
+		// the printer.
+		results := []reflect.Value{reflect.ValueOf(tv)}
+		return &results, nil
+	} else if tvs, ok, err := evalInterpCall(expr, e); ok {
+		if err != nil {
+			Errmsg("Error evaluating expression '%s' (%v)\n", expr, err)
+			return nil, err
+		}
+		results := make([]reflect.Value, len(tvs))
+		for i, tv := range tvs {
+			results[i] = reflect.ValueOf(tv)
+		}
+		return &results, nil
 	} else if cexpr, errs := eval.CheckExpr(ctx, e, env); len(errs) != 0 {
 		Errmsg("Error checking expression '%s' (%v)\n", expr, errs)
 		return nil, errs[0]
@@ -156,6 +190,40 @@ func EvalExpr(expr string) (*[]reflect.Value, error) {
 	return nil, nil
 }
 
+// evalInterpCall evaluates e if it is a call of an interpreted
+// function. ok is false if e should be handed to eval instead.
+func evalInterpCall(src string, e ast.Expr) (results []interp.TypedValue, ok bool, err error) {
+	if call, isCall := e.(*ast.CallExpr); isCall {
+		return evalCallExpr(src, call)
+	}
+	return nil, false, nil
+}
+
+// evalAST checks and evaluates e, which is part of the expression
+// src, with eval.
+func evalAST(src string, e ast.Expr) (*[]reflect.Value, error) {
+	ctx := &eval.Ctx{src}
+	cexpr, errs := eval.CheckExpr(ctx, e, &evalEnv)
+	if len(errs) != 0 {
+		return nil, errs[0]
+	}
+	results, _, err := eval.EvalExpr(ctx, cexpr, &evalEnv)
+	return results, err
+}
+
+// evalPathExpr evaluates e with EvalPath if it is a path expression
+// that EvalPath can handle. ok is false if e should be handed to eval
+// instead.
+func evalPathExpr(e ast.Expr) (tv interp.TypedValue, ok bool, err error) {
+	if !isPathExpr(e) {
+		return tv, false, nil
+	}
+	if tv, err = EvalPath(e); err == errNotPath {
+		return tv, false, nil
+	}
+	return tv, true, err
+}
+
 // FIXME should an interp2reflect function be in interp?
 var myConvertFunc = func (r reflect.Value, rtyped bool) (reflect.Value, bool, error) {
 	switch v := r.Interface().(type) {
@@ -0,0 +1,380 @@
+// Copyright 2013 Rocky Bernstein.
+// Evaluation of "path" expressions: variable names followed by any
+// number of field or method selections, type assertions and pointer
+// indirections, e.g. p.x, (*p).y.(T).z or *q; and of calls of
+// interpreted functions. These are evaluated here on
+// interp.TypedValues rather than by 0xfaded/eval, since eval knows
+// nothing about the static types of interpreted values.
+package gub
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"code.google.com/p/go.tools/go/exact"
+	"code.google.com/p/go.tools/go/types"
+	"github.com/rocky/ssa-interp"
+	"github.com/rocky/ssa-interp/interp"
+)
+
+// errNotPath is returned by EvalPath when the expression isn't one it
+// handles, such as a function or constant name. The caller should then
+// fall back to eval.
+var errNotPath = errors.New("not a path expression")
+
+// typedValue pairs interpVal, the value of nameVal in the current
+// frame, with its static type. Variables, which the interpreter keeps
+// in *interp.Value cells, come back addressable; so do variables of an
+// enclosing function, which closures capture by address.
+func typedValue(interpVal interp.Value, nameVal ssa2.Value) interp.TypedValue {
+	switch nameVal.(type) {
+	case *ssa2.Alloc, *ssa2.Global, *ssa2.Capture:
+		if addr, ok := interpVal.(*interp.Value); ok && addr != nil {
+			return interp.NewTypedVariable(deref(nameVal.Type()), addr)
+		}
+	}
+	return interp.TypedValue{Type: nameVal.Type(), Value: interpVal}
+}
+
+// lookupTyped finds variable name in the current frame, or in the
+// current package.
+func lookupTyped(name string) (interp.TypedValue, error) {
+	nameVal, interpVal, _ := EnvLookup(curFrame, name, curScope)
+	if nameVal == nil {
+		return interp.TypedValue{}, errNotPath
+	}
+	if interpVal == nil {
+		if _, ok := nameVal.(*ssa2.Global); !ok {
+			return interp.TypedValue{}, errNotPath
+		}
+		g, ok := curFrame.I().Global(name, curFrame.Fn().Pkg)
+		if !ok {
+			return interp.TypedValue{}, errNotPath
+		}
+		interpVal = g
+	}
+	return typedValue(interpVal, nameVal), nil
+}
+
+// isPathExpr reports whether e is a path expression.
+func isPathExpr(e ast.Expr) bool {
+	switch e := e.(type) {
+	case *ast.Ident:
+		return true
+	case *ast.SelectorExpr:
+		return isPathExpr(e.X)
+	case *ast.TypeAssertExpr:
+		// e.Type is nil in x.(type), which is only valid in a type switch
+		return e.Type != nil && isPathExpr(e.X)
+	case *ast.StarExpr:
+		return isPathExpr(e.X)
+	case *ast.ParenExpr:
+		return isPathExpr(e.X)
+	}
+	return false
+}
+
+// EvalPath evaluates path expression e in the current frame.
+func EvalPath(e ast.Expr) (interp.TypedValue, error) {
+	return evalPath("", e, false)
+}
+
+// EvalLvalue evaluates e, part of expression src, as the left-hand
+// side of an assignment: a path expression which may also index
+// arrays, slices and maps, as in a.b[i].c or m["key"].
+func EvalLvalue(src string, e ast.Expr) (interp.TypedValue, error) {
+	tv, err := evalPath(src, e, true)
+	if err == errNotPath {
+		err = fmt.Errorf("cannot assign to %s", types.ExprString(e))
+	}
+	return tv, err
+}
+
+// evalPath does the work of EvalPath and EvalLvalue; index says
+// whether index expressions are allowed. (For eval we leave indexing
+// to 0xfaded/eval, which our callbacks give plain slices.)
+func evalPath(src string, e ast.Expr, index bool) (interp.TypedValue, error) {
+	switch e := e.(type) {
+	case *ast.Ident:
+		return lookupTyped(e.Name)
+
+	case *ast.SelectorExpr:
+		if id, ok := e.X.(*ast.Ident); ok {
+			if nameVal, _, _ := EnvLookup(curFrame, id.Name, curScope); nameVal == nil {
+				// Perhaps a package-qualified variable
+				pkg := curFrame.I().Program().PackagesByName[id.Name]
+				if pkg == nil {
+					return interp.TypedValue{}, errNotPath
+				}
+				v := pkg.Var(e.Sel.Name)
+				if v == nil {
+					return interp.TypedValue{}, errNotPath
+				}
+				g, ok := curFrame.I().Global(e.Sel.Name, pkg)
+				if !ok {
+					return interp.TypedValue{}, fmt.Errorf("%s name lookup failed unexpectedly for %s",
+						pkg, e.Sel.Name)
+				}
+				return typedValue(g, v), nil
+			}
+		}
+		x, err := evalPath(src, e.X, index)
+		if err != nil {
+			return interp.TypedValue{}, err
+		}
+		return x.Select(curFrame.I().Program(), curFrame.Fn().Pkg.Object, e.Sel.Name)
+
+	case *ast.TypeAssertExpr:
+		x, err := evalPath(src, e.X, index)
+		if err != nil {
+			return interp.TypedValue{}, err
+		}
+		t, err := evalType(e.Type)
+		if err != nil {
+			return interp.TypedValue{}, err
+		}
+		return x.TypeAssert(t)
+
+	case *ast.StarExpr:
+		x, err := evalPath(src, e.X, index)
+		if err != nil {
+			return interp.TypedValue{}, err
+		}
+		return x.Deref()
+
+	case *ast.ParenExpr:
+		return evalPath(src, e.X, index)
+
+	case *ast.IndexExpr:
+		if !index {
+			break
+		}
+		x, err := evalPath(src, e.X, index)
+		if err != nil {
+			return interp.TypedValue{}, err
+		}
+		idx, err := evalOperand(src, e.Index)
+		if err != nil {
+			return interp.TypedValue{}, err
+		}
+		return x.Index(idx)
+	}
+	return interp.TypedValue{}, errNotPath
+}
+
+// evalType returns the type denoted by type expression e, as seen
+// from the current package. Only named types, pointers, slices and
+// maps are handled.
+func evalType(e ast.Expr) (types.Type, error) {
+	switch e := e.(type) {
+	case *ast.Ident:
+		obj := curFrame.Fn().Pkg.Object.Scope().Lookup(e.Name)
+		if obj == nil {
+			obj = types.Universe.Lookup(e.Name)
+		}
+		if tn, ok := obj.(*types.TypeName); ok {
+			return tn.Type(), nil
+		}
+		return nil, fmt.Errorf("%s is not a type", e.Name)
+
+	case *ast.SelectorExpr:
+		if id, ok := e.X.(*ast.Ident); ok {
+			pkg := curFrame.I().Program().PackagesByName[id.Name]
+			if pkg == nil {
+				return nil, fmt.Errorf("undefined: %s", id.Name)
+			}
+			if t := pkg.Type(e.Sel.Name); t != nil {
+				return t.Object().Type(), nil
+			}
+			return nil, fmt.Errorf("%s.%s is not a type", id.Name, e.Sel.Name)
+		}
+
+	case *ast.StarExpr:
+		elem, err := evalType(e.X)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewPointer(elem), nil
+
+	case *ast.ArrayType:
+		if e.Len == nil {
+			elem, err := evalType(e.Elt)
+			if err != nil {
+				return nil, err
+			}
+			return types.NewSlice(elem), nil
+		}
+
+	case *ast.MapType:
+		key, err := evalType(e.Key)
+		if err != nil {
+			return nil, err
+		}
+		elem, err := evalType(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewMap(key, elem), nil
+
+	case *ast.ParenExpr:
+		return evalType(e.X)
+	}
+	return nil, fmt.Errorf("unsupported type expression %T", e)
+}
+
+// evalFunc evaluates fun, the function part of a call, if it denotes
+// an interpreted function or function value. Functions that 0xfaded/eval
+// has a host version of, such as fmt.Println, are left to eval.
+func evalFunc(fun ast.Expr) (interp.TypedValue, error) {
+	switch e := fun.(type) {
+	case *ast.Ident:
+		if fv, err := lookupTyped(e.Name); err != errNotPath {
+			return fv, err
+		}
+		if fn := curFrame.Fn().Pkg.Func(e.Name); fn != nil {
+			return interp.TypedValue{Type: fn.Signature, Value: fn}, nil
+		}
+		return interp.TypedValue{}, errNotPath
+
+	case *ast.SelectorExpr:
+		if id, ok := e.X.(*ast.Ident); ok {
+			if nameVal, _, _ := EnvLookup(curFrame, id.Name, curScope); nameVal == nil {
+				pkg := curFrame.I().Program().PackagesByName[id.Name]
+				if pkg == nil {
+					return interp.TypedValue{}, errNotPath
+				}
+				if _, ok := evalEnv.Pkgs[id.Name].Funcs[e.Sel.Name]; ok {
+					return interp.TypedValue{}, errNotPath
+				}
+				if fn := pkg.Func(e.Sel.Name); fn != nil {
+					return interp.TypedValue{Type: fn.Signature, Value: fn}, nil
+				}
+			}
+		}
+	}
+	if !isPathExpr(fun) {
+		return interp.TypedValue{}, errNotPath
+	}
+	return EvalPath(fun)
+}
+
+// evalOperand evaluates e, an operand such as a call argument,
+// giving an interp.TypedValue or, for what 0xfaded/eval computes, a
+// host value. src is the text of the whole expression, which e is
+// part of.
+func evalOperand(src string, e ast.Expr) (interface{}, error) {
+	if tv, ok, err := evalPathExpr(e); ok {
+		return tv, err
+	}
+	if results, ok, err := evalInterpCall(src, e); ok {
+		if err != nil {
+			return nil, err
+		}
+		if len(results) != 1 {
+			return nil, fmt.Errorf("multiple-value or no-value call %s used as a single value",
+				types.ExprString(e))
+		}
+		return results[0], nil
+	}
+	if id, ok := e.(*ast.Ident); ok && id.Name == "nil" {
+		return nil, nil
+	}
+	if c, ok := constOperand(e); ok {
+		return c, nil
+	}
+	results, err := evalAST(src, e)
+	if err != nil {
+		return nil, err
+	}
+	if results == nil || len(*results) != 1 {
+		return nil, fmt.Errorf("%s is not a single value", types.ExprString(e))
+	}
+	return (*results)[0].Interface(), nil
+}
+
+// constOperand returns the value of e if it is a numeric, character
+// or string literal, possibly negated. Integers come back as int64
+// and floating-point numbers as float64; interp.ConvertValue then
+// treats them as untyped constants.
+func constOperand(e ast.Expr) (interface{}, bool) {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		v := exact.MakeFromLiteral(e.Value, e.Kind)
+		switch v.Kind() {
+		case exact.Int:
+			if n, ok := exact.Int64Val(v); ok {
+				return n, true
+			}
+		case exact.Float:
+			f, _ := exact.Float64Val(v)
+			return f, true
+		case exact.String:
+			return exact.StringVal(v), true
+		}
+	case *ast.UnaryExpr:
+		if e.Op == token.SUB {
+			switch x, _ := constOperand(e.X); x := x.(type) {
+			case int64:
+				return -x, true
+			case float64:
+				return -x, true
+			}
+		}
+	case *ast.ParenExpr:
+		return constOperand(e.X)
+	}
+	return nil, false
+}
+
+// evalCallExpr evaluates call expression e, part of the expression
+// src, if it calls an interpreted function; ok is false if it should
+// be handed to eval instead.
+func evalCallExpr(src string, e *ast.CallExpr) (results []interp.TypedValue, ok bool, err error) {
+	fn, err := evalFunc(e.Fun)
+	if err == errNotPath {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, true, err
+	}
+	sig, isFunc := fn.Type.Underlying().(*types.Signature)
+	if !isFunc {
+		return nil, true, fmt.Errorf("cannot call non-function (type %s)", fn.Type)
+	}
+	params := sig.Params()
+	nparams := params.Len()
+	variadic := sig.IsVariadic() && !e.Ellipsis.IsValid()
+	if variadic && len(e.Args) < nparams-1 || !variadic && len(e.Args) != nparams {
+		return nil, true, fmt.Errorf("wrong number of arguments in call: have %d, want %d",
+			len(e.Args), nparams)
+	}
+	args := make([]interp.Value, 0, nparams)
+	var extra []interp.Value
+	for i, arg := range e.Args {
+		var t types.Type
+		if variadic && i >= nparams-1 {
+			t = params.At(nparams - 1).Type().(*types.Slice).Elem()
+		} else {
+			t = params.At(i).Type()
+		}
+		x, err := evalOperand(src, arg)
+		if err != nil {
+			return nil, true, err
+		}
+		v, err := interp.ConvertValue(x, t)
+		if err != nil {
+			return nil, true, fmt.Errorf("argument %d: %s", i+1, err)
+		}
+		if variadic && i >= nparams-1 {
+			extra = append(extra, v)
+		} else {
+			args = append(args, v)
+		}
+	}
+	if variadic {
+		args = append(args, extra)
+	}
+	results, err = CallFunction(fn, args)
+	return results, true, err
+}
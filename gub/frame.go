@@ -16,6 +16,7 @@ const MAXSTACKSHOW = 50  // maximum number of frame entries to show
 
 func CurFrame() *interp.Frame { return curFrame }
 func TopFrame() *interp.Frame { return topFrame }
+func CurScope() *ssa2.Scope { return curScope }
 
 func frameInit(fr *interp.Frame) {
 	topFrame = fr
@@ -61,6 +62,7 @@ func AdjustFrame(frameNum int, absolutePos bool) {
 	frame, frameNum := getFrame(frameNum, absolutePos)
 	if frame == nil { return }
 	curFrame = frame
+	curScope = frame.Scope()
 	frameIndex = frameNum
 	event := ssa2.CALL_ENTER
 	if (0 == frameIndex) {
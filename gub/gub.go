@@ -32,7 +32,6 @@ var Term string
 
 var Maxwidth int
 var initial_cwd string
-var GUB_RESTART_CMD string
 
 // history_file is file name where history entries were and are to be saved. If
 // the empty string, no history is saved and no history read in initially.
@@ -85,7 +84,6 @@ func gnuReadLineSetup() {
 func init() {
 	widthstr := os.Getenv("COLS")
 	initial_cwd, _ = os.Getwd()
-	GUB_RESTART_CMD = os.Getenv("GUB_RESTART_CMD")
 	if len(widthstr) == 0 {
 		Maxwidth = 80
 	} else if i, err := strconv.Atoi(widthstr); err == nil {
@@ -16,14 +16,28 @@ const slash = string(os.PathSeparator)
 type testDatum struct {
 	gofile  string
 	baseName string
+	interpOpts string // -interp options; "S" if empty
 }
 
 // Note we should order these from simple to more complex
 var testData = []testDatum {
 	{gofile: "gcd",   baseName: "stepping"},
 	{gofile: "panic", baseName: "panic"},
+	{gofile: "catch", baseName: "catch"},
 	{gofile: "gcd",   baseName: "frame"},
+	{gofile: "gcd",   baseName: "list"},
+	{gofile: "gcd",   baseName: "display"},
+	{gofile: "gcd",   baseName: "control"},
+	{gofile: "gcd",   baseName: "jump"},
+	{gofile: "range", baseName: "jumprange"},
+	{gofile: "jumpif", baseName: "jumpif"},
+	{gofile: "gcd",   baseName: "session"},
+	{gofile: "scope", baseName: "scope"},
 	{gofile: "expr",  baseName: "eval"},
+	{gofile: "selector", baseName: "selector"},
+	{gofile: "print", baseName: "print"},
+	{gofile: "pointsto", baseName: "pointsto"},
+	{gofile: "postmortem", baseName: "postmortem", interpOpts: "SP"},
 }
 
 // Runs debugger on go program with baseName. Then compares output.
@@ -40,8 +54,14 @@ func run(t *testing.T, test testDatum) bool {
 		log.Fatal(err)
 	}
 
-	// fmt.Println("+++1", "../tortoise", "-run", "-interp=S", gubOpt, goFile)
-	got, err  := exec.Command("../tortoise", "-run", "-interp=S", gubOpt, goFile).Output()
+	interpOpts := test.interpOpts
+	if interpOpts == "" {
+		interpOpts = "S"
+	}
+	interpOpt := "-interp=" + interpOpts
+
+	// fmt.Println("+++1", "../tortoise", "-run", interpOpt, gubOpt, goFile)
+	got, err  := exec.Command("../tortoise", "-run", interpOpt, gubOpt, goFile).Output()
 
 	if err != nil {
 		fmt.Printf("%s", got)
@@ -108,6 +128,9 @@ func TestInterp(t *testing.T) {
 	// want tracebacks anyway.
 	os.Setenv("GOTRACEBACK", "2")
 
+	// The session test saves its session here and reads it back.
+	defer os.Remove("testdata" + slash + "session.save")
+
 	for _, test := range testData {
 		if !run(t, test) {
 			failures = append(failures, test.baseName)
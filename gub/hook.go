@@ -33,17 +33,45 @@ func skipEvent(fr *interp.Frame, event ssa2.TraceEvent) bool {
 	if event == ssa2.BREAKPOINT {
 		bps := BreakpointFindByPos(fr.StartP())
 		for _, bpnum := range bps {
-			bp := Breakpoints[bpnum]
-			if !bp.Enabled { continue }
-			// FIXME: check things like the condition
+			if !Breakpoints[bpnum].stops(fr) { continue }
 			curBpnum = bpnum
-			bp.Hits ++
 			break
 		}
+		// Otherwise we stop only in the function "advance" is going to.
+		return curBpnum == NoBp && fr.Fn() != advanceFn
+	} else if isCatchEvent(event) {
+		if bpnum, ok := catchpointHit(fr, event); ok {
+			curBpnum = bpnum
+			return false
+		}
+		// Without a catchpoint, we still show a panic when stepping.
+		return !(event == ssa2.PANIC && interp.GlobalStmtTracing())
+	} else if trace := stmtBreakpoint(fr, event); trace != nil {
+		for _, bpnum := range BreakpointFindByPos(fr.StartP()) {
+			if Breakpoints[bpnum].stops(fr) {
+				curBpnum = bpnum
+				return false
+			}
+		}
+		// Otherwise we stop only where "advance" is going to.
+		return trace != advanceTrace
 	}
 	return false
 }
 
+// stmtBreakpoint gives the statement with a breakpoint that fr has
+// reached, if that and not stepping is why event stops us.
+func stmtBreakpoint(fr *interp.Frame, event ssa2.TraceEvent) *ssa2.Trace {
+	if fr.StmtStepping() || fr.Block() == nil || fr.PC() >= uint(len(fr.Block().Instrs)) {
+		return nil
+	}
+	trace, ok := fr.Block().Instrs[fr.PC()].(*ssa2.Trace)
+	if !ok || !trace.Breakpoint || trace.Event != event {
+		return nil
+	}
+	return trace
+}
+
 // Compute the gub read prompt. It has the command count and
 // a goroutine number if we aren't in the main goroutine.
 func computePrompt() string {
@@ -61,19 +89,37 @@ func computePrompt() string {
 // FIXME: remove instr
 func GubTraceHook(fr *interp.Frame, instr *ssa2.Instruction, event ssa2.TraceEvent) {
 	if !fr.I().TraceEventMask[event] { return }
-	gubLock.Lock()
-    defer gubLock.Unlock()
-	if skipEvent(fr, event) { return }
+	if inDebuggerCall(fr) {
+		// We are running a function called from the command loop
+		// below, and so already hold gubLock.
+		if !CallBreakpoints { return }
+	} else {
+		gubLock.Lock()
+		defer gubLock.Unlock()
+	}
+	if fr.I() != interp.GetInterpreter() {
+		return // a goroutine left over from before a restart
+	}
+	if skipEvent(fr, event) || skipUntil(fr, instr) { return }
+	advanceDone()
 	frameInit(fr)
 	// FIXME: use unconditionally
 	if instr == nil {
 		instr = &fr.Block().Instrs[fr.PC()]
 	}
-	if event == ssa2.BREAKPOINT && Breakpoints[curBpnum].Kind == "Function" {
+	// Without a breakpoint, this is the function "advance" was
+	// going to.
+	if event == ssa2.BREAKPOINT && (curBpnum == NoBp || Breakpoints[curBpnum].Kind == "Function") {
 		event = ssa2.CALL_ENTER
 	}
 	TraceEvent = event
+	if event == ssa2.POST_MORTEM {
+		Msg("The program panicked and nothing can recover. Entering post-mortem debugging.")
+	}
 	printLocInfo(topFrame, instr, event)
+	showDisplays()
+
+	loadAutoSession()
 
 	line := ""
 	var err error
@@ -86,39 +132,43 @@ func GubTraceHook(fr *interp.Frame, instr *ssa2.Instruction, event ssa2.TraceEve
         if err != nil {
             break
         }
-		line = strings.Trim(line, " \t\n")
-		args  := strings.Split(line, " ")
-		if len(args) == 0 || len(args[0]) == 0 {
-			Msg("Empty line skipped")
-			gnureadline.RemoveHistory(gnureadline.HistoryLength()-1)
-			continue
-		} else if args[0][0] == '#' {
+		if !runCommand(line) {
 			gnureadline.RemoveHistory(gnureadline.HistoryLength()-1)
-			Msg(line) // echo line but do nothing
-			continue
 		}
+	}
+}
 
-		name := args[0]
-		CmdArgstr = strings.TrimLeft(line[len(name):], " ")
-		if newname := LookupCmd(name); newname != "" {
-			name = newname
-		}
-		cmd := Cmds[name];
+// runCommand runs the debugger command line, read from the terminal
+// or from a file of commands. It reports whether the line is worth
+// keeping in the command history.
+func runCommand(line string) bool {
+	line = strings.Trim(line, " \t\n")
+	args  := strings.Split(line, " ")
+	if len(args) == 0 || len(args[0]) == 0 {
+		Msg("Empty line skipped")
+		return false
+	} else if args[0][0] == '#' {
+		Msg(line) // echo line but do nothing
+		return false
+	}
 
-		if cmd != nil {
-			if ArgCountOK(cmd.Min_args, cmd.Max_args, args) {
-				Cmds[name].Fn(args)
-			}
-			continue
-		}
+	name := args[0]
+	CmdArgstr = strings.TrimLeft(line[len(name):], " ")
+	if newname := LookupCmd(name); newname != "" {
+		name = newname
+	}
+	cmd := Cmds[name];
 
-		if len(args) > 0 {
-			if !WhatisName(args[0]) {
-				gnureadline.RemoveHistory(gnureadline.HistoryLength()-1)
-			}
-		} else {
-			gnureadline.RemoveHistory(gnureadline.HistoryLength()-1)
-			Errmsg("Unknown command %s\n", cmd)
+	if cmd != nil {
+		if ArgCountOK(cmd.Min_args, cmd.Max_args, args) {
+			Cmds[name].Fn(args)
 		}
+		return true
 	}
+
+	if len(args) > 0 {
+		return WhatisName(args[0])
+	}
+	Errmsg("Unknown command %s\n", cmd)
+	return false
 }
@@ -23,12 +23,19 @@ func deref(typ types.Type) types.Type {
 }
 
 
+// LocalsLookup returns one more than the index in fr's locals of the
+// variable name refers to in scope, or 0 if it isn't a local with an
+// address.
 func LocalsLookup(fr *interp.Frame, name string, scope *ssa2.Scope) uint {
-	nameScope := ssa2.NameScope{
-		Name: name,
-		Scope: scope,
+	nameVal, _, _ := EnvLookup(fr, name, scope)
+	if l, ok := nameVal.(*ssa2.Alloc); ok {
+		for i, local := range fr.Fn().Locals {
+			if local == l {
+				return uint(i+1)
+			}
+		}
 	}
-	return fr.Fn().LocalsByName[nameScope]
+	return 0
 }
 
 
@@ -42,6 +49,7 @@ func PrintLocal(fr *interp.Frame, i uint) {
 	if scope != nil {
 		scopeStr = fmt.Sprintf(" scope %d", scope.ScopeId())
 	}
+	scopeStr += LiveNote(fr, l)
 	if name[0] == 't' && fr.Reg2Var[name] != "" {
 		Msg("%3d:\t%s %s (%s) = %s%s %s", i, fr.Reg2Var[name], name,
 			deref(l.Type()), interp.ToInspect(v), scopeStr,
@@ -53,6 +61,20 @@ func PrintLocal(fr *interp.Frame, i uint) {
 	}
 }
 
+// LiveNote says why local l isn't live where fr is stopped, if it
+// isn't: either its declaration hasn't run yet or we have left the
+// scope it is declared in.
+func LiveNote(fr *interp.Frame, l *ssa2.Alloc) string {
+	pos := fr.StartP()
+	if l.Live(pos) {
+		return ""
+	}
+	if start, _ := l.LiveRange(); pos <= start {
+		return " (not yet declared)"
+	}
+	return " (out of scope)"
+}
+
 func PrintIfLocal(fr *interp.Frame, varname string) bool {
 	if i := LocalsLookup(curFrame, varname, curScope); i != 0 {
 		PrintLocal(curFrame, i-1)
@@ -214,7 +236,7 @@ func WhatisName(name string) bool {
 		Msg("  %s", ssa2.FmtRange(myfn, v.Pos(), v.EndP()))
 		Msg("  %s", v.Type())
 		if g, ok := curFrame.I().Global(name, pkg); ok {
-			Msg("  %s", interp.ToInspect(*g))
+			Msg("  %s", interp.ToInspectTyped(*g, deref(v.Type())))
 		}
 	} else if c := pkg.Const(name); c != nil {
 		printConstantInfo(c, name, pkg)
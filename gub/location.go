@@ -28,8 +28,12 @@ func init() {
 		ssa2.FOR_INIT        : "lo:",
 		ssa2.FOR_COND        : "lo?",
 		ssa2.FOR_ITER        : "lo+",
+		ssa2.GO_START        : "go>",
+		ssa2.GO_EXIT         : "<go",
 		ssa2.MAIN            : "m()",
 		ssa2.PANIC           : "oX ",  // My attempt at skull and cross bones
+		ssa2.POST_MORTEM     : "oX!",
+		ssa2.RECOVER         : "oR ",
 		ssa2.RANGE_STMT      : "...",
 		ssa2.SELECT_TYPE     : "sel",
 		ssa2.SWITCH_COND     : "sw?",
@@ -75,8 +79,12 @@ func printLocInfo(fr *interp.Frame, inst *ssa2.Instruction,
 				Msg("%s nil", fn.Params[i])
 			}
 		}
-	case ssa2.PANIC:
-		// fmt.Printf("panic arg: %s\n", fr.Get(instr.X))
+	case ssa2.PANIC, ssa2.POST_MORTEM, ssa2.RECOVER:
+		if tv, ok := fr.PanicValue(); ok {
+			Msg("panic value: %s", tv)
+		}
+	case ssa2.GO_START, ssa2.GO_EXIT:
+		Msg("goroutine %d", fr.GoNum())
 	}
 
 	Msg(fr.PositionRange())
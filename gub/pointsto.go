@@ -0,0 +1,164 @@
+// Copyright 2013 Rocky Bernstein.
+// Points-to queries for "info pointsto".
+package gub
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+
+	"code.google.com/p/go.tools/go/types"
+	"github.com/rocky/ssa-interp"
+	"github.com/rocky/ssa-interp/pointer"
+)
+
+// The points-to analysis of the program, run the first time it is
+// needed.
+var pointsTo *pointer.Result
+
+// PointsTo gives the points-to analysis of the program we are
+// debugging, starting at the init and main functions of the main
+// packages.
+func PointsTo() *pointer.Result {
+	if pointsTo == nil {
+		prog := curFrame.I().Program()
+		pointsTo = pointer.Analyze(prog, mainRoots(prog))
+	}
+	return pointsTo
+}
+
+// isVar reports whether v, as found by EnvLookup, is the address of a
+// variable rather than a register.
+func isVar(v ssa2.Value) bool {
+	switch v.(type) {
+	case *ssa2.Alloc, *ssa2.Global, *ssa2.Capture:
+		return true
+	}
+	return false
+}
+
+// pointsToExpr gives the objects the value of expr may point to, and
+// the static type of that value. expr is made of variables and
+// registers, &, *, selectors and index expressions. Since the analysis
+// doesn't tell fields or array elements apart, a field or element of
+// a value that isn't a pointer may point to what the value does.
+func pointsToExpr(r *pointer.Result, expr ast.Expr) ([]*pointer.Object, types.Type, error) {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return pointsToExpr(r, e.X)
+	case *ast.Ident:
+		v, _, _ := EnvLookup(curFrame, e.Name, curScope)
+		if v == nil {
+			return nil, nil, fmt.Errorf("Name %s not found in environment", e.Name)
+		}
+		if isVar(v) {
+			// What the variable holds, not its address.
+			return r.PointsToVar(v), deref(v.Type()), nil
+		}
+		return r.PointsTo(v), v.Type(), nil
+	case *ast.UnaryExpr:
+		if id, ok := e.X.(*ast.Ident); ok && e.Op == token.AND {
+			v, _, _ := EnvLookup(curFrame, id.Name, curScope)
+			if v != nil && isVar(v) {
+				return r.PointsTo(v), v.Type(), nil
+			}
+			return nil, nil, fmt.Errorf("can't take the address of %s", id.Name)
+		}
+	case *ast.StarExpr:
+		objs, typ, err := pointsToExpr(r, e.X)
+		if err != nil {
+			return nil, nil, err
+		}
+		p, ok := typ.Underlying().(*types.Pointer)
+		if !ok {
+			return nil, nil, fmt.Errorf("%s isn't a pointer", types.ExprString(e.X))
+		}
+		return contents(r, objs), p.Elem(), nil
+	case *ast.SelectorExpr:
+		objs, typ, err := pointsToExpr(r, e.X)
+		if err != nil {
+			return nil, nil, err
+		}
+		if p, ok := typ.Underlying().(*types.Pointer); ok {
+			objs, typ = contents(r, objs), p.Elem()
+		}
+		if st, ok := typ.Underlying().(*types.Struct); ok {
+			for i, n := 0, st.NumFields(); i < n; i++ {
+				if f := st.Field(i); f.Name() == e.Sel.Name {
+					return objs, f.Type(), nil
+				}
+			}
+		}
+		return nil, nil, fmt.Errorf("%s has no field %s", types.ExprString(e.X), e.Sel.Name)
+	case *ast.IndexExpr:
+		objs, typ, err := pointsToExpr(r, e.X)
+		if err != nil {
+			return nil, nil, err
+		}
+		if p, ok := typ.Underlying().(*types.Pointer); ok {
+			if a, ok := p.Elem().Underlying().(*types.Array); ok {
+				return contents(r, objs), a.Elem(), nil
+			}
+		}
+		switch t := typ.Underlying().(type) {
+		case *types.Array:
+			return objs, t.Elem(), nil
+		case *types.Slice:
+			return contents(r, objs), t.Elem(), nil
+		case *types.Map:
+			return contents(r, objs), t.Elem(), nil
+		}
+		return nil, nil, fmt.Errorf("can't index %s", types.ExprString(e.X))
+	}
+	return nil, nil, fmt.Errorf("can't analyze expression %s", types.ExprString(expr))
+}
+
+// contents gives what is stored in any of objs may point to.
+func contents(r *pointer.Result, objs []*pointer.Object) []*pointer.Object {
+	var all []*pointer.Object
+	seen := make(map[*pointer.Object]bool)
+	for _, o := range objs {
+		for _, p := range r.Contents(o) {
+			if !seen[p] {
+				seen[p] = true
+				all = append(all, p)
+			}
+		}
+	}
+	return all
+}
+
+// PrintPointsTo shows what the value of expr, as seen where the
+// current frame is stopped, may point to.
+func PrintPointsTo(expr string) {
+	e, err := parser.ParseExpr(expr)
+	if err != nil {
+		Errmsg("%s", err)
+		return
+	}
+	r := PointsTo()
+	if !r.Reachable(curFrame.Fn()) {
+		Errmsg("%s isn't reachable from main; no points-to information", curFrame.Fn())
+		return
+	}
+	objs, _, err := pointsToExpr(r, e)
+	if err != nil {
+		Errmsg("%s", err)
+		return
+	}
+	if len(objs) == 0 {
+		Msg("%s points to nothing the analysis knows of", expr)
+		return
+	}
+	Section("%s may point to", expr)
+	for _, o := range objs {
+		Msg("  %s", o)
+	}
+	if id, ok := e.(*ast.Ident); ok {
+		nameVal, _, _ := EnvLookup(curFrame, id.Name, curScope)
+		if alloc, ok := nameVal.(*ssa2.Alloc); ok && r.Escapes(alloc) && !alloc.Heap {
+			Msg("%s escapes its function", expr)
+		}
+	}
+}
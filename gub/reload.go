@@ -0,0 +1,134 @@
+// Copyright 2013 Rocky Bernstein.
+// Reloading changed source code for the "reload" command.
+package gub
+
+import (
+	"go/token"
+
+	"github.com/rocky/ssa-interp"
+	"github.com/rocky/ssa-interp/interp"
+)
+
+// liveFrames gives the frames which haven't returned, of all
+// goroutines.
+func liveFrames() []*interp.Frame {
+	var frames []*interp.Frame
+	seen := make(map[*interp.Frame]bool)
+	tops := []*interp.Frame{topFrame}
+	for _, goTop := range curFrame.I().GoTops() {
+		tops = append(tops, goTop.Fr)
+	}
+	for _, fr := range tops {
+		for ; fr != nil && !seen[fr]; fr = fr.Caller(0) {
+			seen[fr] = true
+			if fr.Status() != interp.StComplete {
+				frames = append(frames, fr)
+			}
+		}
+	}
+	return frames
+}
+
+// Reload rebuilds the packages whose source files have changed, and
+// reports what changed. Breakpoints in the files are set again at the
+// same lines, and the analyses which depend on the program's code are
+// thrown away.
+func Reload() {
+	prog := curFrame.I().Program()
+	frames := liveFrames()
+	running := make(map[*ssa2.Function]bool)
+	for _, fr := range frames {
+		running[fr.Fn()] = true
+	}
+	result, err := prog.Reload(func(fn *ssa2.Function) bool { return running[fn] })
+	if err != nil {
+		Errmsg("Reload failed: %s", err)
+		return
+	}
+	if len(result.Packages) == 0 {
+		Msg("No source files have changed")
+		return
+	}
+	for _, file := range result.Files {
+		delete(sourceCache, file.Name())
+	}
+	callGraph = nil
+	pointsTo = nil
+	rebindBreakpoints(prog, result)
+
+	for _, pkg := range result.Packages {
+		Msg("Reloaded package %s", pkg.Object.Path())
+	}
+	if len(result.Changed) == 0 {
+		Msg("No function's code changed")
+	}
+	for _, fn := range result.Changed {
+		Msg("  %s changed", fn)
+	}
+	if len(result.Running) == 0 {
+		return
+	}
+	Section("Frames still running old code")
+	stale := make(map[*ssa2.Function]bool)
+	for _, fn := range result.Running {
+		stale[fn] = true
+	}
+	for _, fr := range frames {
+		if stale[fr.Fn()] {
+			Msg("  goroutine %d: %s", fr.GoNum(), fr.FnAndParamString())
+		}
+	}
+	Msg("Their next calls run the new code, except through function values made before the reload.")
+}
+
+// rebindBreakpoints moves the breakpoints in the source files which
+// Reload replaced to the same lines of the new files, or disables
+// them if there is nothing to stop at on the line any more.
+func rebindBreakpoints(prog *ssa2.Program, result *ssa2.ReloadResult) {
+	fset := prog.Fset
+	for _, bp := range Breakpoints {
+		if bp.Deleted || bp.Kind == "Catch" || !result.Stale(fset, bp.Pos) {
+			continue
+		}
+		position := fset.Position(bp.Pos)
+		found := false
+	pkgs:
+		for _, pkg := range result.Packages {
+			for _, l := range pkg.Locs() {
+				try := fset.Position(l.Pos())
+				if try.Filename != position.Filename || try.Line != position.Line {
+					continue
+				}
+				if bp.Kind == "Function" {
+					if l.Fn == nil {
+						continue
+					}
+					l.Fn.Breakpoint = true
+				} else if l.Trace != nil {
+					l.Trace.Breakpoint = true
+				} else {
+					continue
+				}
+				moveBreakpoint(bp, l.Pos())
+				found = true
+				break pkgs
+			}
+		}
+		if !found {
+			bp.Enabled = false
+			Msg("Breakpoint %d at %s no longer has a statement; disabled",
+				bp.Id, ssa2.FmtPos(fset, bp.Pos))
+		}
+	}
+}
+
+// moveBreakpoint makes bp a breakpoint at pos.
+func moveBreakpoint(bp *Breakpoint, pos token.Pos) {
+	for i := range BrkptLocs {
+		if BrkptLocs[i].bpnum == bp.Id {
+			BrkptLocs[i].pos = pos
+		}
+	}
+	bp.Pos = pos
+	bp.EndP = pos
+}
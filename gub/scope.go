@@ -0,0 +1,121 @@
+// Copyright 2013 Rocky Bernstein.
+// The variables visible where we are stopped, scope by scope.
+package gub
+
+import (
+	"go/ast"
+	"sort"
+
+	"code.google.com/p/go.tools/go/types"
+	"github.com/rocky/ssa-interp"
+	"github.com/rocky/ssa-interp/interp"
+)
+
+// A ScopeVar is a variable declared in a scope of a stopped frame.
+type ScopeVar struct {
+	Name     string
+	Type     types.Type
+	Value    interp.Value // nil if we can't tell
+	Known    bool         // whether Value is known
+	Shadowed bool         // hidden by a variable of an inner scope
+	Declared bool         // whether its declaration has run
+}
+
+// ScopeVars gives the variables declared in scope, sorted by name,
+// noting which are declared and visible where fr is stopped. inner is
+// the innermost scope there, for telling which variables are
+// shadowed.
+func ScopeVars(fr *interp.Frame, scope, inner *ssa2.Scope) []ScopeVar {
+	fn := fr.Fn()
+	pos := fr.StartP()
+	var vars []ScopeVar
+	names := scope.Names()
+	sort.Strings(names)
+	for _, name := range names {
+		obj, ok := scope.Lookup(name).(*types.Var)
+		if !ok {
+			continue
+		}
+		sv := ScopeVar{Name: name, Type: obj.Type(), Declared: true}
+		if pos.IsValid() && obj.Pos() >= pos {
+			sv.Declared = false
+		} else if _, declScope := fn.LookupObject(name, inner, pos); declScope != scope {
+			sv.Shadowed = true
+		} else if nameVal, val, _ := EnvLookup(fr, name, inner); nameVal != nil {
+			sv.Value, sv.Known = varValue(obj, nameVal, val), true
+		}
+		vars = append(vars, sv)
+	}
+	return vars
+}
+
+// varValue gives the value of variable obj, given the SSA value that
+// holds it and that value in a frame. Allocs and captured variables
+// hold the address of the variable rather than its value.
+func varValue(obj *types.Var, nameVal ssa2.Value, val interp.Value) interp.Value {
+	switch nameVal.(type) {
+	case *ssa2.Alloc, *ssa2.Capture:
+		if !types.IsIdentical(nameVal.Type(), obj.Type()) {
+			return DerefValue(val)
+		}
+	}
+	return val
+}
+
+// isPackageScope reports whether scope is outside of any function:
+// the package scope or a file scope.
+func isPackageScope(fn *ssa2.Function, scope *ssa2.Scope) bool {
+	if scope.Scope == fn.Pkg.Object.Scope() {
+		return true
+	}
+	if node := scope.Node(); node != nil {
+		_, isFile := (*node).(*ast.File)
+		return isFile
+	}
+	return false
+}
+
+// printScopeVar shows sv, one of the variables ScopeVars gives.
+func printScopeVar(sv ScopeVar) {
+	switch {
+	case !sv.Declared:
+		Msg("  %s %s (not yet declared)", sv.Name, sv.Type)
+	case sv.Shadowed:
+		Msg("  %s %s (shadowed)", sv.Name, sv.Type)
+	case !sv.Known:
+		Msg("  %s %s = ?", sv.Name, sv.Type)
+	default:
+		Msg("  %s %s = %s", sv.Name, sv.Type,
+			interp.ToInspectTyped(sv.Value, sv.Type))
+	}
+}
+
+// PrintScopeVars shows the variables of scope, marking those that are
+// shadowed or not yet declared where fr is stopped.
+func PrintScopeVars(fr *interp.Frame, scope, inner *ssa2.Scope) {
+	for _, sv := range ScopeVars(fr, scope, inner) {
+		printScopeVar(sv)
+	}
+}
+
+// PrintVisibleVars shows the variables which are in scope where fr is
+// stopped, innermost scope first, down to those of the outermost
+// function enclosing fr's.
+func PrintVisibleVars(fr *interp.Frame, inner *ssa2.Scope) {
+	fn := fr.Fn()
+	for scope := inner; scope != nil && !isPackageScope(fn, scope); scope = ssa2.ParentScope(fn, scope) {
+		var visible []ScopeVar
+		for _, sv := range ScopeVars(fr, scope, inner) {
+			if sv.Declared && !sv.Shadowed {
+				visible = append(visible, sv)
+			}
+		}
+		if len(visible) == 0 {
+			continue
+		}
+		Section("scope %d", scope.ScopeId())
+		for _, sv := range visible {
+			printScopeVar(sv)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2013 Rocky Bernstein.
+// Saving and restoring debugger sessions as scripts of gub commands.
+package gub
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rocky/ssa-interp/interp"
+)
+
+// BreakpointCommands gives the commands which set the breakpoints and
+// catchpoints again, with their conditions, ignore counts and enabled
+// flags. Line breakpoints are set by absolute file name, so that the
+// commands work wherever we are stopped, and from any directory.
+func BreakpointCommands() []string {
+	var cmds []string
+	for _, bp := range Breakpoints {
+		if bp.Deleted {
+			continue
+		}
+		if bp.Kind == "Catch" {
+			cmds = append(cmds, "catch "+catchWhat(*bp))
+		} else {
+			position := curFrame.Fset().Position(bp.Pos)
+			filename := position.Filename
+			if abs, err := filepath.Abs(filename); err == nil {
+				filename = abs
+			}
+			cmds = append(cmds, fmt.Sprintf("breakpoint %s:%d %d",
+				filename, position.Line, position.Column))
+		}
+		if bp.Condition != "" {
+			cmds = append(cmds, "condition $bpnum "+bp.Condition)
+		}
+		if bp.Ignore > 0 {
+			cmds = append(cmds, fmt.Sprintf("ignore $bpnum %d", bp.Ignore))
+		}
+		if !bp.Enabled {
+			cmds = append(cmds, "disable $bpnum")
+		}
+	}
+	return cmds
+}
+
+// SessionCommands gives the commands which restore the settings,
+// aliases, breakpoints and auto-display expressions of this session.
+// Settings which are on or off are given only where they differ from
+// gub's defaults.
+func SessionCommands() []string {
+	var cmds []string
+	if !*Highlight {
+		cmds = append(cmds, "set highlight off")
+	}
+	if interp.InstTracing() {
+		cmds = append(cmds, "set trace on")
+	}
+	if CallBreakpoints {
+		cmds = append(cmds, "set callbreak on")
+	}
+	cmds = append(cmds,
+		fmt.Sprintf("set print depth %d", interp.PrintDepth),
+		fmt.Sprintf("set print elements %d", interp.PrintElements))
+	for _, alias := range userAliases {
+		cmds = append(cmds, fmt.Sprintf("alias %s %s", alias, Aliases[alias]))
+	}
+	cmds = append(cmds, BreakpointCommands()...)
+	for _, d := range Displays {
+		if d.Fn == nil {
+			cmds = append(cmds, "display "+d.Expr)
+		} else {
+			cmds = append(cmds, fmt.Sprintf("display %s.%s: %s",
+				d.Fn.Pkg.Object.Name(), d.Fn.Name(), d.Expr))
+		}
+	}
+	return cmds
+}
+
+// WriteCommands writes cmds, one per line, to file filename, which
+// "source" or -cmdfile can then read.
+func WriteCommands(filename string, cmds []string) error {
+	var script []byte
+	for _, cmd := range cmds {
+		script = append(script, cmd+"\n"...)
+	}
+	return ioutil.WriteFile(filename, script, 0644)
+}
+
+// Source runs the debugger commands in file filename, until one of
+// them resumes the program.
+func Source(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for InCmdLoop && scanner.Scan() {
+		runCommand(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// sessionDir, in the home directory, has a session file for each
+// program debugged interactively. It is read when gub first stops in
+// the program, and written when gub quits or restarts the program.
+const sessionDir = ".gub.d"
+
+// sessionLoaded is set once gub, used interactively, has looked for a
+// session file to restore; only then is the session saved.
+var sessionLoaded bool
+
+// sessionFile gives the name of the session file for the program
+// being debugged: its main file's absolute path with the separators
+// turned into "!", in sessionDir. It is empty if there is no home
+// directory or no main file.
+func sessionFile() string {
+	home := os.Getenv("HOME")
+	if home == "" {
+		return ""
+	}
+	prog := curFrame.I().Program()
+	for _, pkg := range prog.AllPackages() {
+		if pkg.Object.Name() != "main" || pkg.Func("main") == nil {
+			continue
+		}
+		filename := prog.Fset.Position(pkg.Func("main").Pos()).Filename
+		if filename == "" {
+			return ""
+		}
+		if abs, err := filepath.Abs(filename); err == nil {
+			filename = abs
+		}
+		filename = strings.Replace(filename, string(os.PathSeparator), "!", -1)
+		return filepath.Join(home, sessionDir, filename)
+	}
+	return ""
+}
+
+// loadAutoSession restores the session saved by the last interactive
+// run of gub on the program, the first time we stop.
+func loadAutoSession() {
+	if sessionLoaded || inputReader != nil {
+		return
+	}
+	sessionLoaded = true
+	filename := sessionFile()
+	if filename == "" {
+		return
+	}
+	if _, err := os.Stat(filename); err != nil {
+		return
+	}
+	Msg("Restoring session from %s", filename)
+	InCmdLoop = true
+	if err := Source(filename); err != nil {
+		Errmsg("Can't read session file %s: %s", filename, err)
+	}
+}
+
+// SaveAutoSession saves the session for the next interactive run of
+// gub on the program.
+func SaveAutoSession() {
+	if !sessionLoaded {
+		return
+	}
+	filename := sessionFile()
+	if filename == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		Errmsg("Can't save session: %s", err)
+		return
+	}
+	if err := WriteCommands(filename, SessionCommands()); err != nil {
+		Errmsg("Can't save session: %s", err)
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright 2013 Rocky Bernstein.
+// Source-code listing, with a cache of file lines and Go syntax
+// highlighting.
+package gub
+
+import (
+	"bytes"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/mgutz/ansi"
+)
+
+// sourceFile holds the lines of a source file, both as is and with
+// terminal highlighting.
+type sourceFile struct {
+	lines       []string
+	highlighted []string
+}
+
+var sourceCache = make(map[string]*sourceFile)
+
+var (
+	termKeyword = ansi.ColorCode("+b")
+	termString  = ansi.ColorCode("green")
+	termComment = ansi.ColorCode("cyan")
+	termNumber  = ansi.ColorCode("magenta")
+)
+
+// getSource returns the lines of filename, reading them only the
+// first time.
+func getSource(filename string) (*sourceFile, error) {
+	if sf := sourceCache[filename]; sf != nil {
+		return sf, nil
+	}
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimSuffix(string(src), "\n")
+	sf := &sourceFile{
+		lines:       strings.Split(text, "\n"),
+		highlighted: highlightSource(src),
+	}
+	sourceCache[filename] = sf
+	return sf, nil
+}
+
+// SourceLine returns line number line of filename, highlighted if
+// highlighting is on. ok is false if there is no such line.
+func SourceLine(filename string, line int) (text string, ok bool) {
+	sf, err := getSource(filename)
+	if err != nil || line < 1 || line > len(sf.lines) {
+		return "", false
+	}
+	if *Highlight && line <= len(sf.highlighted) {
+		return sf.highlighted[line-1], true
+	}
+	return sf.lines[line-1], true
+}
+
+// SourceLineCount returns the number of lines in filename.
+func SourceLineCount(filename string) int {
+	if sf, err := getSource(filename); err == nil {
+		return len(sf.lines)
+	}
+	return 0
+}
+
+// highlightSource colors the tokens of Go source src for the
+// terminal and returns the result as lines. Tokens that span lines,
+// such as raw strings and block comments, are colored on each line.
+func highlightSource(src []byte) []string {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+
+	var b bytes.Buffer
+	last := 0
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		var color string
+		switch {
+		case tok.IsKeyword():
+			color = termKeyword
+		case tok == token.STRING || tok == token.CHAR:
+			color = termString
+		case tok == token.COMMENT:
+			color = termComment
+		case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+			color = termNumber
+		default:
+			continue
+		}
+		start := file.Offset(pos)
+		end := start + len(lit)
+		if start < last || end > len(src) {
+			continue
+		}
+		b.Write(src[last:start])
+		// Reapply the color after each newline inside the token.
+		for i, part := range strings.Split(string(src[start:end]), "\n") {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString(color + part + termReset)
+		}
+		last = end
+	}
+	b.Write(src[last:])
+	return strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+}
+
+// listSize is the number of lines "list" shows.
+var listSize = 10
+
+// Where the last listing left off, so that "list" without a location
+// continues it, and "list -" shows what came before it.
+var (
+	listFilename string
+	listFirst    int
+	listLast     int
+	listStopPos  token.Pos // Trace start of the stop last listed around
+)
+
+// breakpointMarks returns "B" for lines of filename with an enabled
+// breakpoint, and "b" for those which only have disabled ones.
+func breakpointMarks(fset *token.FileSet, filename string) map[int]string {
+	marks := make(map[int]string)
+	for _, bp := range Breakpoints {
+		if bp.Deleted {
+			continue
+		}
+		position := fset.Position(bp.Pos)
+		if position.Filename != filename {
+			continue
+		}
+		if bp.Enabled {
+			marks[position.Line] = "B"
+		} else if marks[position.Line] == "" {
+			marks[position.Line] = "b"
+		}
+	}
+	return marks
+}
+
+// ListLines shows lines first to last of filename. Lines with
+// breakpoints are marked, and if the frame we are focused on is
+// stopped in the range, its line is marked with "->" and underlined
+// with the column span of the statement being traced.
+func ListLines(filename string, first, last int) {
+	count := SourceLineCount(filename)
+	if count == 0 {
+		Errmsg("Can't read source file %s", filename)
+		return
+	}
+	if first < 1 {
+		first = 1
+	}
+	if last > count {
+		last = count
+	}
+	if first > last {
+		Errmsg("Line number %d out of range; %s has %d lines.", first, filename, count)
+		return
+	}
+
+	fset := curFrame.Fset()
+	marks := breakpointMarks(fset, filename)
+	start := fset.Position(curFrame.StartP())
+	end := fset.Position(curFrame.EndP())
+	stopped := start.IsValid() && start.Filename == filename
+
+	for line := first; line <= last; line++ {
+		text, _ := SourceLine(filename, line)
+		bpMark := marks[line]
+		if bpMark == "" {
+			bpMark = " "
+		}
+		curMark := "  "
+		if stopped && line == start.Line {
+			curMark = "->"
+		}
+		Msg("%4d%s%s\t%s", line, bpMark, curMark, text)
+		if stopped && line == start.Line {
+			plain := ""
+			if sf, err := getSource(filename); err == nil {
+				plain = sf.lines[line-1]
+			}
+			Msg("       \t%s", columnSpan(plain, start, end))
+		}
+	}
+	listFilename, listFirst, listLast = filename, first, last
+}
+
+// columnSpan returns a line which, printed under line, marks the
+// columns from start to end with carets. Tabs in line are copied so
+// that the carets line up.
+func columnSpan(line string, start, end token.Position) string {
+	endCol := len(line) + 1
+	if end.IsValid() && end.Line == start.Line && end.Column > start.Column {
+		endCol = end.Column
+	}
+	var b bytes.Buffer
+	for i := 0; i < start.Column-1 && i < len(line); i++ {
+		if line[i] == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	if endCol <= start.Column {
+		endCol = start.Column + 1
+	}
+	b.WriteString(strings.Repeat("^", endCol-start.Column))
+	return b.String()
+}
+
+// ListAround lists the lines of filename centered on line.
+func ListAround(filename string, line int) {
+	first := line - listSize/2
+	if first < 1 {
+		first = 1
+	}
+	ListLines(filename, first, first+listSize-1)
+}
+
+// ListContinue lists the next lines after the last listing, or, the
+// first time after stopping, the lines around the stop.
+func ListContinue() {
+	position := curFrame.Position()
+	if listFilename == "" || curFrame.StartP() != listStopPos {
+		if !position.IsValid() {
+			Errmsg("No current source position to list around")
+			return
+		}
+		listStopPos = curFrame.StartP()
+		ListAround(position.Filename, position.Line)
+		return
+	}
+	ListLines(listFilename, listLast+1, listLast+listSize)
+}
+
+// ListBefore lists the lines before the last listing.
+func ListBefore() {
+	if listFilename == "" {
+		ListContinue()
+		return
+	}
+	if listFirst <= 1 {
+		Errmsg("Already at start of %s.", listFilename)
+		return
+	}
+	ListLines(listFilename, listFirst-listSize, listFirst-1)
+}
+
+// ListLocation lists around location, which is a function name as for
+// "break", a line number in the file of the last listing or stop, or
+// filename:line.
+func ListLocation(location string) {
+	if fn := GetFunction(location); fn != nil {
+		position := fn.Prog.Fset.Position(fn.Pos())
+		if !position.IsValid() {
+			Errmsg("No source position for function %s", location)
+			return
+		}
+		ListAround(position.Filename, position.Line)
+		return
+	}
+	filename := listFilename
+	if filename == "" {
+		filename = curFrame.Position().Filename
+	}
+	lineStr := location
+	if i := strings.LastIndex(location, ":"); i >= 0 {
+		filename = FindSourceFile(location[:i])
+		lineStr = location[i+1:]
+	}
+	var line int
+	if _, err := fmt.Sscanf(lineStr, "%d", &line); err != nil || line < 1 {
+		Errmsg("Expecting a function name, a line number or file:line; got '%s'", location)
+		return
+	}
+	ListAround(filename, line)
+}
+
+// FindSourceFile returns the name, as the program's FileSet has it,
+// of the source file that name refers to: a file with that name, or
+// the same absolute path, or else one whose path ends in name. If
+// there is none, it returns name itself.
+func FindSourceFile(name string) string {
+	abs := func(name string) string {
+		if a, err := filepath.Abs(name); err == nil {
+			return a
+		}
+		return filepath.Clean(name)
+	}
+	absName := abs(name)
+	found, suffixed := "", ""
+	curFrame.Fset().Iterate(func(f *token.File) bool {
+		if f.Name() == name || abs(f.Name()) == absName {
+			found = f.Name()
+			return false
+		}
+		if suffixed == "" && strings.HasSuffix(f.Name(), "/"+name) {
+			suffixed = f.Name()
+		}
+		return true
+	})
+	switch {
+	case found != "":
+		return found
+	case suffixed != "":
+		return suffixed
+	}
+	return name
+}
@@ -0,0 +1,13 @@
+# Test of catchpoints
+# Use with catch.go
+set highlight off
+catch panic string
+catch recover
+info break
+continue
+# Should be stopped at the recover in the first div()
+catch panic errorString
+continue
+# Should be stopped at the panic in the second div()
+info break
+quit
@@ -0,0 +1,15 @@
+package main
+
+func div(a, b int) (q int) {
+	defer func() {
+		if recover() != nil {
+			q = -1
+		}
+	}()
+	return a / b
+}
+
+func main() {
+	div(1, 0)
+	div(2, 0)
+}
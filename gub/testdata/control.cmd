@@ -0,0 +1,15 @@
+# Test of "advance", "until" and "return"
+# Use with gcd.go
+set highlight off
+step
+step
+# Should now be in gcd(5,3) - the first time
+advance 16
+# Should now be at line 16, not having stopped at lines 10 to 14
+until
+# Should now be at line 19
+step
+# Should now be in gcd(2,3) - recursively
+return 7
+# gcd(5,3) should now be returning 7
+quit
@@ -0,0 +1,14 @@
+# Test of "display", "undisplay" and "info display"
+# Use with gcd.go
+set highlight off
+display gcd: a
+display gcd: b
+info display
+step
+step
+# Should now be in gcd(5,3) and showing a and b
+undisplay 1
+next
+undisplay 7
+info display
+quit
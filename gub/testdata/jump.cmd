@@ -0,0 +1,15 @@
+# Test of "jump"
+# Use with gcd.go
+set highlight off
+step
+step
+next
+next
+next
+next
+# Should now be at line 14, after swapping a and b
+jump 30
+# Go back and run the swap again
+jump 10
+next
+quit
@@ -0,0 +1,15 @@
+# Test of "jump" past an if header, and out of a nested scope
+# Use with jumpif.go
+set highlight off
+next
+next
+# Should now be at line 7, the first if's condition
+# jump 16 -- into the second if, after which s, set at line 10, is used
+jump 16
+# jump 10 -- past the first if, in the same scope
+jump 10
+break 13
+continue
+# jump 15 -- out of the {} block, though in the same basic block
+jump 15
+quit
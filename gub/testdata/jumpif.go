@@ -0,0 +1,19 @@
+package main
+
+import "fmt"
+
+func main() {
+	a := 7
+	if a > 5 {
+		fmt.Println("big")
+	}
+	s := fmt.Sprint(a)
+	{
+		t := s + "!"
+		fmt.Println(t)
+	}
+	if a > 6 {
+		fmt.Println("bigger")
+	}
+	fmt.Println(s)
+}
@@ -0,0 +1,15 @@
+# Test of "jump" into loops, whose values haven't been computed
+# Use with range.go
+set highlight off
+next
+next
+# Should now be at line 7, before the loops
+# jump 9 -- into the body of a range over a map
+jump 9
+# jump 13 -- into the body of a range over a string
+jump 13
+# jump 15 -- uses n, set in the first loop
+jump 15
+# Back to the start, in the same block
+jump 6
+quit
@@ -0,0 +1,15 @@
+# Test of "list"
+# Use with gcd.go
+set highlight off
+step
+# list -- around where we are stopped
+list
+# list - -- the lines before
+list -
+# list gcd -- around a function
+list gcd
+# list 14 -- around a line
+list 14
+# list testdata/gcd.go:30 -- past the end
+list testdata/gcd.go:30
+quit
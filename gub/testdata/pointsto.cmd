@@ -0,0 +1,14 @@
+# Test of points-to queries on variables and expressions
+# Use with pointsto.go
+set highlight off
+break 14
+continue
+# info pointsto p -- through the closure's capture of p
+info pointsto p
+# info pointsto p.next -- a field of what p points to
+info pointsto p.next
+# info pointsto &p -- the variable p itself
+info pointsto &p
+# info pointsto p.next.next -- next is never set in the object a points to
+info pointsto p.next.next
+quit
@@ -0,0 +1,17 @@
+package main
+
+import "fmt"
+
+type node struct {
+	next *node
+}
+
+func main() {
+	a := &node{}
+	b := &node{next: a}
+	p := b
+	f := func() *node {
+		return p.next
+	}
+	fmt.Println(f() == a)
+}
@@ -0,0 +1,10 @@
+# Test of post-mortem debugging
+# Use with postmortem.go and -interp=SP
+set highlight off
+continue
+# Should be stopped where f() panicked
+bt
+# y should still be 42: the deferred call hasn't run
+eval y
+eval d
+quit
@@ -0,0 +1,22 @@
+package main
+
+func f(x, d int) int {
+	y := x * 2
+	// Post-mortem debugging should see y before this zeroes it.
+	defer func() { y = 0 }()
+	return y / d
+}
+
+func main() {
+	safe()
+	f(21, 0)
+}
+
+// safe's panic is recovered, so post-mortem debugging doesn't stop
+// there.
+func safe() (err interface{}) {
+	defer func() { err = recover() }()
+	var m map[string]int
+	m["x"] = 1
+	return nil
+}
@@ -0,0 +1,27 @@
+# Test of showing values according to their types
+# Use with print.go
+set highlight off
+# eval p -- struct field names
+eval p
+# eval m -- map keys sorted
+eval m
+# eval n -- pointer cycle
+eval n
+# eval nums
+eval nums
+# eval nested
+eval nested
+# eval ch -- channel state
+eval ch
+# eval s -- dynamic type of an interface
+eval s
+set print elements 3
+# eval nums -- truncated
+eval nums
+set print depth 1
+# eval n -- depth limited
+eval n
+# eval nested -- depth limited
+eval nested
+set print
+quit
@@ -0,0 +1,35 @@
+package main
+
+import "fmt"
+
+type Point struct{ X, Y int }
+
+type Node struct {
+	Val  int
+	Next *Node
+}
+
+var p = Point{1, 2}
+var m = map[string]int{"c": 3, "a": 1, "b": 2}
+var n = ring()
+var nums = []int{1, 2, 3, 4, 5}
+var nested = [][]int{{1, 2}, {3}}
+var ch = closedChan()
+var s interface{} = p
+
+func ring() *Node {
+	n := &Node{Val: 1}
+	n.Next = &Node{2, n}
+	return n
+}
+
+func closedChan() chan int {
+	ch := make(chan int, 2)
+	ch <- 7
+	close(ch)
+	return ch
+}
+
+func main() {
+	fmt.Println(p, len(m), n.Val, nums, nested, len(ch), s)
+}
@@ -0,0 +1,16 @@
+package main
+
+import "fmt"
+
+func main() {
+	counts := map[string]int{"go": 1}
+	n := 0
+	for k, v := range counts {
+		fmt.Println(k, v)
+		n += v
+	}
+	for i, r := range "gub" {
+		fmt.Println(i, r)
+	}
+	fmt.Println(n)
+}
@@ -0,0 +1,26 @@
+# Test of variable lookup in nested scopes and closures
+# Use with scope.go
+set highlight off
+next
+# locals x -- not declared until this statement has run
+locals x
+next
+# eval x -- now declared
+eval x
+break 10
+break 13
+continue
+# eval x -- the inner x, which shadows the outer one
+eval x
+# eval y -- from the enclosing scope
+eval y
+continue
+# eval x -- the outer x, captured by the closure
+eval x
+# set var x = 5 -- through the closure's capture of x
+set var x = 5
+next
+next
+# locals z -- the closure's own local, in scope to the end of its body
+locals z
+quit
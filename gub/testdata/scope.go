@@ -0,0 +1,18 @@
+package main
+
+import "fmt"
+
+func main() {
+	x := 1
+	y := "outer"
+	{
+		x := 2
+		fmt.Println(x, y)
+	}
+	f := func() int {
+		var z [1]int
+		z[0] = x + 10
+		return z[0]
+	}
+	fmt.Println(x, f())
+}
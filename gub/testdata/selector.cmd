@@ -0,0 +1,47 @@
+# Test of struct and interface selection, calls and assignment
+# Use with selector.go
+set highlight off
+next
+next
+next
+# eval n.Name -- field through a pointer
+eval n.Name
+# eval n.X -- promoted field of embedded Point
+eval n.X
+# eval n.Point.Y
+eval n.Point.Y
+# eval (*n).Name
+eval (*n).Name
+# eval s.(Point).X -- type assertion
+eval s.(Point).X
+# eval s.(*Point) -- failing type assertion
+eval s.(*Point)
+# eval n.Z
+eval n.Z
+# eval n.Sum() -- promoted method call
+eval n.Sum()
+# call s.Sum() -- method call through an interface
+call s.Sum()
+# eval div(7, 2) -- function call
+eval div(7, 2)
+# eval div(1, 0) -- call that panics
+eval div(1, 0)
+# eval n.X -- still stopped in main
+eval n.X
+# set var n.X = 10 -- field through a pointer
+set var n.X = 10
+# eval n.Sum()
+eval n.Sum()
+# set var n.Name = 5 -- type mismatch
+set var n.Name = 5
+# set var counts["go"] = 7 -- map entry
+set var counts["go"] = 7
+# eval counts
+eval counts
+# set var primes[1] = 4 -- slice element
+set var primes[1] = 4
+# eval primes
+eval primes
+# set var primes[3] = 1 -- out of range
+set var primes[3] = 1
+quit
@@ -0,0 +1,29 @@
+package main
+
+import "fmt"
+
+type Point struct{ X, Y int }
+
+func (p Point) Sum() int { return p.X + p.Y }
+
+type Named struct {
+	Point
+	Name string
+}
+
+type Shape interface {
+	Sum() int
+}
+
+func main() {
+	n := &Named{Point{1, 2}, "origin"}
+	s := Shape(n.Point)
+	fmt.Println(n.Name, s.Sum())
+}
+
+func div(a, b int) int {
+	return a / b
+}
+
+var counts = map[string]int{"go": 1}
+var primes = []int{2, 3, 5}
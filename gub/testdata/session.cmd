@@ -0,0 +1,14 @@
+# Test of "source", "condition", "ignore" and "save"
+# Use with gcd.go
+set highlight off
+source testdata/session.gub
+info break
+continue
+# Should be stopped at line 16 in gcd(1,2); gcd(2,3) was ignored
+eval a
+info break
+# Save the session, with breakpoints by absolute file name, and read
+# it back: the breakpoints are set again as 2 and 3
+save session testdata/session.save
+source testdata/session.save
+quit
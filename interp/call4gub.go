@@ -0,0 +1,75 @@
+// Copyright 2013 Rocky Bernstein.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interp
+
+// Calls of interpreted functions made by the debugger, e.g. for
+// "eval f(x)" while the program is stopped.
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// CallFunction calls fn, an interpreted *ssa2.Function or closure,
+// with arguments args on a new frame whose caller is fr, the frame
+// the debugger is stopped in. The call runs on the debugger's own
+// (host) goroutine, but counts as part of fr's goroutine.
+//
+// Statement stepping is turned off for the duration of the call, so
+// trace events come only from breakpoints and panics; the debugger
+// decides whether to stop for them. If the call panics and doesn't
+// recover, the panic unwinds the called frames, running their
+// deferred calls as usual, and is returned as an error; fr and its
+// goroutine are left as they were.
+func CallFunction(fr *Frame, fn Value, args []Value) (result Value, err error) {
+	i := fr.i
+	goTop := i.goTops[fr.goNum]
+	savedTop, savedTracing, savedMode := goTop.Fr, fr.tracing, i.TraceMode
+	fr.tracing = TRACE_STEP_NONE
+	i.TraceMode &= ^EnableStmtTracing
+	fr.debugCalls++
+	defer func() {
+		fr.debugCalls--
+		fr.tracing = savedTracing
+		i.TraceMode = savedMode
+		goTop.Fr = savedTop
+		if p := recover(); p != nil {
+			if i.isStopped() {
+				panic(p) // keep unwinding; see Restart
+			}
+			err = callPanicError(p)
+		}
+	}()
+	return call(i, fr.goNum, fr, fn, args), nil
+}
+
+// inDebuggerCall reports whether fr was called, directly or
+// indirectly, by CallFunction. Panics in such frames aren't swallowed
+// by runFrame but passed up to CallFunction.
+func (fr *Frame) inDebuggerCall() bool {
+	for caller := fr.caller; caller != nil; caller = caller.caller {
+		if caller.debugCalls > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// callPanicError describes panic p, which ended a call made by
+// CallFunction, in the manner of the top-level error handler in
+// Interpret.
+func callPanicError(p interface{}) error {
+	switch p := p.(type) {
+	case exitPanic:
+		return fmt.Errorf("program called os.Exit(%d)", int(p))
+	case targetPanic:
+		return fmt.Errorf("panic: %s", toString(p.v))
+	case runtime.Error:
+		return fmt.Errorf("panic: %s", p.Error())
+	case string:
+		return fmt.Errorf("panic: %s", p)
+	}
+	return fmt.Errorf("panic: unexpected type: %T", p)
+}
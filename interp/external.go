@@ -10,6 +10,7 @@ package interp
 import (
 	"math"
 	"os"
+	"regexp"
 	"runtime"
 	"syscall"
 	"time"
@@ -76,6 +77,17 @@ var externals = map[string]externalFn{
 	"math.Float64bits":                ext۰math۰Float64bits,
 	"math.Float64frombits":            ext۰math۰Float64frombits,
 	"math.Min":                        ext۰math۰Min,
+	"net.runtimeNano":                 ext۰net۰runtimeNano,
+	"net.runtime_pollClose":           ext۰net۰runtime_pollClose,
+	"net.runtime_pollOpen":            ext۰net۰runtime_pollOpen,
+	"net.runtime_pollReset":           ext۰net۰runtime_pollReset,
+	"net.runtime_pollServerInit":      ext۰net۰runtime_pollServerInit,
+	"net.runtime_pollSetDeadline":     ext۰net۰runtime_pollSetDeadline,
+	"net.runtime_pollUnblock":         ext۰net۰runtime_pollUnblock,
+	"net.runtime_pollWait":            ext۰net۰runtime_pollWait,
+	"net.runtime_pollWaitCanceled":    ext۰net۰runtime_pollWaitCanceled,
+	"net.runtime_Semacquire":          ext۰sync۰runtime_Semacquire,
+	"net.runtime_Semrelease":          ext۰sync۰runtime_Semrelease,
 	"os.Exit":                         ext۰os۰Exit,
 	"reflect.New":                     ext۰reflect۰New,
 	"reflect.TypeOf":                  ext۰reflect۰TypeOf,
@@ -95,29 +107,90 @@ var externals = map[string]externalFn{
 	"runtime.getgoroot":               ext۰runtime۰getgoroot,
 	"runtime.Stack":                   ext۰runtime۰Stack,
 	"sync.runtime_registerPool":       ext۰sync۰runtime_registerPool,
+	"sync.runtime_Semacquire":         ext۰sync۰runtime_Semacquire,
+	"sync.runtime_Semrelease":         ext۰sync۰runtime_Semrelease,
+	"sync.runtime_Syncsemacquire":     ext۰sync۰runtime_Syncsemacquire,
 	"sync.runtime_Syncsemcheck":       ext۰sync۰runtime_Syncsemcheck,
+	"sync.runtime_Syncsemrelease":     ext۰sync۰runtime_Syncsemrelease,
+	"(*sync.copyChecker).check":       ext۰sync۰copyChecker۰check,
+	"(*sync/atomic.Value).Load":       ext۰atomic۰Value۰Load,
+	"(*sync/atomic.Value).Store":      ext۰atomic۰Value۰Store,
 	"sync/atomic.AddInt32":            ext۰atomic۰AddInt32,
+	"sync/atomic.AddInt64":            ext۰atomic۰AddInt64,
+	"sync/atomic.AddUint32":           ext۰atomic۰AddUint32,
+	"sync/atomic.AddUint64":           ext۰atomic۰AddUint64,
+	"sync/atomic.AddUintptr":          ext۰atomic۰AddUintptr,
 	"sync/atomic.CompareAndSwapInt32": ext۰atomic۰CompareAndSwapInt32,
-	"sync/atomic.LoadInt32":           ext۰atomic۰LoadInt32,
-	"sync/atomic.LoadUint32":          ext۰atomic۰LoadUint32,
-	"sync/atomic.StoreInt32":          ext۰atomic۰StoreInt32,
-	"sync/atomic.StoreUint32":         ext۰atomic۰StoreUint32,
+	"sync/atomic.CompareAndSwapInt64": ext۰atomic۰CompareAndSwapInt64,
+	"sync/atomic.CompareAndSwapPointer": ext۰atomic۰CompareAndSwapPointer,
+	"sync/atomic.CompareAndSwapUint32": ext۰atomic۰CompareAndSwapUint32,
+	"sync/atomic.CompareAndSwapUint64": ext۰atomic۰CompareAndSwapUint64,
+	"sync/atomic.CompareAndSwapUintptr": ext۰atomic۰CompareAndSwapUintptr,
+	"sync/atomic.LoadInt32":           ext۰atomic۰Load,
+	"sync/atomic.LoadInt64":           ext۰atomic۰Load,
+	"sync/atomic.LoadPointer":         ext۰atomic۰Load,
+	"sync/atomic.LoadUint32":          ext۰atomic۰Load,
+	"sync/atomic.LoadUint64":          ext۰atomic۰Load,
+	"sync/atomic.LoadUintptr":         ext۰atomic۰Load,
+	"sync/atomic.StoreInt32":          ext۰atomic۰Store,
+	"sync/atomic.StoreInt64":          ext۰atomic۰Store,
+	"sync/atomic.StorePointer":        ext۰atomic۰Store,
+	"sync/atomic.StoreUint32":         ext۰atomic۰Store,
+	"sync/atomic.StoreUint64":         ext۰atomic۰Store,
+	"sync/atomic.StoreUintptr":        ext۰atomic۰Store,
+	"sync/atomic.SwapInt32":           ext۰atomic۰Swap,
+	"sync/atomic.SwapInt64":           ext۰atomic۰Swap,
+	"sync/atomic.SwapPointer":         ext۰atomic۰Swap,
+	"sync/atomic.SwapUint32":          ext۰atomic۰Swap,
+	"sync/atomic.SwapUint64":          ext۰atomic۰Swap,
+	"sync/atomic.SwapUintptr":         ext۰atomic۰Swap,
+	"syscall.Accept":                  ext۰syscall۰Accept,
+	"syscall.Accept4":                 ext۰syscall۰Accept4,
+	"syscall.Bind":                    ext۰syscall۰Bind,
 	"syscall.Close":                   ext۰syscall۰Close,
+	"syscall.CloseOnExec":             ext۰syscall۰CloseOnExec,
+	"syscall.Connect":                 ext۰syscall۰Connect,
 	//"syscall.Exit":                    ext۰syscall۰Exit,
 	"syscall.Exit":                    ext۰syscall۰Exit,
+	"syscall.ForkExec":                ext۰syscall۰ForkExec,
 	"syscall.Fstat":                   ext۰syscall۰Fstat,
+	"syscall.Getpeername":             ext۰syscall۰Getpeername,
 	"syscall.Getpid":                  ext۰syscall۰Getpid,
+	"syscall.Getsockname":             ext۰syscall۰Getsockname,
+	"syscall.GetsockoptInt":           ext۰syscall۰GetsockoptInt,
 	"syscall.Getwd":                   ext۰syscall۰Getwd,
 	"syscall.Kill":                    ext۰syscall۰Kill,
+	"syscall.Listen":                  ext۰syscall۰Listen,
 	"syscall.Lstat":                   ext۰syscall۰Lstat,
+	"syscall.Mkdir":                   ext۰syscall۰Mkdir,
 	"syscall.Open":                    ext۰syscall۰Open,
 	"syscall.ParseDirent":             ext۰syscall۰ParseDirent,
+	"syscall.Pipe":                    ext۰syscall۰Pipe,
+	"syscall.Pipe2":                   ext۰syscall۰Pipe2,
 	"syscall.RawSyscall":              ext۰syscall۰RawSyscall,
 	"syscall.Read":                    ext۰syscall۰Read,
 	"syscall.ReadDirent":              ext۰syscall۰ReadDirent,
+	"syscall.Recvfrom":                ext۰syscall۰Recvfrom,
+	"syscall.Rmdir":                   ext۰syscall۰Rmdir,
+	"syscall.Seek":                    ext۰syscall۰Seek,
+	"syscall.Sendto":                  ext۰syscall۰Sendto,
+	"syscall.SetNonblock":             ext۰syscall۰SetNonblock,
+	"syscall.SetsockoptByte":          ext۰syscall۰SetsockoptByte,
+	"syscall.SetsockoptInet4Addr":     ext۰syscall۰SetsockoptInet4Addr,
+	"syscall.SetsockoptInt":           ext۰syscall۰SetsockoptInt,
+	"syscall.SetsockoptLinger":        ext۰syscall۰SetsockoptLinger,
+	"syscall.SetsockoptTimeval":       ext۰syscall۰SetsockoptTimeval,
+	"syscall.Shutdown":                ext۰syscall۰Shutdown,
+	"syscall.Socket":                  ext۰syscall۰Socket,
+	"syscall.StartProcess":            ext۰syscall۰StartProcess,
 	"syscall.Stat":                    ext۰syscall۰Stat,
+	"syscall.Unlink":                  ext۰syscall۰Unlink,
+	"syscall.Wait4":                   ext۰syscall۰Wait4,
 	"syscall.Write":                   ext۰syscall۰Write,
+	"testmain.matchString":            ext۰testmain۰matchString,
 	"time.Sleep":                      ext۰time۰Sleep,
+	"time.startTimer":                 ext۰time۰startTimer,
+	"time.stopTimer":                  ext۰time۰stopTimer,
 	"time.now":                        ext۰time۰now,
 }
 
@@ -253,46 +326,6 @@ func ext۰runtime۰ReadMemStats(fr *Frame, args []Value) Value {
 	return nil
 }
 
-func ext۰atomic۰LoadUint32(fr *Frame, args []Value) Value {
-	// TODO(adonovan): fix: not atomic!
-	return (*args[0].(*Value)).(uint32)
-}
-
-func ext۰atomic۰StoreUint32(fr *Frame, args []Value) Value {
-	// TODO(adonovan): fix: not atomic!
-	*args[0].(*Value) = args[1].(uint32)
-	return nil
-}
-
-func ext۰atomic۰LoadInt32(fr *Frame, args []Value) Value {
-	// TODO(adonovan): fix: not atomic!
-	return (*args[0].(*Value)).(int32)
-}
-
-func ext۰atomic۰StoreInt32(fr *Frame, args []Value) Value {
-	// TODO(adonovan): fix: not atomic!
-	*args[0].(*Value) = args[1].(int32)
-	return nil
-}
-
-func ext۰atomic۰CompareAndSwapInt32(fr *Frame, args []Value) Value {
-	// TODO(adonovan): fix: not atomic!
-	p := args[0].(*Value)
-	if (*p).(int32) == args[1].(int32) {
-		*p = args[2].(int32)
-		return true
-	}
-	return false
-}
-
-func ext۰atomic۰AddInt32(fr *Frame, args []Value) Value {
-	// TODO(adonovan): fix: not atomic!
-	p := args[0].(*Value)
-	newv := (*p).(int32) + args[1].(int32)
-	*p = newv
-	return newv
-}
-
 func ext۰runtime۰SetFinalizer(fr *Frame, args []Value) Value {
 	return nil // ignore
 }
@@ -315,12 +348,24 @@ func ext۰time۰Sleep(fr *Frame, args []Value) Value {
 	return nil
 }
 
+func ext۰testmain۰matchString(fr *Frame, args []Value) Value {
+	// func matchString(pat, str string) (result bool, err error)
+	// The -test.run and -test.bench filter of the synthetic
+	// testmain package; see ssa2.CreateTestMainPackage.
+	matched, err := regexp.MatchString(args[0].(string), args[1].(string))
+	return tuple{matched, wrapError(err)}
+}
+
 func ext۰syscall۰Exit(fr *Frame, args []Value) Value {
 	panic(exitPanic(args[0].(int)))
 }
 
+// getwd gives the program's working directory. On Unix it is replaced
+// by one which asks the syscall back end; see sysbackend_unix.go.
+var getwd = syscall.Getwd
+
 func ext۰syscall۰Getwd(fr *Frame, args []Value) Value {
-	s, err := syscall.Getwd()
+	s, err := getwd()
 	return tuple{s, wrapError(err)}
 }
 
@@ -416,31 +461,10 @@ func valueToBytes(v Value) []byte {
 // runtime/race.go:26:func RaceWrite(addr unsafe.Pointer)
 // runtime/race.go:28:func RaceSemacquire(s *uint32)
 // runtime/race.go:29:func RaceSemrelease(s *uint32)
-// sync/atomic/doc.go:49:func CompareAndSwapInt64(addr *int64, old, new int64) (swapped bool)
-// sync/atomic/doc.go:52:func CompareAndSwapUint32(addr *uint32, old, new uint32) (swapped bool)
-// sync/atomic/doc.go:55:func CompareAndSwapUint64(addr *uint64, old, new uint64) (swapped bool)
-// sync/atomic/doc.go:58:func CompareAndSwapUintptr(addr *uintptr, old, new uintptr) (swapped bool)
-// sync/atomic/doc.go:61:func CompareAndSwapPointer(addr *unsafe.Pointer, old, new unsafe.Pointer) (swapped bool)
-// sync/atomic/doc.go:67:func AddUint32(addr *uint32, delta uint32) (new uint32)
-// sync/atomic/doc.go:70:func AddInt64(addr *int64, delta int64) (new int64)
-// sync/atomic/doc.go:73:func AddUint64(addr *uint64, delta uint64) (new uint64)
-// sync/atomic/doc.go:76:func AddUintptr(addr *uintptr, delta uintptr) (new uintptr)
-// sync/atomic/doc.go:82:func LoadInt64(addr *int64) (val int64)
-// sync/atomic/doc.go:88:func LoadUint64(addr *uint64) (val uint64)
-// sync/atomic/doc.go:91:func LoadUintptr(addr *uintptr) (val uintptr)
-// sync/atomic/doc.go:94:func LoadPointer(addr *unsafe.Pointer) (val unsafe.Pointer)
-// sync/atomic/doc.go:100:func StoreInt64(addr *int64, val int64)
-// sync/atomic/doc.go:106:func StoreUint64(addr *uint64, val uint64)
-// sync/atomic/doc.go:109:func StoreUintptr(addr *uintptr, val uintptr)
-// sync/atomic/doc.go:112:func StorePointer(addr *unsafe.Pointer, val unsafe.Pointer)
-// sync/runtime.go:12:func runtime_Semacquire(s *uint32)
-// sync/runtime.go:18:func runtime_Semrelease(s *uint32)
 // syscall/env_unix.go:30:func setenv_c(k, v string)
 // syscall/syscall_linux_amd64.go:60:func Gettimeofday(tv *Timeval) (err error)
 // syscall/syscall_linux_amd64.go:61:func Time(t *Time_t) (tt Time_t, err error)
 // syscall/syscall_linux_arm.go:28:func Seek(fd int, offset int64, whence int) (newoffset int64, err error)
-// time/sleep.go:25:func startTimer(*runtimeTimer)
-// time/sleep.go:26:func stopTimer(*runtimeTimer) bool
 // time/time.go:758:func now() (sec int64, nsec int32)
 // unsafe/unsafe.go:27:func Sizeof(v ArbitraryType) uintptr
 // unsafe/unsafe.go:32:func Offsetof(v ArbitraryType) uintptr
@@ -42,6 +42,9 @@ func ValueAry2byteAry(ary[] Value) []byte {
 }
 
 func ext۰os۰Exit(fr *Frame, args []Value) Value {
+	if fr.i.Mode&DisableHostExit != 0 {
+		panic(exitPanic(args[0].(int)))
+	}
 	msg := fmt.Sprintf("exit status %d", args[0].(int))
 	io.WriteString(os.Stderr, msg)
 	io.WriteString(os.Stderr, "\n")
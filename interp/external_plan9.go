@@ -45,6 +45,37 @@ func ext۰syscall۰Write(fr *Frame, args []Value) Value {
 	n, err := syscall.Write(args[0].(int), b)
 	return tuple{n, wrapError(err)}
 }
+func ext۰syscall۰Mkdir(fr *Frame, args []Value) Value {
+	panic("syscall.Mkdir not yet implemented")
+}
+func ext۰syscall۰Rmdir(fr *Frame, args []Value) Value {
+	panic("syscall.Rmdir not yet implemented")
+}
+func ext۰syscall۰Seek(fr *Frame, args []Value) Value {
+	panic("syscall.Seek not yet implemented")
+}
+func ext۰syscall۰Unlink(fr *Frame, args []Value) Value {
+	panic("syscall.Unlink not yet implemented")
+}
+func ext۰syscall۰ForkExec(fr *Frame, args []Value) Value {
+	panic("syscall.ForkExec not yet implemented")
+}
+func ext۰syscall۰Pipe(fr *Frame, args []Value) Value {
+	panic("syscall.Pipe not yet implemented")
+}
+func ext۰syscall۰Pipe2(fr *Frame, args []Value) Value {
+	panic("syscall.Pipe2 not yet implemented")
+}
+func ext۰syscall۰StartProcess(fr *Frame, args []Value) Value {
+	panic("syscall.StartProcess not yet implemented")
+}
+func ext۰syscall۰Wait4(fr *Frame, args []Value) Value {
+	panic("syscall.Wait4 not yet implemented")
+}
 func ext۰syscall۰RawSyscall(fn *Frame, args []value) value {
 	return tuple{^uintptr(0), uintptr(0), uintptr(0)}
 }
+
+// releaseHost would close the descriptors and end the processes a
+// run of the program leaves behind, for Restart; there are none here.
+func (i *interpreter) releaseHost() {}
@@ -39,7 +39,12 @@ func fillStat(st *syscall.Stat_t, stat structure) {
 
 func ext۰syscall۰Close(fr *Frame, args []Value) Value {
 	// func Close(fd int) (err error)
-	return wrapError(syscall.Close(args[0].(int)))
+	fd := args[0].(int)
+	err := sysBackend.Close(fd)
+	if err == nil {
+		fr.i.forgetFd(fd)
+	}
+	return wrapErrno(fr, err)
 }
 
 func ext۰syscall۰Fstat(fr *Frame, args []Value) Value {
@@ -48,9 +53,9 @@ func ext۰syscall۰Fstat(fr *Frame, args []Value) Value {
 	stat := (*args[1].(*Value)).(structure)
 
 	var st syscall.Stat_t
-	err := syscall.Fstat(fd, &st)
+	err := sysBackend.Fstat(fd, &st)
 	fillStat(&st, stat)
-	return wrapError(err)
+	return wrapErrno(fr, err)
 }
 
 func ext۰syscall۰ReadDirent(fr *Frame, args []Value) Value {
@@ -58,16 +63,16 @@ func ext۰syscall۰ReadDirent(fr *Frame, args []Value) Value {
 	fd := args[0].(int)
 	p := args[1].([]Value)
 	b := make([]byte, len(p))
-	n, err := syscall.ReadDirent(fd, b)
+	n, err := sysBackend.ReadDirent(fd, b)
 	for i := 0; i < n; i++ {
 		p[i] = b[i]
 	}
-	return tuple{n, wrapError(err)}
+	return tuple{n, wrapErrno(fr, err)}
 }
 
 func ext۰syscall۰Kill(fr *Frame, args []Value) Value {
 	// func Kill(pid int, sig Signal) (err error)
-	return wrapError(syscall.Kill(args[0].(int), syscall.Signal(args[1].(int))))
+	return wrapErrno(fr, sysBackend.Kill(args[0].(int), syscall.Signal(args[1].(int))))
 }
 
 func ext۰syscall۰Lstat(fr *Frame, args []Value) Value {
@@ -76,9 +81,9 @@ func ext۰syscall۰Lstat(fr *Frame, args []Value) Value {
 	stat := (*args[1].(*Value)).(structure)
 
 	var st syscall.Stat_t
-	err := syscall.Lstat(name, &st)
+	err := sysBackend.Lstat(name, &st)
 	fillStat(&st, stat)
-	return wrapError(err)
+	return wrapErrno(fr, err)
 }
 
 func ext۰syscall۰Open(fr *Frame, args []Value) Value {
@@ -86,8 +91,11 @@ func ext۰syscall۰Open(fr *Frame, args []Value) Value {
 	path := args[0].(string)
 	mode := args[1].(int)
 	perm := args[2].(uint32)
-	fd, err := syscall.Open(path, mode, perm)
-	return tuple{fd, wrapError(err)}
+	fd, err := sysBackend.Open(path, mode, perm)
+	if err == nil {
+		fr.i.noteFd(fd)
+	}
+	return tuple{fd, wrapErrno(fr, err)}
 }
 
 func ext۰syscall۰ParseDirent(fr *Frame, args []Value) Value {
@@ -97,7 +105,7 @@ func ext۰syscall۰ParseDirent(fr *Frame, args []Value) Value {
 	for _, iname := range args[2].([]Value) {
 		names = append(names, iname.(string))
 	}
-	consumed, count, newnames := syscall.ParseDirent(ValueToBytes(args[0]), max, names)
+	consumed, count, newnames := sysBackend.ParseDirent(ValueToBytes(args[0]), max, names)
 	var inewnames []Value
 	for _, newname := range newnames {
 		inewnames = append(inewnames, newname)
@@ -110,11 +118,11 @@ func ext۰syscall۰Read(fr *Frame, args []Value) Value {
 	fd := args[0].(int)
 	p := args[1].([]Value)
 	b := make([]byte, len(p))
-	n, err := syscall.Read(fd, b)
+	n, err := sysBackend.Read(fd, b)
 	for i := 0; i < n; i++ {
 		p[i] = b[i]
 	}
-	return tuple{n, wrapError(err)}
+	return tuple{n, wrapErrno(fr, err)}
 }
 
 func ext۰syscall۰Stat(fr *Frame, args []Value) Value {
@@ -123,15 +131,36 @@ func ext۰syscall۰Stat(fr *Frame, args []Value) Value {
 	stat := (*args[1].(*Value)).(structure)
 
 	var st syscall.Stat_t
-	err := syscall.Stat(name, &st)
+	err := sysBackend.Stat(name, &st)
 	fillStat(&st, stat)
-	return wrapError(err)
+	return wrapErrno(fr, err)
 }
 
 func ext۰syscall۰Write(fr *Frame, args []Value) Value {
 	// func Write(fd int, p []byte) (n int, err error)
-	n, err := syscall.Write(args[0].(int), ValueToBytes(args[1]))
-	return tuple{n, wrapError(err)}
+	n, err := sysBackend.Write(args[0].(int), ValueToBytes(args[1]))
+	return tuple{n, wrapErrno(fr, err)}
+}
+
+func ext۰syscall۰Seek(fr *Frame, args []Value) Value {
+	// func Seek(fd int, offset int64, whence int) (off int64, err error)
+	off, err := sysBackend.Seek(args[0].(int), args[1].(int64), args[2].(int))
+	return tuple{off, wrapErrno(fr, err)}
+}
+
+func ext۰syscall۰Mkdir(fr *Frame, args []Value) Value {
+	// func Mkdir(path string, mode uint32) (err error)
+	return wrapErrno(fr, sysBackend.Mkdir(args[0].(string), args[1].(uint32)))
+}
+
+func ext۰syscall۰Unlink(fr *Frame, args []Value) Value {
+	// func Unlink(path string) (err error)
+	return wrapErrno(fr, sysBackend.Unlink(args[0].(string)))
+}
+
+func ext۰syscall۰Rmdir(fr *Frame, args []Value) Value {
+	// func Rmdir(path string) (err error)
+	return wrapErrno(fr, sysBackend.Rmdir(args[0].(string)))
 }
 
 func ext۰syscall۰RawSyscall(fn *Frame, args []Value) Value {
@@ -38,6 +38,37 @@ func ext۰syscall۰Stat(fn *frame, args []value) value {
 func ext۰syscall۰Write(fn *frame, args []value) value {
 	panic("syscall.Write not yet implemented")
 }
+func ext۰syscall۰Mkdir(fn *frame, args []value) value {
+	panic("syscall.Mkdir not yet implemented")
+}
+func ext۰syscall۰Rmdir(fn *frame, args []value) value {
+	panic("syscall.Rmdir not yet implemented")
+}
+func ext۰syscall۰Seek(fn *frame, args []value) value {
+	panic("syscall.Seek not yet implemented")
+}
+func ext۰syscall۰Unlink(fn *frame, args []value) value {
+	panic("syscall.Unlink not yet implemented")
+}
+func ext۰syscall۰ForkExec(fn *frame, args []value) value {
+	panic("syscall.ForkExec not yet implemented")
+}
+func ext۰syscall۰Pipe(fn *frame, args []value) value {
+	panic("syscall.Pipe not yet implemented")
+}
+func ext۰syscall۰Pipe2(fn *frame, args []value) value {
+	panic("syscall.Pipe2 not yet implemented")
+}
+func ext۰syscall۰StartProcess(fn *frame, args []value) value {
+	panic("syscall.StartProcess not yet implemented")
+}
+func ext۰syscall۰Wait4(fn *frame, args []value) value {
+	panic("syscall.Wait4 not yet implemented")
+}
 func ext۰syscall۰RawSyscall(fn *ssa.Function, args []value) value {
 	return tuple{uintptr(0), uintptr(0), uintptr(syscall.ENOSYS)}
 }
+
+// releaseHost would close the descriptors and end the processes a
+// run of the program leaves behind, for Restart; there are none here.
+func (i *interpreter) releaseHost() {}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"go/token"
+	"code.google.com/p/go.tools/go/types"
 	"github.com/rocky/ssa-interp"
 )
 
@@ -26,19 +27,28 @@ type Frame struct {
 	env              map[ssa2.Value]Value // dynamic Values of SSA variables
 	locals           []Value
 	defers           []func()
+	mayRecover       bool        // one of defers may call recover()
 	result           Value
 	panicking        bool
 	panic            interface{}
 
 	status           RunStatusType
+	returning        bool        // debugger "return": leave with forcedResult
+	forcedResult     Value
 	tracing		     TraceType
 	goNum            int         // Goroutine number
+	debugCalls       int         // Calls in progress made by the
+	                             // debugger from this frame
 	Var2Reg          map[string] string // Turns an SSA
 										// register/variable into its
 										// local name
 	Reg2Var         map[string] string  // Turns an SSA
 										// register/variable into its
 										// local name
+	ObjRefs         map[types.Object] ssa2.Value // The value a source
+										// variable had when last
+										// referred to, from DebugRefs
+	scope            *ssa2.Scope // Scope of the last trace instr run
 
 	// For tracking where we are
 	pc               uint        // Instruction index of basic block
@@ -71,7 +81,12 @@ func (fr *Frame) FnAndParamString() string {
 	return fr.Fn().FnAndParamString()
 }
 
+// Scope returns the innermost scope of the statement fr is at, or, if
+// that isn't known, the scope of its basic block.
 func (fr *Frame) Scope() *ssa2.Scope {
+	if fr.scope != nil {
+		return fr.scope
+	}
 	if fr.block == nil {
 		println("Whoa there, block is nil!")
 		return nil
@@ -89,6 +104,7 @@ func (fr *Frame) runDefers() {
 		fn()
 	}
 	fr.defers = fr.defers[:0]
+	fr.mayRecover = false
 }
 
 func (fr *Frame) Fset() *token.FileSet { return fr.fn.Prog.Fset }
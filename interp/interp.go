@@ -20,11 +20,10 @@
 //
 // * The reflect package is only partially implemented.
 //
-// * "sync/atomic" operations are not currently atomic due to the
+// * "sync/atomic" operations can't be atomic in hardware due to the
 // "boxed" value representation: it is not possible to read, modify
-// and write an interface value atomically.  As a consequence, Mutexes
-// are currently broken.  TODO(adonovan): provide a metacircular
-// implementation of Mutex avoiding the broken atomic primitives.
+// and write an interface value atomically.  Instead all of them are
+// serialized through a single interpreter lock; see sync.go.
 //
 // * recover is only partially implemented.  Also, the interpreter
 // makes no attempt to distinguish target panics from interpreter
@@ -40,8 +39,17 @@
 // to have zero size, e.g. struct{}.  This can cause asymptotic
 // performance degradation.
 //
-// * os.Exit is implemented using panic, causing deferred functions to
-// run.
+// * os.Exit is implemented using panic, which unwinds the interpreted
+// goroutine without running its deferred functions, as the real
+// os.Exit doesn't; Interpret then returns the exit code.
+//
+// * file-system system calls go to the host unless another
+// SyscallBackend, such as an in-memory MemFS, has been installed; see
+// sysbackend_unix.go.
+//
+// * sockets are host sockets, and the net package's poller waits for
+// them with ppoll(2), but I/O deadlines are ignored; see
+// socket_linux.go.
 package interp
 
 import (
@@ -51,6 +59,7 @@ import (
 	"reflect"
 	"runtime"
 	"runtime/debug"
+	"sync"
 
 	"code.google.com/p/go.tools/go/types"
 	"github.com/rocky/ssa-interp"
@@ -70,6 +79,14 @@ type Mode uint
 const (
 	// Disable recover() in target programs; show interpreter crash instead.
 	DisableRecover Mode = 1 << iota
+
+	// Have os.Exit end Interpret, which returns the exit code,
+	// rather than exit the interpreter's own process.
+	DisableHostExit
+
+	// Stop in the debugger at a panic that can't be recovered,
+	// with the panicking frame and its callers still there.
+	EnablePostMortem
 )
 
 type methodSet map[string]*ssa2.Function
@@ -88,6 +105,16 @@ type interpreter struct {
 	TraceEventMask ssa2.TraceEventMask
 	nGoroutines    int                      // number of goroutines
 	goTops         []*GoreState
+	postMortemDone bool                     // the debugger has seen the fatal panic
+	restart        chan []string            // where Restart asks Interpret to run the program again
+	stopped        int32                    // Restart has ended this run of the program (atomic)
+	debugged       bool                     // run under gub or with tracing, from the start of init
+	closedMu       sync.Mutex               // guards closedChans
+	closedChans    map[chan Value]bool      // the channels a debugged run has closed; see noteClosed
+	syncSemas      map[*Value]uint32        // outstanding releases of each sync.syncSema cell; guarded by atomicMu
+	hostMu         sync.Mutex               // guards fds and pids
+	fds            map[int]bool             // descriptors this run has got from the back end and not closed
+	pids           map[int]bool             // child processes this run has started and not waited for
 }
 
 // lookupMethod returns the method set for type typ, which may be one
@@ -114,6 +141,7 @@ func visitInstr(fr *Frame, genericInstr ssa2.Instruction) continuation {
 	switch instr := genericInstr.(type) {
 	case *ssa2.DebugRef:
 		if instr.Object != nil {
+			fr.ObjRefs[instr.Object] = instr.X
 			regName := instr.X.Name()
 			varName := instr.Object.Name()
 			if regName != varName && regName[0] == 't' {
@@ -182,6 +210,8 @@ func visitInstr(fr *Frame, genericInstr ssa2.Instruction) continuation {
 				runtime۰Gotraceback(otherFr)
 			}
 		}
+		// Save the panic value so the debugger can show it.
+		fr.panic = targetPanic{fr.get(instr.X)}
 		TraceHook(fr, &genericInstr, ssa2.PANIC)
 		// Don't know if setting fr.status really does anything, but
 		// just to try to be totally Kosher. We do this *after*
@@ -215,6 +245,9 @@ func visitInstr(fr *Frame, genericInstr ssa2.Instruction) continuation {
 	case *ssa2.Defer:
 		fn, args := prepareCall(fr, &instr.Call)
 		fr.defers = append(fr.defers, func() { call(fr.i, fr.goNum, fr, fn, args) })
+		if mayRecover(fn) {
+			fr.mayRecover = true
+		}
 		// fr.defers = &deferred{
 		// 	fn:    fn,
 		// 	args:  args,
@@ -224,7 +257,7 @@ func visitInstr(fr *Frame, genericInstr ssa2.Instruction) continuation {
 
 	case *ssa2.Go:
 		fn, args := prepareCall(fr, &instr.Call)
-		go call(fr.i, i.nGoroutines, nil, fn, args)
+		go fr.i.goCall(fr.i.newGoroutine(), fn, args)
 
 	case *ssa2.MakeChan:
 		fr.env[instr] = make(chan Value, asInt(fr.get(instr.Size)))
@@ -306,9 +339,8 @@ func visitInstr(fr *Frame, genericInstr ssa2.Instruction) continuation {
 	case *ssa2.Trace:
 		fr.startP = instr.Start
 		fr.endP   = instr.End
-		if (fr.tracing == TRACE_STEP_IN) ||
-			instr.Breakpoint ||
-			(fr.tracing == TRACE_STEP_OVER) && GlobalStmtTracing() {
+		fr.scope  = instr.Scope
+		if fr.StmtStepping() || instr.Breakpoint {
 			TraceHook(fr, &genericInstr, instr.Event)
 		}
 
@@ -469,6 +501,7 @@ func callSSA(i *interpreter, goNum int, caller *Frame, fn *ssa2.Function, args [
 		goNum   : goNum,
 		Var2Reg : make(map[string]string),
 		Reg2Var : make(map[string]string),
+		ObjRefs : make(map[types.Object]ssa2.Value),
 	}
 	i.goTops[goNum].Fr = fr
 
@@ -518,17 +551,59 @@ func runFrame(fr *Frame) {
 		if fr.block == nil {
 			return // normal return
 		}
+		if fr.i.isStopped() {
+			return // Restart ended the run; see restartPanic
+		}
+		if fr.returning {
+			// The debugger's "return" when stopped at a panic().
+			recover()
+			fr.forceReturn()
+			return
+		}
 		if fr.i.Mode&DisableRecover != 0 {
+			if fr.i.Mode&EnablePostMortem != 0 {
+				p := recover()
+				fr.postMortem(p)
+				panic(p)
+			}
 			return // let interpreter crash
 		}
 		fr.panicking = true
 		fr.panic = recover()
+		if _, ok := fr.panic.(exitPanic); ok {
+			// os.Exit doesn't run deferred functions.
+			panic(fr.panic)
+		}
 		if InstTracing() || GlobalStmtTracing() {
 			fmt.Fprintf(os.Stderr, "Panicking: %T %v.\n", fr.panic, fr.panic)
 			debug.PrintStack()
 		}
+		if fr.status != StPanic {
+			// A run-time error rather than a call to panic(),
+			// which the Panic instruction has already reported.
+			fr.status = StPanic
+			TraceHook(fr, &fr.block.Instrs[fr.pc], ssa2.PANIC)
+		}
+		// Unless a deferred call may recover the panic, the
+		// debugger sees the frame as it was at the panic, before
+		// deferred calls change it; otherwise it looks once they
+		// have run, if they didn't recover it.
+		fr.postMortem(fr.panic)
 		fr.runDefers()
+		if fr.panicking {
+			fr.postMortem(fr.panic)
+		}
+		if fr.panicking && fr.inDebuggerCall() {
+			// Not recovered; see CallFunction.
+			panic(fr.panic)
+		}
+		instr := &fr.block.Instrs[fr.pc]
 		fr.block = fr.fn.Recover // recovered panic
+		if fr.block == nil && fr.caller == nil && fr.goNum != 0 {
+			// The goroutine ends here rather than at the
+			// Recover block's return.
+			TraceHook(fr, instr, ssa2.GO_EXIT)
+		}
 	}()
 
 	fn        := fr.fn
@@ -541,7 +616,13 @@ func runFrame(fr *Frame) {
 		if fn.Breakpoint { event = ssa2.BREAKPOINT }
 		TraceHook(fr, &fr.block.Instrs[0], event)
 	}
+	if fr.caller == nil && fr.goNum != 0 && fr.block == fn.Blocks[0] {
+		TraceHook(fr, &fr.block.Instrs[0], ssa2.GO_START)
+	}
 	for {
+		if fr.i.isStopped() {
+			panic(restartPanic{})
+		}
 		var instr ssa2.Instruction
 		if InstTracing() {
 			fmt.Fprintf(os.Stderr, ".%s:\n", fr.block)
@@ -561,6 +642,10 @@ func runFrame(fr *Frame) {
 			if fr.tracing == TRACE_STEP_INSTRUCTION {
 				TraceHook(fr, &instr, ssa2.STEP_INSTRUCTION)
 			}
+			if fr.returning {
+				fr.forceReturn()
+				return
+			}
 			switch visitInstr(fr, instr) {
 			case kReturn:
 				switch return_instr := instr.(type) {
@@ -572,6 +657,9 @@ func runFrame(fr *Frame) {
 				if (fr.tracing != TRACE_STEP_NONE) && GlobalStmtTracing() {
 					TraceHook(fr, &instr, ssa2.CALL_RETURN)
 				}
+				if fr.caller == nil && fr.goNum != 0 {
+					TraceHook(fr, &instr, ssa2.GO_EXIT)
+				}
 				return
 			case kNext:
 				// no-op
@@ -591,26 +679,32 @@ func doRecover(caller *Frame) Value {
 	if caller.i.Mode&DisableRecover == 0 &&
 		caller != nil && !caller.panicking &&
 		caller.caller != nil && caller.caller.panicking {
+		TraceHook(caller, nil, ssa2.RECOVER)
 		caller.caller.panicking = false
 		p := caller.caller.panic
 		caller.caller.panic = nil
-		switch p := p.(type) {
-		case targetPanic:
-			// The target program explicitly called panic().
-			return p.v
-		case runtime.Error:
-			// The interpreter encountered a runtime error.
-			return iface{caller.i.runtimeErrorString, p.Error()}
-		case string:
-			// The interpreter explicitly called panic().
-			return iface{caller.i.runtimeErrorString, p}
-		default:
-			panic(fmt.Sprintf("unexpected panic type %T in target call to recover()", p))
-		}
+		return recoveredValue(caller.i, p)
 	}
 	return iface{}
 }
 
+// recoveredValue returns the value recover() returns for panic p.
+func recoveredValue(i *interpreter, p interface{}) Value {
+	switch p := p.(type) {
+	case targetPanic:
+		// The target program explicitly called panic().
+		return p.v
+	case runtime.Error:
+		// The interpreter encountered a runtime error.
+		return iface{i.runtimeErrorString, p.Error()}
+	case string:
+		// The interpreter explicitly called panic().
+		return iface{i.runtimeErrorString, p}
+	default:
+		panic(fmt.Sprintf("unexpected panic type %T in target call to recover()", p))
+	}
+}
+
 // setGlobal sets the value of a system-initialized global variable.
 func setGlobal(i *interpreter, pkg *ssa2.Package, name string, v Value) {
 	if g, ok := i.globals[pkg.Var(name)]; ok {
@@ -629,16 +723,52 @@ var stdSizes = types.StdSizes{WordSize: 8, MaxAlign: 8}
 // Interpret returns the exit code of the program: 2 for panic (like
 // gc does), or the argument to os.Exit for normal termination.
 //
+// The debugger can run the program again from the start with Restart;
+// each run gets a fresh interpreter.
+//
 func Interpret(mainpkg *ssa2.Package, mode Mode, traceMode TraceMode,
 	filename string, args []string) (exitCode int) {
-	i = &interpreter{
-		prog:    mainpkg.Prog,
-		globals: make(map[ssa2.Value]*Value),
-		Mode:    mode,
-		TraceMode: traceMode,
-		TraceEventMask: make(ssa2.TraceEventMask, ssa2.TRACE_EVENT_LAST),
+	for {
+		i = &interpreter{
+			prog:    mainpkg.Prog,
+			globals: make(map[ssa2.Value]*Value),
+			Mode:    mode,
+			TraceMode: traceMode,
+			TraceEventMask: make(ssa2.TraceEventMask, ssa2.TRACE_EVENT_LAST),
+			restart: make(chan []string, 1),
+			debugged: traceMode&(EnableStmtTracing|EnableTracing) != 0 ||
+				mode&EnablePostMortem != 0,
+			closedChans: make(map[chan Value]bool),
+			syncSemas: make(map[*Value]uint32),
+			fds:     make(map[int]bool),
+			pids:    make(map[int]bool),
+		}
+		// The program runs in a goroutine of its own, which Restart
+		// can abandon.
+		done := make(chan int, 1)
+		go func(i *interpreter) {
+			done <- i.run(mainpkg, traceMode, filename, args)
+		}(i)
+		var newArgs []string
+		select {
+		case exitCode = <-done:
+			if !i.isStopped() {
+				return exitCode
+			}
+			newArgs = <-i.restart
+		case newArgs = <-i.restart:
+		}
+		if newArgs != nil {
+			args = newArgs
+		}
+		// Keep the debugger's "set trace".
+		traceMode = traceMode&^EnableTracing | i.TraceMode&EnableTracing
 	}
+}
 
+// run runs the program once, for Interpret.
+func (i *interpreter) run(mainpkg *ssa2.Package, traceMode TraceMode,
+	filename string, args []string) (exitCode int) {
 	runtimePkg := i.prog.ImportedPackage("runtime")
 	if runtimePkg == nil {
 		panic("Internal error: Interperter should have imported the runtime package")
@@ -697,6 +827,10 @@ func Interpret(mainpkg *ssa2.Package, mode Mode, traceMode TraceMode,
 	// Top-level error handler.
 	exitCode = 2
 	defer func() {
+		if i.isStopped() {
+			recover() // Restart ended the run
+			return
+		}
 		if exitCode != 2 || (i.Mode & DisableRecover) != 0 {
 			return
 		}
@@ -7,7 +7,14 @@ alike.
 
 */
 package interp
-import "github.com/rocky/ssa-interp"
+
+import (
+	"runtime"
+	"sync/atomic"
+
+	"code.google.com/p/go.tools/go/types"
+	"github.com/rocky/ssa-interp"
+)
 
 func (i  *interpreter) Global(name string, pkg *ssa2.Package)  (v *Value, ok bool) {
 	v, ok = i.globals[pkg.Var(name)]
@@ -34,3 +41,173 @@ func SetGlobal(i *interpreter, pkg *ssa2.Package, name string, v Value) {
 func (i *interpreter) Program() *ssa2.Program { return i.prog }
 func (i  *interpreter) Globals() map[ssa2.Value]*Value { return i.globals }
 func (i  *interpreter) GoTops() []*GoreState { return i.goTops }
+
+// newGoroutine numbers a goroutine that is about to be started and
+// makes room for its top frame in goTops.
+func (i *interpreter) newGoroutine() int {
+	gocall.Lock()
+	defer gocall.Unlock()
+	i.nGoroutines++
+	i.goTops = append(i.goTops, &GoreState{Fr: nil, state: 0})
+	return i.nGoroutines
+}
+
+// restartPanic unwinds the goroutines of a run of the program which
+// Restart has ended.
+type restartPanic struct{}
+
+// Restart ends this run of the program and has Interpret run it again
+// from the start on a fresh interpreter, with args as the program's
+// arguments, or the previous ones if args is nil. It is called from
+// the debugger, stopped in the program, and doesn't return. Nothing
+// more of the program runs, not even its deferred calls: the calling
+// goroutine unwinds at once, and the program's other goroutines as
+// soon as they get to run. The run's timers are stopped, the
+// descriptors it has open are closed, and the child processes it
+// started are killed. Goroutines blocked for good, say on a channel
+// nobody else has, are left behind.
+func (i *interpreter) Restart(args []string) {
+	atomic.StoreInt32(&i.stopped, 1)
+	stopTimers()
+	unblockPolls()
+	i.releaseHost()
+	wakeSemaWaiters()
+	select {
+	case i.restart <- args:
+	default: // already restarting
+	}
+	panic(restartPanic{})
+}
+
+// isStopped reports whether Restart has ended this run of the program.
+func (i *interpreter) isStopped() bool {
+	return atomic.LoadInt32(&i.stopped) != 0
+}
+
+// goCall runs fn in goroutine goNum, for a go statement or a timer.
+// The goroutine ends quietly if Restart ends the run.
+func (i *interpreter) goCall(goNum int, fn Value, args []Value) {
+	defer func() {
+		if i.isStopped() {
+			recover()
+		}
+	}()
+	call(i, goNum, nil, fn, args)
+}
+
+// PanicValue returns the value of the panic that fr is raising, at a
+// PANIC trace event, or that fr is recovering, at a RECOVER event.
+// The value is an interface{}; ok is false if there is no panic.
+func (fr *Frame) PanicValue() (tv TypedValue, ok bool) {
+	p := fr.panic
+	if p == nil && fr.caller != nil && fr.caller.panicking {
+		p = fr.caller.panic
+	}
+	switch p.(type) {
+	case targetPanic, runtime.Error, string:
+		return TypedValue{Type: new(types.Interface), Value: recoveredValue(fr.i, p)}, true
+	}
+	return TypedValue{}, false
+}
+
+// postMortem lets the debugger look at fr, whose panic p is about to
+// unwind it, if post-mortem debugging is enabled and nothing can
+// recover p: none of the deferred calls still to run in fr and its
+// callers calls recover(). Then fr and its callers are as they were
+// at the panic, though fr's deferred calls may have run. The debugger
+// looks only once.
+func (fr *Frame) postMortem(p interface{}) {
+	if fr.i.Mode&EnablePostMortem == 0 || fr.i.postMortemDone || fr.inDebuggerCall() {
+		return
+	}
+	if _, ok := p.(exitPanic); ok {
+		return
+	}
+	if fr.i.Mode&DisableRecover == 0 {
+		for f := fr; f != nil; f = f.caller {
+			if f.mayRecover {
+				return
+			}
+		}
+	}
+	fr.i.postMortemDone = true
+	fr.panic = p
+	fr.status = StPanic
+	TraceHook(fr, &fr.block.Instrs[fr.pc], ssa2.POST_MORTEM)
+}
+
+// mayRecover reports whether a deferred call of fn may recover a
+// panic: whether fn's body calls recover(), or fn is something whose
+// body we can't see.
+func mayRecover(fn Value) bool {
+	var f *ssa2.Function
+	switch fn := fn.(type) {
+	case *ssa2.Function:
+		f = fn
+	case *closure:
+		f = fn.fn
+	case *ssa2.Builtin:
+		// "defer recover()" has no effect; see doRecover.
+		return false
+	default:
+		return true
+	}
+	for _, b := range f.Blocks {
+		for _, instr := range b.Instrs {
+			call, ok := instr.(ssa2.CallInstruction)
+			if !ok {
+				continue
+			}
+			if builtin, ok := call.Common().Value.(*ssa2.Builtin); ok && builtin.Name() == "recover" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ForceReturn makes fr, stopped in the debugger, return result as
+// soon as it resumes, running its deferred calls but none of the rest
+// of its code. result is a tuple if fr's function has several
+// results. If fr is already returning, result replaces what it was
+// going to return.
+func (fr *Frame) ForceReturn(result Value) {
+	if fr.block == nil {
+		fr.result = result
+		return
+	}
+	fr.returning = true
+	fr.forcedResult = result
+}
+
+// forceReturn does what ForceReturn arranged, leaving fr as a normal
+// return does.
+func (fr *Frame) forceReturn() {
+	fr.returning = false
+	fr.panicking = false
+	fr.panic = nil
+	fr.runDefers()
+	fr.result = fr.forcedResult
+	fr.forcedResult = nil
+	fr.block = nil
+	fr.status = StComplete
+}
+
+// Zero returns the zero value of type t.
+func Zero(t types.Type) Value { return zero(t) }
+
+// Tuple returns the value of a call with several results.
+func Tuple(values []Value) Value { return tuple(values) }
+
+// Jump makes fr, stopped in the debugger, continue at instruction pc
+// of block b of its function, as if it had come to b from the block it
+// is in. The debugger's "jump" command checks that this makes sense.
+func (fr *Frame) Jump(b *ssa2.BasicBlock, pc uint) {
+	if b != fr.block {
+		fr.prevBlock = fr.block
+		fr.block = b
+	}
+	// The interpreter loop increments pc before running the next
+	// instruction.
+	fr.pc = pc - 1
+}
@@ -10,9 +10,11 @@ import (
 	"bytes"
 	"fmt"
 	"go/build"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
@@ -128,8 +130,10 @@ var gorootTestTests = []string{
 
 // These are files in go.tools/ssa/interp/testdata/.
 var testdataTests = []string{
+	"atomic.go",
 	"boundmeth.go",
 	"coverage.go",
+	"exec.go",
 	"fieldprom.go",
 	"ifaceprom.go",
 	"initorder.go",
@@ -216,7 +220,8 @@ func run(t *testing.T, dir, input string, success successPredicate) bool {
 	interp.CapturedOutput = &out
 
 	hint = fmt.Sprintf("To trace execution, run:\n%% go build code.google.com/p/go.tools/cmd/ssadump && ./ssadump -build=C -run --interp=T %s\n", input)
-	exitCode := interp.Interpret(mainPkg, 0, 0, inputs[0], []string{})
+	// testing.Main calls os.Exit, which mustn't end the test binary.
+	exitCode := interp.Interpret(mainPkg, interp.DisableHostExit, 0, inputs[0], []string{})
 
 	// The definition of success varies with each file.
 	if err := success(exitCode, out.String()); err != nil {
@@ -263,6 +268,95 @@ func TestTestdataFiles(t *testing.T) {
 	printFailures(failures)
 }
 
+// TestMemFS runs testdata/memfs.go against an in-memory file system
+// that it can't escape from.
+func TestMemFS(t *testing.T) {
+	memfs := interp.NewMemFS()
+	memfs.AddFile("etc/motd", []byte("hello, sandbox\n"), 0644)
+	memfs.AddFile("secret/key", []byte("xyzzy"), 0600)
+	memfs.AddDir("tmp", 0777)
+	interp.SetSyscallBackend(interp.NewPolicyBackend(memfs,
+		interp.Policy{Deny: []string{"/secret"}}))
+	defer interp.SetSyscallBackend(nil)
+
+	if !run(t, "testdata"+slash, "memfs.go", exitsZero) {
+		printFailures([]string{"memfs.go"})
+	}
+}
+
+// TestPolicySymlink checks that a symbolic link can't lead around a
+// Deny entry on the host.
+func TestPolicySymlink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	secret := filepath.Join(dir, "secret")
+	if err := os.Mkdir(secret, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(secret, "key"), []byte("xyzzy"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(secret, link); err != nil {
+		t.Fatal(err)
+	}
+	b := interp.NewPolicyBackend(interp.HostBackend{},
+		interp.Policy{Deny: []string{secret}})
+
+	if _, err := b.Open(filepath.Join(link, "key"), syscall.O_RDONLY, 0); err != syscall.EACCES {
+		t.Errorf("Open through a link into a denied directory: got %v, want EACCES", err)
+	}
+	if err := b.Mkdir(filepath.Join(link, "new"), 0700); err != syscall.EACCES {
+		t.Errorf("Mkdir through a link into a denied directory: got %v, want EACCES", err)
+	}
+	var st syscall.Stat_t
+	if err := b.Stat(filepath.Join(dir, "other"), &st); err != syscall.ENOENT {
+		t.Errorf("Stat outside the denied directory: got %v, want ENOENT", err)
+	}
+}
+
+// TestPolicySocket checks that -fs-readonly keeps a Unix-domain socket
+// from being bound on the host, and that a MemFS makes none at all.
+func TestPolicySocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fd, err := syscall.Socket(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(fd)
+	b := interp.NewPolicyBackend(interp.HostBackend{},
+		interp.Policy{ReadOnly: true})
+
+	name := filepath.Join(dir, "sock")
+	if err := b.Bind(fd, &syscall.SockaddrUnix{Name: name}); err != syscall.EROFS {
+		t.Errorf("Bind of a Unix-domain socket: got %v, want EROFS", err)
+	}
+	if _, err := os.Lstat(name); !os.IsNotExist(err) {
+		t.Errorf("Bind made %s", name)
+	}
+	if _, err := interp.NewMemFS().Socket(syscall.AF_UNIX, syscall.SOCK_STREAM, 0); err != syscall.EAFNOSUPPORT {
+		t.Errorf("MemFS Socket(AF_UNIX): got %v, want EAFNOSUPPORT", err)
+	}
+}
+
+// TestHTTPLoopback runs an HTTP server and client, both interpreted,
+// over the loopback interface.
+func TestHTTPLoopback(t *testing.T) {
+	if testing.Short() {
+		return // net/http takes a while to interpret
+	}
+	if !run(t, "testdata"+slash, "httploopback.go", exitsZero) {
+		printFailures([]string{"httploopback.go"})
+	}
+}
+
 // TestGorootTest runs the interpreter on $GOROOT/test/*.go.
 func TestGorootTest(t *testing.T) {
 	if testing.Short() {
@@ -294,24 +388,32 @@ func TestGorootTest(t *testing.T) {
 	printFailures(failures)
 }
 
-// FIXME: (rocky) reinstate
-// // TestTestmainPackage runs the interpreter on a synthetic "testmain" package.
-// func TestTestmainPackage(t *testing.T) {
-// 	success := func(exitcode int, output string) error {
-// 		if exitcode == 0 {
-// 			return fmt.Errorf("unexpected success")
-// 		}
-// 		if !strings.Contains(output, "FAIL: TestFoo") {
-// 			return fmt.Errorf("missing failure log for TestFoo")
-// 		}
-// 		if !strings.Contains(output, "FAIL: TestBar") {
-// 			return fmt.Errorf("missing failure log for TestBar")
-// 		}
-// 		// TODO(adonovan): test benchmarks too
-// 		return nil
-// 	}
-// 	run(t, "testdata"+slash, "a_test.go", success)
-// }
+// TestTestmainPackage runs the interpreter on a synthetic "testmain" package.
+func TestTestmainPackage(t *testing.T) {
+	success := func(exitcode int, output string) error {
+		if exitcode == 0 {
+			return fmt.Errorf("unexpected success")
+		}
+		if !strings.Contains(output, "FAIL: TestFoo") {
+			return fmt.Errorf("missing failure log for TestFoo")
+		}
+		if !strings.Contains(output, "FAIL: TestBar") {
+			return fmt.Errorf("missing failure log for TestBar")
+		}
+		if !strings.Contains(output, "FAIL: ExampleBad") {
+			return fmt.Errorf("missing failure log for ExampleBad")
+		}
+		if strings.Contains(output, "FAIL: ExampleGood") {
+			return fmt.Errorf("ExampleGood failed")
+		}
+		if strings.Contains(output, "unchecked example") {
+			return fmt.Errorf("ExampleUnchecked was run")
+		}
+		// TODO(adonovan): test benchmarks too
+		return nil
+	}
+	run(t, "testdata"+slash, "a_test.go", success)
+}
 
 // CreateTestMainPackage should return nil if there were no tests.
 func TestNullTestmainPackage(t *testing.T) {
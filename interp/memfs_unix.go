@@ -0,0 +1,571 @@
+// Copyright 2013 Rocky Bernstein.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows,!plan9
+
+package interp
+
+// An in-memory file system for interpreted programs.
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// memFdBase is the first file descriptor handed out by a MemFS. It is
+// well above anything the host will give us, so that descriptors for
+// sockets and pipes, which still come from the host, can't collide
+// with ours.
+const memFdBase = 1 << 20
+
+// A MemFS is a SyscallBackend that keeps a whole file system in
+// memory. Paths are resolved against the MemFS's own working
+// directory, initially "/". Of the file descriptors that it didn't
+// hand out, only 0, 1 and 2 and the program's sockets and pipes are
+// passed on to the host; anything else is EBADF, so that the program
+// can't get at the host descriptors of tortoise itself.
+type MemFS struct {
+	HostBackend
+	mu      sync.Mutex
+	files   map[string]*memFile // keyed by clean absolute path
+	fds     map[int]*memFd
+	nextFd  int
+	nextIno uint64
+	cwd     string
+}
+
+type memFile struct {
+	mode uint32 // S_IFDIR or S_IFREG, plus permission bits
+	data []byte
+	ino  uint64
+}
+
+type memFd struct {
+	path  string
+	file  *memFile
+	flags int
+	off   int64
+}
+
+// NewMemFS returns a MemFS holding just an empty root directory.
+func NewMemFS() *MemFS {
+	fs := &MemFS{
+		files:  make(map[string]*memFile),
+		fds:    make(map[int]*memFd),
+		nextFd: memFdBase,
+		cwd:    "/",
+	}
+	fs.files["/"] = fs.newFile(syscall.S_IFDIR | 0755)
+	return fs
+}
+
+// NewMemFSFromPath returns a MemFS preloaded from path, which is
+// either a directory or a tar archive, possibly gzip-compressed. The
+// contents appear at the root of the new file system.
+func NewMemFSFromPath(path string) (*MemFS, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	fs := NewMemFS()
+	if fi.IsDir() {
+		err = fs.LoadDir(path)
+	} else {
+		var f *os.File
+		if f, err = os.Open(path); err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		var r io.Reader = f
+		if strings.HasSuffix(path, ".gz") || strings.HasSuffix(path, ".tgz") {
+			if r, err = gzip.NewReader(f); err != nil {
+				return nil, err
+			}
+		}
+		err = fs.LoadTar(r)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// LoadDir copies the host directory tree rooted at dir into fs.
+func (fs *MemFS) LoadDir(dir string) error {
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case fi.IsDir():
+			fs.AddDir(rel, uint32(fi.Mode().Perm()))
+		case fi.Mode().IsRegular():
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			fs.AddFile(rel, data, uint32(fi.Mode().Perm()))
+		}
+		// Symbolic links, devices and the like are skipped.
+		return nil
+	})
+}
+
+// LoadTar adds the directories and regular files of the tar archive
+// read from r to fs.
+func (fs *MemFS) LoadTar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		perm := uint32(hdr.Mode) & 0777
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			fs.AddDir(hdr.Name, perm)
+		case tar.TypeReg, tar.TypeRegA:
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			fs.AddFile(hdr.Name, data, perm)
+		}
+	}
+}
+
+// AddDir creates directory name, and any missing parents, in fs.
+func (fs *MemFS) AddDir(name string, perm uint32) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.mkdirAll(fs.abs(name), perm)
+}
+
+// AddFile creates or replaces file name in fs, creating any missing
+// parent directories.
+func (fs *MemFS) AddFile(name string, data []byte, perm uint32) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	path := fs.abs(name)
+	fs.mkdirAll(filepath.Dir(path), 0755)
+	f := fs.newFile(syscall.S_IFREG | perm)
+	f.data = append([]byte(nil), data...)
+	fs.files[path] = f
+}
+
+// Chdir sets the working directory against which fs resolves relative
+// paths.
+func (fs *MemFS) Chdir(dir string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	path := fs.abs(dir)
+	if f := fs.files[path]; f == nil {
+		return syscall.ENOENT
+	} else if !f.isDir() {
+		return syscall.ENOTDIR
+	}
+	fs.cwd = path
+	return nil
+}
+
+// The methods below must be called with fs.mu held.
+
+func (fs *MemFS) newFile(mode uint32) *memFile {
+	fs.nextIno++
+	return &memFile{mode: mode, ino: fs.nextIno}
+}
+
+func (f *memFile) isDir() bool {
+	return f.mode&syscall.S_IFMT == syscall.S_IFDIR
+}
+
+func (fs *MemFS) abs(name string) string {
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(fs.cwd, name)
+	}
+	return filepath.Clean(name)
+}
+
+func (fs *MemFS) mkdirAll(path string, perm uint32) {
+	if _, ok := fs.files[path]; ok {
+		return
+	}
+	fs.mkdirAll(filepath.Dir(path), perm)
+	fs.files[path] = fs.newFile(syscall.S_IFDIR | perm)
+}
+
+// parentDir returns the directory that would hold path, or an error
+// if there is no such directory.
+func (fs *MemFS) parentDir(path string) (*memFile, error) {
+	dir := fs.files[filepath.Dir(path)]
+	if dir == nil {
+		return nil, syscall.ENOENT
+	}
+	if !dir.isDir() {
+		return nil, syscall.ENOTDIR
+	}
+	return dir, nil
+}
+
+// children returns the sorted names of the entries in directory path.
+func (fs *MemFS) children(path string) []string {
+	var names []string
+	for p := range fs.files {
+		if p != path && filepath.Dir(p) == path {
+			names = append(names, filepath.Base(p))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (fs *MemFS) fd(fd int) *memFd {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.fds[fd]
+}
+
+// fillStat fills in the parts of st that a MemFS keeps track of. The
+// field types of syscall.Stat_t vary from system to system, so it
+// goes through package reflect.
+func (f *memFile) fillStat(st *syscall.Stat_t) {
+	*st = syscall.Stat_t{}
+	v := reflect.ValueOf(st).Elem()
+	set := func(field string, x uint64) {
+		if fv := v.FieldByName(field); fv.IsValid() {
+			switch fv.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				fv.SetInt(int64(x))
+			default:
+				fv.SetUint(x)
+			}
+		}
+	}
+	set("Ino", f.ino)
+	set("Mode", uint64(f.mode))
+	set("Nlink", 1)
+	set("Size", uint64(len(f.data)))
+	set("Blksize", 4096)
+	set("Blocks", uint64(len(f.data)+511)/512)
+}
+
+func (fs *MemFS) Open(path string, mode int, perm uint32) (int, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	path = fs.abs(path)
+	f := fs.files[path]
+	switch {
+	case f == nil && mode&syscall.O_CREAT == 0:
+		return -1, syscall.ENOENT
+	case f == nil:
+		if _, err := fs.parentDir(path); err != nil {
+			return -1, err
+		}
+		f = fs.newFile(syscall.S_IFREG | perm&0777)
+		fs.files[path] = f
+	case mode&(syscall.O_CREAT|syscall.O_EXCL) == syscall.O_CREAT|syscall.O_EXCL:
+		return -1, syscall.EEXIST
+	case f.isDir() && mode&(syscall.O_WRONLY|syscall.O_RDWR) != 0:
+		return -1, syscall.EISDIR
+	}
+	if mode&syscall.O_TRUNC != 0 && !f.isDir() {
+		f.data = nil
+	}
+	fd := fs.nextFd
+	fs.nextFd++
+	fs.fds[fd] = &memFd{path: path, file: f, flags: mode}
+	return fd, nil
+}
+
+func (fs *MemFS) Close(fd int) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if _, ok := fs.fds[fd]; !ok {
+		if !isHostFd(fd) {
+			return syscall.EBADF
+		}
+		return fs.HostBackend.Close(fd)
+	}
+	delete(fs.fds, fd)
+	return nil
+}
+
+func (fs *MemFS) Read(fd int, p []byte) (int, error) {
+	mfd := fs.fd(fd)
+	if mfd == nil {
+		if !isHostFd(fd) {
+			return -1, syscall.EBADF
+		}
+		return fs.HostBackend.Read(fd, p)
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if mfd.file.isDir() {
+		return -1, syscall.EISDIR
+	}
+	if mfd.flags&syscall.O_ACCMODE == syscall.O_WRONLY {
+		return -1, syscall.EBADF
+	}
+	if mfd.off >= int64(len(mfd.file.data)) {
+		return 0, nil
+	}
+	n := copy(p, mfd.file.data[mfd.off:])
+	mfd.off += int64(n)
+	return n, nil
+}
+
+func (fs *MemFS) Write(fd int, p []byte) (int, error) {
+	mfd := fs.fd(fd)
+	if mfd == nil {
+		if !isHostFd(fd) {
+			return -1, syscall.EBADF
+		}
+		return fs.HostBackend.Write(fd, p)
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if mfd.flags&syscall.O_ACCMODE == syscall.O_RDONLY {
+		return -1, syscall.EBADF
+	}
+	f := mfd.file
+	if mfd.flags&syscall.O_APPEND != 0 {
+		mfd.off = int64(len(f.data))
+	}
+	if end := mfd.off + int64(len(p)); end > int64(len(f.data)) {
+		f.data = append(f.data, make([]byte, end-int64(len(f.data)))...)
+	}
+	copy(f.data[mfd.off:], p)
+	mfd.off += int64(len(p))
+	return len(p), nil
+}
+
+func (fs *MemFS) Seek(fd int, offset int64, whence int) (int64, error) {
+	mfd := fs.fd(fd)
+	if mfd == nil {
+		if !isHostFd(fd) {
+			return -1, syscall.EBADF
+		}
+		return fs.HostBackend.Seek(fd, offset, whence)
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	switch whence {
+	case 1:
+		offset += mfd.off
+	case 2:
+		offset += int64(len(mfd.file.data))
+	}
+	if offset < 0 {
+		return -1, syscall.EINVAL
+	}
+	mfd.off = offset
+	return offset, nil
+}
+
+func (fs *MemFS) Fstat(fd int, st *syscall.Stat_t) error {
+	mfd := fs.fd(fd)
+	if mfd == nil {
+		if !isHostFd(fd) {
+			return syscall.EBADF
+		}
+		return fs.HostBackend.Fstat(fd, st)
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	mfd.file.fillStat(st)
+	return nil
+}
+
+func (fs *MemFS) Stat(path string, st *syscall.Stat_t) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	f := fs.files[fs.abs(path)]
+	if f == nil {
+		return syscall.ENOENT
+	}
+	f.fillStat(st)
+	return nil
+}
+
+// Lstat is the same as Stat since a MemFS has no symbolic links.
+func (fs *MemFS) Lstat(path string, st *syscall.Stat_t) error {
+	return fs.Stat(path, st)
+}
+
+func (fs *MemFS) Mkdir(path string, perm uint32) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	path = fs.abs(path)
+	if _, ok := fs.files[path]; ok {
+		return syscall.EEXIST
+	}
+	if _, err := fs.parentDir(path); err != nil {
+		return err
+	}
+	fs.files[path] = fs.newFile(syscall.S_IFDIR | perm&0777)
+	return nil
+}
+
+func (fs *MemFS) Unlink(path string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	path = fs.abs(path)
+	f := fs.files[path]
+	if f == nil {
+		return syscall.ENOENT
+	}
+	if f.isDir() {
+		return syscall.EISDIR
+	}
+	delete(fs.files, path)
+	return nil
+}
+
+func (fs *MemFS) Rmdir(path string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	path = fs.abs(path)
+	f := fs.files[path]
+	switch {
+	case f == nil:
+		return syscall.ENOENT
+	case !f.isDir():
+		return syscall.ENOTDIR
+	case path == "/":
+		return syscall.EBUSY
+	case len(fs.children(path)) > 0:
+		return syscall.ENOTEMPTY
+	}
+	delete(fs.files, path)
+	return nil
+}
+
+func (fs *MemFS) Getwd() (string, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.cwd, nil
+}
+
+// ReadDirent fills buf with the names of the directory's entries, each
+// terminated by a NUL byte. As with the real thing, the offset of fd
+// records how far we have got; here it counts entries, not bytes.
+func (fs *MemFS) ReadDirent(fd int, buf []byte) (int, error) {
+	mfd := fs.fd(fd)
+	if mfd == nil {
+		if !isHostFd(fd) {
+			return -1, syscall.EBADF
+		}
+		return fs.HostBackend.ReadDirent(fd, buf)
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if !mfd.file.isDir() {
+		return -1, syscall.ENOTDIR
+	}
+	names := fs.children(mfd.path)
+	n := 0
+	for ; mfd.off < int64(len(names)); mfd.off++ {
+		name := names[mfd.off]
+		if n+len(name)+1 > len(buf) {
+			if n == 0 {
+				return -1, syscall.EINVAL
+			}
+			break
+		}
+		n += copy(buf[n:], name)
+		buf[n] = 0
+		n++
+	}
+	return n, nil
+}
+
+// ParseDirent decodes what ReadDirent wrote.
+func (fs *MemFS) ParseDirent(buf []byte, max int, names []string) (consumed int, count int, newnames []string) {
+	origlen := len(buf)
+	for max != 0 && len(buf) > 0 {
+		i := 0
+		for i < len(buf) && buf[i] != 0 {
+			i++
+		}
+		names = append(names, string(buf[:i]))
+		if i < len(buf) {
+			i++
+		}
+		buf = buf[i:]
+		count++
+		max--
+	}
+	return origlen - len(buf), count, names
+}
+
+// Socket refuses, with EAFNOSUPPORT, to make Unix-domain sockets for a
+// MemFS: their addresses would be paths on the host. Other sockets
+// are the host's, like pipes.
+func (fs *MemFS) Socket(domain, typ, proto int) (int, error) {
+	if domain == syscall.AF_UNIX {
+		return -1, syscall.EAFNOSUPPORT
+	}
+	return fs.HostBackend.Socket(domain, typ, proto)
+}
+
+// Bind, Connect and Sendto refuse Unix-domain addresses with EACCES,
+// and descriptors as CheckHostFd does.
+func (fs *MemFS) Bind(fd int, sa syscall.Sockaddr) error {
+	if err := fs.checkSockaddr(fd, sa); err != nil {
+		return err
+	}
+	return fs.HostBackend.Bind(fd, sa)
+}
+
+func (fs *MemFS) Connect(fd int, sa syscall.Sockaddr) error {
+	if err := fs.checkSockaddr(fd, sa); err != nil {
+		return err
+	}
+	return fs.HostBackend.Connect(fd, sa)
+}
+
+func (fs *MemFS) Sendto(fd int, p []byte, flags int, to syscall.Sockaddr) error {
+	if err := fs.checkSockaddr(fd, to); err != nil {
+		return err
+	}
+	return fs.HostBackend.Sendto(fd, p, flags, to)
+}
+
+// CheckHostFd refuses, with EBADF, descriptors that aren't the
+// program's sockets and pipes, and, with ENOTSOCK, files of the MemFS.
+func (fs *MemFS) CheckHostFd(fd int) error {
+	if fs.fd(fd) != nil {
+		return syscall.ENOTSOCK
+	}
+	if !isHostFd(fd) {
+		return syscall.EBADF
+	}
+	return nil
+}
+
+func (fs *MemFS) checkSockaddr(fd int, sa syscall.Sockaddr) error {
+	if err := fs.CheckHostFd(fd); err != nil {
+		return err
+	}
+	if _, ok := sa.(*syscall.SockaddrUnix); ok {
+		return syscall.EACCES
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2013 Rocky Bernstein.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interp
+
+// Emulated runtime hooks of the time package: the timers behind
+// time.After, time.AfterFunc, time.NewTimer and time.Ticker. The net
+// package's poller hooks are in socket_linux.go.
+
+import (
+	"sync"
+	"time"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+func ext۰net۰runtimeNano(fr *Frame, args []Value) Value {
+	// func runtimeNano() int64
+	return time.Now().UnixNano()
+}
+
+// timers maps the address of each active time.runtimeTimer in the
+// interpreted program to the host timer that fires it.
+var (
+	timersMu sync.Mutex
+	timers   = make(map[*Value]*time.Timer)
+)
+
+// timerFields gives the indices of the fields of time.runtimeTimer
+// that we use. They are looked up by name in the interpreted program's
+// time package, whose runtimeTimer must mirror the runtime's timer
+// struct; in Go 1.2 it is
+// struct { i int32; when, period int64; f func(int64, interface{}); arg interface{} }
+type timerFields struct {
+	when, period, f, arg int
+}
+
+func runtimeTimerFields(fr *Frame) timerFields {
+	pkg := fr.i.prog.ImportedPackage("time")
+	if pkg == nil || pkg.Type("runtimeTimer") == nil {
+		panic("time.runtimeTimer not found")
+	}
+	st := pkg.Type("runtimeTimer").Object().Type().Underlying().(*types.Struct)
+	index := func(name string) int {
+		for i, n := 0, st.NumFields(); i < n; i++ {
+			if st.Field(i).Name() == name {
+				return i
+			}
+		}
+		panic("no field " + name + " in time.runtimeTimer")
+	}
+	return timerFields{
+		when:   index("when"),
+		period: index("period"),
+		f:      index("f"),
+		arg:    index("arg"),
+	}
+}
+
+// fireTimer calls the function of timer t in a new interpreted
+// goroutine, as the runtime does, and arranges for it to fire again if
+// it is periodic.
+func fireTimer(i *interpreter, t *Value, tf timerFields, when int64) {
+	timersMu.Lock()
+	if _, ok := timers[t]; !ok {
+		timersMu.Unlock()
+		return // stopped meanwhile
+	}
+	s := (*t).(structure)
+	if period := s[tf.period].(int64); period > 0 {
+		when += period
+		timers[t] = time.AfterFunc(time.Duration(when-time.Now().UnixNano()),
+			func() { fireTimer(i, t, tf, when) })
+	} else {
+		delete(timers, t)
+	}
+	timersMu.Unlock()
+	go i.goCall(i.newGoroutine(), s[tf.f], []Value{time.Now().UnixNano(), s[tf.arg]})
+}
+
+// stopTimers stops all timers, for Restart.
+func stopTimers() {
+	timersMu.Lock()
+	defer timersMu.Unlock()
+	for t, ht := range timers {
+		ht.Stop()
+		delete(timers, t)
+	}
+}
+
+func ext۰time۰startTimer(fr *Frame, args []Value) Value {
+	// func startTimer(*runtimeTimer)
+	t := args[0].(*Value)
+	tf := runtimeTimerFields(fr)
+	when := (*t).(structure)[tf.when].(int64)
+	timersMu.Lock()
+	timers[t] = time.AfterFunc(time.Duration(when-time.Now().UnixNano()),
+		func() { fireTimer(fr.i, t, tf, when) })
+	timersMu.Unlock()
+	return nil
+}
+
+func ext۰time۰stopTimer(fr *Frame, args []Value) Value {
+	// func stopTimer(*runtimeTimer) bool
+	t := args[0].(*Value)
+	timersMu.Lock()
+	defer timersMu.Unlock()
+	ht, ok := timers[t]
+	if !ok {
+		return false
+	}
+	delete(timers, t)
+	return ht.Stop()
+}
@@ -962,6 +962,7 @@ func callBuiltin(caller *Frame, fn *ssa2.Builtin, args []Value) Value {
 
 	case "close": // close(chan T)
 		close(args[0].(chan Value))
+		caller.i.noteClosed(args[0].(chan Value))
 		return nil
 
 	case "delete": // delete(map[K]Value, K)
@@ -0,0 +1,325 @@
+// Copyright 2013 Rocky Bernstein.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interp
+
+// A type-directed printer for the debugger.
+//
+// toInspect only has the representation of a value to go on, so a
+// struct comes out as a list of anonymous fields and a pointer as an
+// address. Given the static type as well, we can show field names,
+// follow pointers, show what an interface holds, and so on.
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"code.google.com/p/go.tools/go/types"
+	"github.com/rocky/ssa-interp"
+)
+
+// PrintDepth is how deeply composite values nested in one another
+// are shown; deeper ones are elided. 0 means no limit.
+var PrintDepth = 20
+
+// PrintElements is the maximum number of elements of an array, slice
+// or map that are shown. 0 means no limit.
+var PrintElements = 200
+
+// noteClosed records that the program has closed ch, since a channel
+// can't be asked whether it is closed without receiving from it. Only
+// a program run under the debugger or with tracing keeps the record,
+// from the start of its initialization: each recorded channel stays
+// reachable for the rest of the run.
+func (i *interpreter) noteClosed(ch chan Value) {
+	if !i.debugged {
+		return
+	}
+	i.closedMu.Lock()
+	i.closedChans[ch] = true
+	i.closedMu.Unlock()
+}
+
+// isClosed reports whether the program being debugged has closed ch.
+func isClosed(ch chan Value) bool {
+	if i == nil {
+		return false
+	}
+	i.closedMu.Lock()
+	defer i.closedMu.Unlock()
+	return i.closedChans[ch]
+}
+
+// ToInspectTyped is like ToInspect, but shows v as a value of type t.
+func ToInspectTyped(v Value, t types.Type) string {
+	var b bytes.Buffer
+	p := printer{w: &b, path: make(map[*Value]bool)}
+	p.print(v, t)
+	return b.String()
+}
+
+type printer struct {
+	w     io.Writer
+	depth int
+
+	// The pointers followed to get to the value being printed, to
+	// stop at cycles.
+	path map[*Value]bool
+}
+
+// enter notes that we are descending into a composite value, and
+// reports whether it is within PrintDepth. If so, the caller must
+// call leave when done with the value.
+func (p *printer) enter() bool {
+	if PrintDepth > 0 && p.depth >= PrintDepth {
+		return false
+	}
+	p.depth++
+	return true
+}
+
+func (p *printer) leave() {
+	p.depth--
+}
+
+// elided reports whether element i is beyond PrintElements. If so,
+// it shows that the rest are left out.
+func (p *printer) elided(i int) bool {
+	if PrintElements <= 0 || i < PrintElements {
+		return false
+	}
+	io.WriteString(p.w, ", ...")
+	return true
+}
+
+func (p *printer) print(v Value, t types.Type) {
+	if t == nil {
+		toInspect(p.w, v)
+		return
+	}
+	switch ut := t.Underlying().(type) {
+	case *types.Struct:
+		s, ok := v.(structure)
+		if !ok {
+			break
+		}
+		if !p.enter() {
+			io.WriteString(p.w, "{...}")
+			return
+		}
+		defer p.leave()
+		io.WriteString(p.w, "{")
+		for i, e := range s {
+			if i > 0 {
+				io.WriteString(p.w, ", ")
+			}
+			f := ut.Field(i)
+			io.WriteString(p.w, f.Name()+": ")
+			p.print(e, f.Type())
+		}
+		io.WriteString(p.w, "}")
+		return
+
+	case *types.Pointer:
+		ptr, ok := v.(*Value)
+		if !ok {
+			break
+		}
+		if ptr == nil {
+			io.WriteString(p.w, "nil")
+			return
+		}
+		if p.path[ptr] {
+			fmt.Fprintf(p.w, "<cycle %s>", t)
+			return
+		}
+		p.path[ptr] = true
+		defer delete(p.path, ptr)
+		io.WriteString(p.w, "&")
+		p.print(*ptr, ut.Elem())
+		return
+
+	case *types.Slice:
+		s, ok := v.([]Value)
+		if !ok {
+			break
+		}
+		if s == nil {
+			io.WriteString(p.w, "nil")
+			return
+		}
+		if p.printElems(s, ut.Elem()) {
+			fmt.Fprintf(p.w, " (len %d, cap %d)", len(s), cap(s))
+		}
+		return
+
+	case *types.Array:
+		a, ok := v.(array)
+		if !ok {
+			break
+		}
+		if p.printElems(a, ut.Elem()) {
+			fmt.Fprintf(p.w, " (len %d)", len(a))
+		}
+		return
+
+	case *types.Map:
+		switch v.(type) {
+		case map[Value]Value, *hashmap:
+			p.printMap(v, ut)
+			return
+		}
+
+	case *types.Chan:
+		ch, ok := v.(chan Value)
+		if !ok {
+			break
+		}
+		if ch == nil {
+			io.WriteString(p.w, "nil")
+			return
+		}
+		fmt.Fprintf(p.w, "%s{len: %d, cap: %d", t, len(ch), cap(ch))
+		if isClosed(ch) {
+			io.WriteString(p.w, ", closed")
+		}
+		io.WriteString(p.w, "}")
+		return
+
+	case *types.Interface:
+		itf, ok := v.(iface)
+		if !ok {
+			break
+		}
+		if itf.t == nil {
+			io.WriteString(p.w, "nil")
+			return
+		}
+		fmt.Fprintf(p.w, "(%s) ", itf.t)
+		p.print(itf.v, itf.t)
+		return
+
+	case *types.Signature:
+		switch fn := v.(type) {
+		case nil:
+			io.WriteString(p.w, "nil")
+			return
+		case *ssa2.Function:
+			io.WriteString(p.w, fn.String())
+			return
+		case *closure:
+			io.WriteString(p.w, fn.fn.String())
+			return
+		case *ssa2.Builtin:
+			io.WriteString(p.w, fn.Name())
+			return
+		}
+	}
+	toInspect(p.w, v)
+}
+
+// printElems shows the elements of an array or slice, and reports
+// whether some were left out.
+func (p *printer) printElems(elems []Value, t types.Type) (elided bool) {
+	if !p.enter() {
+		io.WriteString(p.w, "[...]")
+		return false
+	}
+	defer p.leave()
+	io.WriteString(p.w, "[")
+	for i, e := range elems {
+		if p.elided(i) {
+			elided = true
+			break
+		}
+		if i > 0 {
+			io.WriteString(p.w, ", ")
+		}
+		p.print(e, t)
+	}
+	io.WriteString(p.w, "]")
+	return elided
+}
+
+// A mapEntry is a map element, along with the key as it is shown.
+type mapEntry struct {
+	key, value Value
+	keyStr     string
+}
+
+// printMap shows map m of type t, with its entries sorted by key.
+func (p *printer) printMap(m Value, t *types.Map) {
+	var entries []mapEntry
+	switch m := m.(type) {
+	case map[Value]Value:
+		if m == nil {
+			io.WriteString(p.w, "nil")
+			return
+		}
+		for k, e := range m {
+			entries = append(entries, mapEntry{key: k, value: e})
+		}
+	case *hashmap:
+		if m == nil {
+			io.WriteString(p.w, "nil")
+			return
+		}
+		for _, e := range m.table {
+			for ; e != nil; e = e.next {
+				entries = append(entries, mapEntry{key: e.key, value: e.Value})
+			}
+		}
+	}
+	if !p.enter() {
+		io.WriteString(p.w, "map[...]")
+		return
+	}
+	defer p.leave()
+	for i := range entries {
+		entries[i].keyStr = ToInspectTyped(entries[i].key, t.Key())
+	}
+	sort.Sort(byKey(entries))
+
+	io.WriteString(p.w, "map[")
+	for i, e := range entries {
+		if p.elided(i) {
+			break
+		}
+		if i > 0 {
+			io.WriteString(p.w, ", ")
+		}
+		io.WriteString(p.w, e.keyStr+": ")
+		p.print(e.value, t.Elem())
+	}
+	io.WriteString(p.w, "]")
+}
+
+// byKey sorts map entries by key: numbers, strings and booleans by
+// value, other keys by how they are shown.
+type byKey []mapEntry
+
+func (s byKey) Len() int      { return len(s) }
+func (s byKey) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byKey) Less(i, j int) bool {
+	x, y := reflect.ValueOf(s[i].key), reflect.ValueOf(s[j].key)
+	if x.IsValid() && y.IsValid() && x.Kind() == y.Kind() {
+		switch x.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return x.Int() < y.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+			reflect.Uint64, reflect.Uintptr:
+			return x.Uint() < y.Uint()
+		case reflect.Float32, reflect.Float64:
+			return x.Float() < y.Float()
+		case reflect.String:
+			return x.String() < y.String()
+		case reflect.Bool:
+			return !x.Bool() && y.Bool()
+		}
+	}
+	return s[i].keyStr < s[j].keyStr
+}
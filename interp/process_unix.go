@@ -0,0 +1,287 @@
+// Copyright 2013 Rocky Bernstein.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows,!plan9
+
+package interp
+
+// Emulated process-creation system calls.
+//
+// syscall.StartProcess and ForkExec go to the SyscallBackend, whose
+// host implementation starts the child with the host's os/exec. The
+// descriptors the interpreted program hands the child are duplicated
+// into host *os.File values, so pipes made with syscall.Pipe work in
+// both directions. Wait4 then waits for the exec.Cmd and reports its
+// exit status. SysProcAttr (process groups, chroot, credentials and
+// so on) is ignored.
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+// children holds the running processes started by HostBackend, by
+// process id.
+var (
+	childrenMu sync.Mutex
+	children   = make(map[int]*exec.Cmd)
+)
+
+// hostProcFile returns a host *os.File for the interpreted program's
+// descriptor fd, which is to be handed to a child process. The
+// descriptor is duplicated so that closing the file, as we do once
+// the child has started, doesn't close fd. The duplicate is
+// close-on-exec, so only this child, to which os/exec hands it, gets
+// it.
+func hostProcFile(fd int) (*os.File, error) {
+	if fd < 0 {
+		return nil, nil
+	}
+	syscall.ForkLock.RLock()
+	nfd, err := syscall.Dup(fd)
+	if err == nil {
+		syscall.CloseOnExec(nfd)
+	}
+	syscall.ForkLock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(nfd), "|"+strconv.Itoa(fd)), nil
+}
+
+// errno digs the syscall.Errno, if any, out of an error from package
+// os or os/exec.
+func errno(err error) error {
+	switch e := err.(type) {
+	case *os.PathError:
+		return e.Err
+	case *os.SyscallError:
+		return e.Err
+	case *exec.Error:
+		return errno(e.Err)
+	}
+	return err
+}
+
+func (HostBackend) StartProcess(argv0 string, argv []string, attr *syscall.ProcAttr) (int, error) {
+	cmd := &exec.Cmd{Path: argv0, Args: argv}
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			if f != nil {
+				f.Close()
+			}
+		}
+	}()
+	if attr != nil {
+		cmd.Dir = attr.Dir
+		cmd.Env = attr.Env
+		for _, fd := range attr.Files {
+			f, err := hostProcFile(int(fd))
+			if err != nil {
+				return 0, err
+			}
+			files = append(files, f)
+		}
+	}
+	// Take care not to store a nil *os.File in cmd.Stdin and
+	// friends: that would be a non-nil interface.
+	for i, f := range files {
+		switch {
+		case f == nil && i < 3:
+			// the child gets the null device
+		case i == 0:
+			cmd.Stdin = f
+		case i == 1:
+			cmd.Stdout = f
+		case i == 2:
+			cmd.Stderr = f
+		default:
+			cmd.ExtraFiles = append(cmd.ExtraFiles, f)
+		}
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, errno(err)
+	}
+	pid := cmd.Process.Pid
+	childrenMu.Lock()
+	children[pid] = cmd
+	childrenMu.Unlock()
+	return pid, nil
+}
+
+// Wait4 waits for a child started by StartProcess. Other processes,
+// and calls with options such as WNOHANG, go straight to the host;
+// os.Process.Wait uses neither. If the host reaps one of our children
+// that way, we release and forget its exec.Cmd, which could otherwise
+// only fail with ECHILD or signal a process that has taken its pid.
+func (HostBackend) Wait4(pid int, options int) (int, syscall.WaitStatus, error) {
+	childrenMu.Lock()
+	cmd := children[pid]
+	childrenMu.Unlock()
+	if cmd == nil || options != 0 {
+		var status syscall.WaitStatus
+		wpid, err := syscall.Wait4(pid, &status, options, nil)
+		if wpid > 0 && !status.Stopped() && !status.Continued() {
+			childrenMu.Lock()
+			if c := children[wpid]; c != nil {
+				c.Process.Release()
+				delete(children, wpid)
+			}
+			childrenMu.Unlock()
+		}
+		return wpid, status, err
+	}
+	err := cmd.Wait()
+	childrenMu.Lock()
+	delete(children, pid)
+	childrenMu.Unlock()
+	if cmd.ProcessState == nil {
+		return -1, 0, errno(err)
+	}
+	// A non-zero exit is reported through the status, not as an
+	// error.
+	return pid, cmd.ProcessState.Sys().(syscall.WaitStatus), nil
+}
+
+// Pipe makes both ends close-on-exec, whatever the program asked for:
+// children get the program's descriptors only through StartProcess,
+// which hands them over explicitly, and any other child started at the
+// same time mustn't inherit them, or a reader waiting for EOF might
+// never see it.
+func (HostBackend) Pipe(p []int) error {
+	syscall.ForkLock.RLock()
+	defer syscall.ForkLock.RUnlock()
+	if err := syscall.Pipe(p); err != nil {
+		return err
+	}
+	syscall.CloseOnExec(p[0])
+	syscall.CloseOnExec(p[1])
+	return nil
+}
+
+func (HostBackend) Kill(pid int, sig syscall.Signal) error {
+	return syscall.Kill(pid, sig)
+}
+
+// StartProcess always fails for a MemFS: the child would see the
+// host's file system, which is what a MemFS is meant to keep the
+// program away from. Pipes are still available.
+func (fs *MemFS) StartProcess(argv0 string, argv []string, attr *syscall.ProcAttr) (int, error) {
+	return 0, syscall.EACCES
+}
+
+// Kill always fails for a MemFS, with EPERM: the only processes there
+// are the host's, which the program has no business signalling.
+func (fs *MemFS) Kill(pid int, sig syscall.Signal) error {
+	return syscall.EPERM
+}
+
+// procAttr converts a *syscall.ProcAttr of the interpreted program.
+func procAttr(fr *Frame, v Value) *syscall.ProcAttr {
+	p, ok := v.(*Value)
+	if !ok || p == nil {
+		return nil
+	}
+	t := syscallType(fr, "ProcAttr")
+	s := (*p).(structure)
+	attr := &syscall.ProcAttr{Dir: s[fieldIndex(t, "Dir")].(string)}
+	for _, env := range s[fieldIndex(t, "Env")].([]Value) {
+		attr.Env = append(attr.Env, env.(string))
+	}
+	for _, fd := range s[fieldIndex(t, "Files")].([]Value) {
+		attr.Files = append(attr.Files, fd.(uintptr))
+	}
+	return attr
+}
+
+// startProcess starts a child for StartProcess or ForkExec, and
+// records it as one of this run's, for Restart.
+func startProcess(fr *Frame, args []Value) (int, error) {
+	var argv []string
+	for _, arg := range args[1].([]Value) {
+		argv = append(argv, arg.(string))
+	}
+	pid, err := sysBackend.StartProcess(args[0].(string), argv, procAttr(fr, args[2]))
+	if err == nil {
+		fr.i.hostMu.Lock()
+		fr.i.pids[pid] = true
+		fr.i.hostMu.Unlock()
+	}
+	return pid, err
+}
+
+func ext۰syscall۰StartProcess(fr *Frame, args []Value) Value {
+	// func StartProcess(argv0 string, argv []string, attr *ProcAttr) (pid int, handle uintptr, err error)
+	pid, err := startProcess(fr, args)
+	return tuple{pid, uintptr(0), wrapErrno(fr, err)}
+}
+
+func ext۰syscall۰ForkExec(fr *Frame, args []Value) Value {
+	// func ForkExec(argv0 string, argv []string, attr *ProcAttr) (pid int, err error)
+	pid, err := startProcess(fr, args)
+	return tuple{pid, wrapErrno(fr, err)}
+}
+
+func ext۰syscall۰Wait4(fr *Frame, args []Value) Value {
+	// func Wait4(pid int, wstatus *WaitStatus, options int, rusage *Rusage) (wpid int, err error)
+	wpid, status, err := sysBackend.Wait4(args[0].(int), args[2].(int))
+	if wpid > 0 && !status.Stopped() && !status.Continued() {
+		fr.i.hostMu.Lock()
+		delete(fr.i.pids, wpid)
+		fr.i.hostMu.Unlock()
+	}
+	if p, ok := args[1].(*Value); ok && p != nil {
+		*p = uint32(status)
+	}
+	return tuple{wpid, wrapErrno(fr, err)}
+}
+
+func ext۰syscall۰Pipe(fr *Frame, args []Value) Value {
+	// func Pipe(p []int) (err error)
+	p := args[0].([]Value)
+	if len(p) != 2 {
+		return wrapErrno(fr, syscall.EINVAL)
+	}
+	var fds [2]int
+	err := sysBackend.Pipe(fds[:])
+	if err == nil {
+		fr.i.noteFd(fds[0])
+		fr.i.noteFd(fds[1])
+		p[0], p[1] = fds[0], fds[1]
+	}
+	return wrapErrno(fr, err)
+}
+
+func ext۰syscall۰Pipe2(fr *Frame, args []Value) Value {
+	// func Pipe2(p []int, flags int) (err error)
+	//
+	// The flags are O_CLOEXEC, which the back end's Pipe always
+	// sets on the host, and O_NONBLOCK, which we drop. Only the net
+	// package's sockets wait in the poller hook; a read from a
+	// non-blocking pipe would fail with EAGAIN rather than wait,
+	// while a blocking one only holds up the goroutine doing it.
+	return ext۰syscall۰Pipe(fr, args[:1])
+}
+
+// releaseHost closes the descriptors this run of the program has left
+// open, and kills and reaps the child processes it hasn't waited for,
+// for Restart. The run's interpreter must still be the current one,
+// for a MemFS to pass its host descriptors on.
+func (i *interpreter) releaseHost() {
+	i.hostMu.Lock()
+	fds, pids := i.fds, i.pids
+	i.fds, i.pids = make(map[int]bool), make(map[int]bool)
+	i.hostMu.Unlock()
+	for fd := range fds {
+		sysBackend.Close(fd)
+	}
+	for pid := range pids {
+		sysBackend.Kill(pid, syscall.SIGKILL)
+		sysBackend.Wait4(pid, 0)
+	}
+}
@@ -0,0 +1,476 @@
+// Copyright 2013 Rocky Bernstein.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interp
+
+// Emulated socket system calls.
+//
+// Sockets are real host sockets, in whatever mode the interpreted
+// program asks for. The net package's own sockets are non-blocking,
+// and when one isn't ready the net package waits in its poller hook
+// runtime_pollWait. We give each descriptor it registers a pipe of
+// its own, and wait for the descriptor or that pipe with ppoll(2);
+// runtime_pollUnblock, called when the descriptor is being closed,
+// closes the write end of the pipe to wake the waiters. Deadlines are
+// not supported.
+//
+// Reads and writes on sockets go through the SyscallBackend like any
+// other descriptor; the back ends pass descriptors they didn't open
+// on to the host. So do Socket, Bind, Connect and Sendto, the calls
+// that can name a Unix-domain socket's path, so that a Policy or a
+// MemFS can keep the program off the host's file system. The back end
+// checks the descriptors of the other socket calls with CheckHostFd,
+// so that a MemFS program can't reach the host's own.
+
+import (
+	"fmt"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+// sockaddrToValue converts a host Sockaddr into the interpreted
+// program's equivalent: an interface holding a pointer to a
+// syscall.SockaddrInet4, SockaddrInet6 or SockaddrUnix.
+func sockaddrToValue(fr *Frame, sa syscall.Sockaddr) Value {
+	var t types.Type
+	var s structure
+	switch sa := sa.(type) {
+	case nil:
+		return iface{}
+	case *syscall.SockaddrInet4:
+		t = syscallType(fr, "SockaddrInet4")
+		s = zero(t).(structure)
+		s[fieldIndex(t, "Port")] = sa.Port
+		s[fieldIndex(t, "Addr")] = array(byteAry2ValueAry(sa.Addr[:]))
+	case *syscall.SockaddrInet6:
+		t = syscallType(fr, "SockaddrInet6")
+		s = zero(t).(structure)
+		s[fieldIndex(t, "Port")] = sa.Port
+		s[fieldIndex(t, "ZoneId")] = sa.ZoneId
+		s[fieldIndex(t, "Addr")] = array(byteAry2ValueAry(sa.Addr[:]))
+	case *syscall.SockaddrUnix:
+		t = syscallType(fr, "SockaddrUnix")
+		s = zero(t).(structure)
+		s[fieldIndex(t, "Name")] = sa.Name
+	default:
+		panic(fmt.Sprintf("unsupported socket address %T", sa))
+	}
+	var cell Value = s
+	return iface{t: types.NewPointer(t), v: &cell}
+}
+
+// valueToSockaddr is the inverse of sockaddrToValue.
+func valueToSockaddr(v Value) (syscall.Sockaddr, error) {
+	itf := v.(iface)
+	if itf.t == nil {
+		return nil, syscall.EINVAL
+	}
+	ptr, ok := itf.t.(*types.Pointer)
+	if !ok {
+		return nil, syscall.EAFNOSUPPORT
+	}
+	t := ptr.Elem()
+	named, ok := t.(*types.Named)
+	if !ok {
+		return nil, syscall.EAFNOSUPPORT
+	}
+	s := (*itf.v.(*Value)).(structure)
+	switch named.Obj().Name() {
+	case "SockaddrInet4":
+		sa := &syscall.SockaddrInet4{Port: s[fieldIndex(t, "Port")].(int)}
+		copy(sa.Addr[:], ValueAry2byteAry(s[fieldIndex(t, "Addr")].(array)))
+		return sa, nil
+	case "SockaddrInet6":
+		sa := &syscall.SockaddrInet6{
+			Port:   s[fieldIndex(t, "Port")].(int),
+			ZoneId: s[fieldIndex(t, "ZoneId")].(uint32),
+		}
+		copy(sa.Addr[:], ValueAry2byteAry(s[fieldIndex(t, "Addr")].(array)))
+		return sa, nil
+	case "SockaddrUnix":
+		return &syscall.SockaddrUnix{Name: s[fieldIndex(t, "Name")].(string)}, nil
+	}
+	return nil, syscall.EAFNOSUPPORT
+}
+
+func ext۰syscall۰Socket(fr *Frame, args []Value) Value {
+	// func Socket(domain, typ, proto int) (fd int, err error)
+	fd, err := sysBackend.Socket(args[0].(int), args[1].(int), args[2].(int))
+	if err == nil {
+		fr.i.noteFd(fd)
+	}
+	return tuple{fd, wrapErrno(fr, err)}
+}
+
+func ext۰syscall۰Bind(fr *Frame, args []Value) Value {
+	// func Bind(fd int, sa Sockaddr) (err error)
+	sa, err := valueToSockaddr(args[1])
+	if err == nil {
+		err = sysBackend.Bind(args[0].(int), sa)
+	}
+	return wrapErrno(fr, err)
+}
+
+func ext۰syscall۰Connect(fr *Frame, args []Value) Value {
+	// func Connect(fd int, sa Sockaddr) (err error)
+	sa, err := valueToSockaddr(args[1])
+	if err == nil {
+		err = sysBackend.Connect(args[0].(int), sa)
+	}
+	return wrapErrno(fr, err)
+}
+
+// The rest of the socket calls name only descriptors, which go
+// straight to the host once the back end has checked that the
+// program may use them.
+
+func ext۰syscall۰Listen(fr *Frame, args []Value) Value {
+	// func Listen(s int, n int) (err error)
+	fd := args[0].(int)
+	if err := sysBackend.CheckHostFd(fd); err != nil {
+		return wrapErrno(fr, err)
+	}
+	return wrapErrno(fr, syscall.Listen(fd, args[1].(int)))
+}
+
+func ext۰syscall۰Accept(fr *Frame, args []Value) Value {
+	// func Accept(fd int) (nfd int, sa Sockaddr, err error)
+	fd := args[0].(int)
+	if err := sysBackend.CheckHostFd(fd); err != nil {
+		return tuple{-1, iface{}, wrapErrno(fr, err)}
+	}
+	nfd, sa, err := syscall.Accept(fd)
+	if err == nil {
+		fr.i.noteFd(nfd)
+	}
+	return tuple{nfd, sockaddrToValue(fr, sa), wrapErrno(fr, err)}
+}
+
+func ext۰syscall۰Accept4(fr *Frame, args []Value) Value {
+	// func Accept4(fd int, flags int) (nfd int, sa Sockaddr, err error)
+	fd := args[0].(int)
+	if err := sysBackend.CheckHostFd(fd); err != nil {
+		return tuple{-1, iface{}, wrapErrno(fr, err)}
+	}
+	nfd, sa, err := syscall.Accept4(fd, args[1].(int))
+	if err == nil {
+		fr.i.noteFd(nfd)
+	}
+	return tuple{nfd, sockaddrToValue(fr, sa), wrapErrno(fr, err)}
+}
+
+func ext۰syscall۰Shutdown(fr *Frame, args []Value) Value {
+	// func Shutdown(fd int, how int) (err error)
+	fd := args[0].(int)
+	if err := sysBackend.CheckHostFd(fd); err != nil {
+		return wrapErrno(fr, err)
+	}
+	return wrapErrno(fr, syscall.Shutdown(fd, args[1].(int)))
+}
+
+func ext۰syscall۰Getsockname(fr *Frame, args []Value) Value {
+	// func Getsockname(fd int) (sa Sockaddr, err error)
+	fd := args[0].(int)
+	if err := sysBackend.CheckHostFd(fd); err != nil {
+		return tuple{iface{}, wrapErrno(fr, err)}
+	}
+	sa, err := syscall.Getsockname(fd)
+	return tuple{sockaddrToValue(fr, sa), wrapErrno(fr, err)}
+}
+
+func ext۰syscall۰Getpeername(fr *Frame, args []Value) Value {
+	// func Getpeername(fd int) (sa Sockaddr, err error)
+	fd := args[0].(int)
+	if err := sysBackend.CheckHostFd(fd); err != nil {
+		return tuple{iface{}, wrapErrno(fr, err)}
+	}
+	sa, err := syscall.Getpeername(fd)
+	return tuple{sockaddrToValue(fr, sa), wrapErrno(fr, err)}
+}
+
+func ext۰syscall۰Recvfrom(fr *Frame, args []Value) Value {
+	// func Recvfrom(fd int, p []byte, flags int) (n int, from Sockaddr, err error)
+	fd := args[0].(int)
+	if err := sysBackend.CheckHostFd(fd); err != nil {
+		return tuple{0, iface{}, wrapErrno(fr, err)}
+	}
+	p := args[1].([]Value)
+	b := make([]byte, len(p))
+	n, from, err := syscall.Recvfrom(fd, b, args[2].(int))
+	for i := 0; i < n; i++ {
+		p[i] = b[i]
+	}
+	return tuple{n, sockaddrToValue(fr, from), wrapErrno(fr, err)}
+}
+
+func ext۰syscall۰Sendto(fr *Frame, args []Value) Value {
+	// func Sendto(fd int, p []byte, flags int, to Sockaddr) (err error)
+	to, err := valueToSockaddr(args[3])
+	if err == nil {
+		err = sysBackend.Sendto(args[0].(int), ValueToBytes(args[1]), args[2].(int), to)
+	}
+	return wrapErrno(fr, err)
+}
+
+func ext۰syscall۰GetsockoptInt(fr *Frame, args []Value) Value {
+	// func GetsockoptInt(fd, level, opt int) (value int, err error)
+	fd := args[0].(int)
+	if err := sysBackend.CheckHostFd(fd); err != nil {
+		return tuple{0, wrapErrno(fr, err)}
+	}
+	v, err := syscall.GetsockoptInt(fd, args[1].(int), args[2].(int))
+	return tuple{v, wrapErrno(fr, err)}
+}
+
+func ext۰syscall۰SetsockoptInt(fr *Frame, args []Value) Value {
+	// func SetsockoptInt(fd, level, opt int, value int) (err error)
+	fd := args[0].(int)
+	if err := sysBackend.CheckHostFd(fd); err != nil {
+		return wrapErrno(fr, err)
+	}
+	return wrapErrno(fr, syscall.SetsockoptInt(fd, args[1].(int),
+		args[2].(int), args[3].(int)))
+}
+
+func ext۰syscall۰SetsockoptByte(fr *Frame, args []Value) Value {
+	// func SetsockoptByte(fd, level, opt int, value byte) (err error)
+	fd := args[0].(int)
+	if err := sysBackend.CheckHostFd(fd); err != nil {
+		return wrapErrno(fr, err)
+	}
+	return wrapErrno(fr, syscall.SetsockoptByte(fd, args[1].(int),
+		args[2].(int), args[3].(byte)))
+}
+
+func ext۰syscall۰SetsockoptInet4Addr(fr *Frame, args []Value) Value {
+	// func SetsockoptInet4Addr(fd, level, opt int, value [4]byte) (err error)
+	fd := args[0].(int)
+	if err := sysBackend.CheckHostFd(fd); err != nil {
+		return wrapErrno(fr, err)
+	}
+	var addr [4]byte
+	copy(addr[:], ValueAry2byteAry(args[3].(array)))
+	return wrapErrno(fr, syscall.SetsockoptInet4Addr(fd, args[1].(int),
+		args[2].(int), addr))
+}
+
+func ext۰syscall۰SetsockoptLinger(fr *Frame, args []Value) Value {
+	// func SetsockoptLinger(fd, level, opt int, l *Linger) (err error)
+	fd := args[0].(int)
+	if err := sysBackend.CheckHostFd(fd); err != nil {
+		return wrapErrno(fr, err)
+	}
+	l := (*args[3].(*Value)).(structure)
+	linger := syscall.Linger{Onoff: l[0].(int32), Linger: l[1].(int32)}
+	return wrapErrno(fr, syscall.SetsockoptLinger(fd, args[1].(int),
+		args[2].(int), &linger))
+}
+
+func ext۰syscall۰SetsockoptTimeval(fr *Frame, args []Value) Value {
+	// func SetsockoptTimeval(fd, level, opt int, tv *Timeval) (err error)
+	fd := args[0].(int)
+	if err := sysBackend.CheckHostFd(fd); err != nil {
+		return wrapErrno(fr, err)
+	}
+	tv := (*args[3].(*Value)).(structure)
+	// The field sizes of Timeval depend on the architecture.
+	nsec := (int64(asInt(tv[0]))*1e6 + int64(asInt(tv[1]))) * 1e3
+	timeval := syscall.NsecToTimeval(nsec)
+	return wrapErrno(fr, syscall.SetsockoptTimeval(fd, args[1].(int),
+		args[2].(int), &timeval))
+}
+
+func ext۰syscall۰SetNonblock(fr *Frame, args []Value) Value {
+	// func SetNonblock(fd int, nonblocking bool) (err error)
+	fd := args[0].(int)
+	if err := sysBackend.CheckHostFd(fd); err != nil {
+		return wrapErrno(fr, err)
+	}
+	return wrapErrno(fr, syscall.SetNonblock(fd, args[1].(bool)))
+}
+
+func ext۰syscall۰CloseOnExec(fr *Frame, args []Value) Value {
+	// func CloseOnExec(fd int)
+	//
+	// There is no error to report for a descriptor the program
+	// may not use; it is left alone.
+	fd := args[0].(int)
+	if sysBackend.CheckHostFd(fd) == nil {
+		syscall.CloseOnExec(fd)
+	}
+	return nil
+}
+
+// Results of runtime_pollWait and friends, as in
+// net/fd_poll_runtime.go.
+const (
+	pollNoError = 0 // ready
+	pollClosing = 1 // the descriptor is being closed
+)
+
+// The events of ppoll(2).
+const (
+	pollIn  = 0x1
+	pollOut = 0x4
+)
+
+type pollFd struct {
+	fd      int32
+	events  int16
+	revents int16
+}
+
+// A pollDesc is the poller's context for a descriptor the net package
+// has registered.
+type pollDesc struct {
+	fd   int
+	wake [2]int // a pipe whose write end is closed to wake the waiters
+
+	mu      sync.Mutex
+	closing bool
+}
+
+// pollDescs holds the registered descriptors, by context.
+var (
+	pollMu    sync.Mutex
+	pollDescs = make(map[uintptr]*pollDesc)
+	pollCtx   uintptr
+)
+
+func lookupPollDesc(ctx uintptr) *pollDesc {
+	pollMu.Lock()
+	defer pollMu.Unlock()
+	return pollDescs[ctx]
+}
+
+func (pd *pollDesc) isClosing() bool {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+	return pd.closing
+}
+
+// unblock wakes the goroutines waiting for pd, now and from now on.
+func (pd *pollDesc) unblock() {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+	if !pd.closing {
+		pd.closing = true
+		syscall.Close(pd.wake[1])
+	}
+}
+
+// wait waits until pd's descriptor is ready for the events, or pd is
+// unblocked.
+func (pd *pollDesc) wait(events int16) int {
+	for !pd.isClosing() {
+		fds := [2]pollFd{
+			{fd: int32(pd.fd), events: events},
+			{fd: int32(pd.wake[0]), events: pollIn},
+		}
+		_, _, e := syscall.Syscall6(syscall.SYS_PPOLL,
+			uintptr(unsafe.Pointer(&fds[0])), uintptr(len(fds)), 0, 0, 0, 0)
+		switch {
+		case e == syscall.EINTR:
+		case fds[1].revents != 0:
+			return pollClosing
+		default:
+			// Ready, or in error, which the next I/O call will
+			// report.
+			return pollNoError
+		}
+	}
+	return pollClosing
+}
+
+// unblockPolls wakes the goroutines waiting in the poller, for
+// Restart.
+func unblockPolls() {
+	pollMu.Lock()
+	defer pollMu.Unlock()
+	for _, pd := range pollDescs {
+		pd.unblock()
+	}
+}
+
+func ext۰net۰runtime_pollServerInit(fr *Frame, args []Value) Value {
+	// func runtime_pollServerInit()
+	return nil
+}
+
+func ext۰net۰runtime_pollOpen(fr *Frame, args []Value) Value {
+	// func runtime_pollOpen(fd uintptr) (uintptr, int)
+	pd := &pollDesc{fd: int(args[0].(uintptr))}
+	if err := syscall.Pipe2(pd.wake[:], syscall.O_CLOEXEC); err != nil {
+		return tuple{uintptr(0), int(err.(syscall.Errno))}
+	}
+	pollMu.Lock()
+	pollCtx++
+	ctx := pollCtx
+	pollDescs[ctx] = pd
+	pollMu.Unlock()
+	return tuple{ctx, pollNoError}
+}
+
+func ext۰net۰runtime_pollClose(fr *Frame, args []Value) Value {
+	// func runtime_pollClose(ctx uintptr)
+	ctx := args[0].(uintptr)
+	pollMu.Lock()
+	pd := pollDescs[ctx]
+	delete(pollDescs, ctx)
+	pollMu.Unlock()
+	if pd != nil {
+		pd.unblock()
+		syscall.Close(pd.wake[0])
+	}
+	return nil
+}
+
+// pollEvents gives the ppoll events for mode 'r', 'w' or 'r'+'w'.
+func pollEvents(mode int) int16 {
+	switch mode {
+	case 'r':
+		return pollIn
+	case 'w':
+		return pollOut
+	}
+	return pollIn | pollOut
+}
+
+func ext۰net۰runtime_pollWait(fr *Frame, args []Value) Value {
+	// func runtime_pollWait(ctx uintptr, mode int) int
+	pd := lookupPollDesc(args[0].(uintptr))
+	if pd == nil {
+		return pollClosing
+	}
+	return pd.wait(pollEvents(args[1].(int)))
+}
+
+func ext۰net۰runtime_pollWaitCanceled(fr *Frame, args []Value) Value {
+	// func runtime_pollWaitCanceled(ctx uintptr, mode int) int
+	return ext۰net۰runtime_pollWait(fr, args)
+}
+
+func ext۰net۰runtime_pollReset(fr *Frame, args []Value) Value {
+	// func runtime_pollReset(ctx uintptr, mode int) int
+	if pd := lookupPollDesc(args[0].(uintptr)); pd == nil || pd.isClosing() {
+		return pollClosing
+	}
+	return pollNoError
+}
+
+func ext۰net۰runtime_pollSetDeadline(fr *Frame, args []Value) Value {
+	// func runtime_pollSetDeadline(ctx uintptr, d int64, mode int)
+	return nil
+}
+
+func ext۰net۰runtime_pollUnblock(fr *Frame, args []Value) Value {
+	// func runtime_pollUnblock(ctx uintptr)
+	if pd := lookupPollDesc(args[0].(uintptr)); pd != nil {
+		pd.unblock()
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2013 Rocky Bernstein.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package interp
+
+// Socket system calls and the net package's poller are only emulated
+// on Linux so far; see socket_linux.go. Elsewhere the poller reports
+// every descriptor as ready.
+
+// pollNoError is the "ready" result of runtime_pollWait and friends,
+// as in net/fd_poll_runtime.go.
+const pollNoError = 0
+
+// unblockPolls would wake the goroutines waiting in the poller, for
+// Restart; none ever wait.
+func unblockPolls() {}
+
+func ext۰net۰runtime_pollServerInit(fr *Frame, args []Value) Value {
+	return nil
+}
+func ext۰net۰runtime_pollOpen(fr *Frame, args []Value) Value {
+	return tuple{args[0].(uintptr), pollNoError}
+}
+func ext۰net۰runtime_pollClose(fr *Frame, args []Value) Value {
+	return nil
+}
+func ext۰net۰runtime_pollWait(fr *Frame, args []Value) Value {
+	return pollNoError
+}
+func ext۰net۰runtime_pollWaitCanceled(fr *Frame, args []Value) Value {
+	return pollNoError
+}
+func ext۰net۰runtime_pollReset(fr *Frame, args []Value) Value {
+	return pollNoError
+}
+func ext۰net۰runtime_pollSetDeadline(fr *Frame, args []Value) Value {
+	return nil
+}
+func ext۰net۰runtime_pollUnblock(fr *Frame, args []Value) Value {
+	return nil
+}
+
+func ext۰syscall۰Socket(fr *Frame, args []Value) Value {
+	panic("syscall.Socket not yet implemented")
+}
+func ext۰syscall۰Bind(fr *Frame, args []Value) Value {
+	panic("syscall.Bind not yet implemented")
+}
+func ext۰syscall۰Connect(fr *Frame, args []Value) Value {
+	panic("syscall.Connect not yet implemented")
+}
+func ext۰syscall۰Listen(fr *Frame, args []Value) Value {
+	panic("syscall.Listen not yet implemented")
+}
+func ext۰syscall۰Accept(fr *Frame, args []Value) Value {
+	panic("syscall.Accept not yet implemented")
+}
+func ext۰syscall۰Accept4(fr *Frame, args []Value) Value {
+	panic("syscall.Accept4 not yet implemented")
+}
+func ext۰syscall۰Shutdown(fr *Frame, args []Value) Value {
+	panic("syscall.Shutdown not yet implemented")
+}
+func ext۰syscall۰Getsockname(fr *Frame, args []Value) Value {
+	panic("syscall.Getsockname not yet implemented")
+}
+func ext۰syscall۰Getpeername(fr *Frame, args []Value) Value {
+	panic("syscall.Getpeername not yet implemented")
+}
+func ext۰syscall۰Recvfrom(fr *Frame, args []Value) Value {
+	panic("syscall.Recvfrom not yet implemented")
+}
+func ext۰syscall۰Sendto(fr *Frame, args []Value) Value {
+	panic("syscall.Sendto not yet implemented")
+}
+func ext۰syscall۰GetsockoptInt(fr *Frame, args []Value) Value {
+	panic("syscall.GetsockoptInt not yet implemented")
+}
+func ext۰syscall۰SetsockoptInt(fr *Frame, args []Value) Value {
+	panic("syscall.SetsockoptInt not yet implemented")
+}
+func ext۰syscall۰SetsockoptByte(fr *Frame, args []Value) Value {
+	panic("syscall.SetsockoptByte not yet implemented")
+}
+func ext۰syscall۰SetsockoptInet4Addr(fr *Frame, args []Value) Value {
+	panic("syscall.SetsockoptInet4Addr not yet implemented")
+}
+func ext۰syscall۰SetsockoptLinger(fr *Frame, args []Value) Value {
+	panic("syscall.SetsockoptLinger not yet implemented")
+}
+func ext۰syscall۰SetsockoptTimeval(fr *Frame, args []Value) Value {
+	panic("syscall.SetsockoptTimeval not yet implemented")
+}
+func ext۰syscall۰SetNonblock(fr *Frame, args []Value) Value {
+	panic("syscall.SetNonblock not yet implemented")
+}
+func ext۰syscall۰CloseOnExec(fr *Frame, args []Value) Value {
+	panic("syscall.CloseOnExec not yet implemented")
+}
@@ -0,0 +1,267 @@
+package interp
+
+// Emulated functions from sync/atomic and the runtime hooks of package
+// sync.
+//
+// Interpreted values are "boxed" in *Value cells, so there is no way
+// to have the hardware read, modify and write one atomically. Instead
+// every atomic operation below is serialized through atomicMu. The
+// semaphores that sync.Mutex, sync.RWMutex, sync.WaitGroup and
+// sync.Cond sleep on are built from the same lock and a condition
+// variable for each semaphore, so a goroutine blocked in Semacquire
+// really does block its host goroutine until some other goroutine
+// releases that semaphore.
+
+import (
+	"sync"
+)
+
+// A semaWaiters is the condition variable the goroutines waiting for
+// one semaphore sleep on, and how many of them there are.
+type semaWaiters struct {
+	cond *sync.Cond
+	n    int
+}
+
+var (
+	atomicMu sync.Mutex
+
+	// semaWaiting holds the waiters of each semaphore cell that has
+	// any. It is guarded by atomicMu.
+	semaWaiting = make(map[*Value]*semaWaiters)
+)
+
+// semaWait waits for semaphore s to be released, with atomicMu held.
+// If Restart ends the run meanwhile, it unwinds the goroutine.
+func semaWait(fr *Frame, s *Value) {
+	if fr.i.isStopped() {
+		atomicMu.Unlock()
+		panic(restartPanic{})
+	}
+	w := semaWaiting[s]
+	if w == nil {
+		w = &semaWaiters{cond: sync.NewCond(&atomicMu)}
+		semaWaiting[s] = w
+	}
+	w.n++
+	w.cond.Wait()
+	if w.n--; w.n == 0 {
+		delete(semaWaiting, s)
+	}
+}
+
+// semaWake wakes the goroutines waiting for semaphore s, with atomicMu
+// held; all of them if all is set, otherwise one.
+func semaWake(s *Value, all bool) {
+	w := semaWaiting[s]
+	switch {
+	case w == nil:
+	case all:
+		w.cond.Broadcast()
+	default:
+		w.cond.Signal()
+	}
+}
+
+// wakeSemaWaiters wakes the goroutines waiting for any semaphore, so
+// that those of a run Restart has ended can unwind.
+func wakeSemaWaiters() {
+	atomicMu.Lock()
+	for _, w := range semaWaiting {
+		w.cond.Broadcast()
+	}
+	atomicMu.Unlock()
+}
+
+func ext۰atomic۰Load(fr *Frame, args []Value) Value {
+	// func LoadT(addr *T) (val T)
+	atomicMu.Lock()
+	defer atomicMu.Unlock()
+	return *args[0].(*Value)
+}
+
+func ext۰atomic۰Store(fr *Frame, args []Value) Value {
+	// func StoreT(addr *T, val T)
+	atomicMu.Lock()
+	*args[0].(*Value) = args[1]
+	atomicMu.Unlock()
+	return nil
+}
+
+func ext۰atomic۰Swap(fr *Frame, args []Value) Value {
+	// func SwapT(addr *T, new T) (old T)
+	p := args[0].(*Value)
+	atomicMu.Lock()
+	defer atomicMu.Unlock()
+	old := *p
+	*p = args[1]
+	return old
+}
+
+// atomicCompareAndSwap implements all of the CompareAndSwapT
+// functions. Since the old and new values have the same dynamic type
+// as the cell, plain interface comparison does the right thing.
+func atomicCompareAndSwap(args []Value) Value {
+	// func CompareAndSwapT(addr *T, old, new T) (swapped bool)
+	p := args[0].(*Value)
+	atomicMu.Lock()
+	defer atomicMu.Unlock()
+	if *p == args[1] {
+		*p = args[2]
+		return true
+	}
+	return false
+}
+
+func ext۰atomic۰CompareAndSwapInt32(fr *Frame, args []Value) Value {
+	return atomicCompareAndSwap(args)
+}
+
+func ext۰atomic۰CompareAndSwapInt64(fr *Frame, args []Value) Value {
+	return atomicCompareAndSwap(args)
+}
+
+func ext۰atomic۰CompareAndSwapUint32(fr *Frame, args []Value) Value {
+	return atomicCompareAndSwap(args)
+}
+
+func ext۰atomic۰CompareAndSwapUint64(fr *Frame, args []Value) Value {
+	return atomicCompareAndSwap(args)
+}
+
+func ext۰atomic۰CompareAndSwapUintptr(fr *Frame, args []Value) Value {
+	return atomicCompareAndSwap(args)
+}
+
+func ext۰atomic۰CompareAndSwapPointer(fr *Frame, args []Value) Value {
+	return atomicCompareAndSwap(args)
+}
+
+func ext۰atomic۰AddInt32(fr *Frame, args []Value) Value {
+	// func AddInt32(addr *int32, delta int32) (new int32)
+	p := args[0].(*Value)
+	atomicMu.Lock()
+	defer atomicMu.Unlock()
+	newv := (*p).(int32) + args[1].(int32)
+	*p = newv
+	return newv
+}
+
+func ext۰atomic۰AddInt64(fr *Frame, args []Value) Value {
+	// func AddInt64(addr *int64, delta int64) (new int64)
+	p := args[0].(*Value)
+	atomicMu.Lock()
+	defer atomicMu.Unlock()
+	newv := (*p).(int64) + args[1].(int64)
+	*p = newv
+	return newv
+}
+
+func ext۰atomic۰AddUint32(fr *Frame, args []Value) Value {
+	// func AddUint32(addr *uint32, delta uint32) (new uint32)
+	p := args[0].(*Value)
+	atomicMu.Lock()
+	defer atomicMu.Unlock()
+	newv := (*p).(uint32) + args[1].(uint32)
+	*p = newv
+	return newv
+}
+
+func ext۰atomic۰AddUint64(fr *Frame, args []Value) Value {
+	// func AddUint64(addr *uint64, delta uint64) (new uint64)
+	p := args[0].(*Value)
+	atomicMu.Lock()
+	defer atomicMu.Unlock()
+	newv := (*p).(uint64) + args[1].(uint64)
+	*p = newv
+	return newv
+}
+
+func ext۰atomic۰AddUintptr(fr *Frame, args []Value) Value {
+	// func AddUintptr(addr *uintptr, delta uintptr) (new uintptr)
+	p := args[0].(*Value)
+	atomicMu.Lock()
+	defer atomicMu.Unlock()
+	newv := (*p).(uintptr) + args[1].(uintptr)
+	*p = newv
+	return newv
+}
+
+// An atomic.Value is struct{ v interface{} }; we work directly on its
+// single field.
+
+func ext۰atomic۰Value۰Load(fr *Frame, args []Value) Value {
+	// func (v *Value) Load() (x interface{})
+	atomicMu.Lock()
+	defer atomicMu.Unlock()
+	return (*args[0].(*Value)).(structure)[0]
+}
+
+func ext۰atomic۰Value۰Store(fr *Frame, args []Value) Value {
+	// func (v *Value) Store(x interface{})
+	x := args[1].(iface)
+	if x.t == nil {
+		panic("sync/atomic: store of nil value into Value")
+	}
+	atomicMu.Lock()
+	defer atomicMu.Unlock()
+	s := (*args[0].(*Value)).(structure)
+	if old := s[0].(iface); old.t != nil && !sameType(old.t, x.t) {
+		panic("sync/atomic: store of inconsistently typed value into Value")
+	}
+	s[0] = copyVal(x)
+	return nil
+}
+
+func ext۰sync۰runtime_Semacquire(fr *Frame, args []Value) Value {
+	// func runtime_Semacquire(s *uint32)
+	s := args[0].(*Value)
+	atomicMu.Lock()
+	for (*s).(uint32) == 0 {
+		semaWait(fr, s)
+	}
+	*s = (*s).(uint32) - 1
+	atomicMu.Unlock()
+	return nil
+}
+
+func ext۰sync۰runtime_Semrelease(fr *Frame, args []Value) Value {
+	// func runtime_Semrelease(s *uint32)
+	s := args[0].(*Value)
+	atomicMu.Lock()
+	*s = (*s).(uint32) + 1
+	semaWake(s, false)
+	atomicMu.Unlock()
+	return nil
+}
+
+func ext۰sync۰runtime_Syncsemacquire(fr *Frame, args []Value) Value {
+	// func runtime_Syncsemacquire(s *syncSema)
+	s := args[0].(*Value)
+	atomicMu.Lock()
+	for fr.i.syncSemas[s] == 0 {
+		semaWait(fr, s)
+	}
+	if fr.i.syncSemas[s]--; fr.i.syncSemas[s] == 0 {
+		delete(fr.i.syncSemas, s)
+	}
+	atomicMu.Unlock()
+	return nil
+}
+
+func ext۰sync۰runtime_Syncsemrelease(fr *Frame, args []Value) Value {
+	// func runtime_Syncsemrelease(s *syncSema, n uint32)
+	s := args[0].(*Value)
+	atomicMu.Lock()
+	fr.i.syncSemas[s] += args[1].(uint32)
+	semaWake(s, true)
+	atomicMu.Unlock()
+	return nil
+}
+
+func ext۰sync۰copyChecker۰check(fr *Frame, args []Value) Value {
+	// The real check compares the checker against its own address
+	// using unsafe.Pointer, which we can't do. Copies of a sync.Cond
+	// go unnoticed.
+	return nil
+}
@@ -0,0 +1,447 @@
+// Copyright 2013 Rocky Bernstein.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows,!plan9
+
+package interp
+
+// Pluggable back ends for the file-system system calls of the
+// interpreted program.
+//
+// The emulated syscall functions in external_unix.go don't go to the
+// host directly but through the SyscallBackend installed with
+// SetSyscallBackend. The default, HostBackend, forwards to the host
+// just as before. A MemFS (memfs_unix.go) gives the program its own
+// in-memory file system, and NewPolicyBackend wraps either one to
+// refuse modifications or access to particular paths.
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+// A SyscallBackend implements the file-system system calls made by an
+// interpreted program. The methods have the signatures of the
+// like-named functions in package syscall.
+type SyscallBackend interface {
+	Open(path string, mode int, perm uint32) (fd int, err error)
+	Close(fd int) error
+	Read(fd int, p []byte) (n int, err error)
+	Write(fd int, p []byte) (n int, err error)
+	Seek(fd int, offset int64, whence int) (off int64, err error)
+	Fstat(fd int, st *syscall.Stat_t) error
+	Stat(path string, st *syscall.Stat_t) error
+	Lstat(path string, st *syscall.Stat_t) error
+	Mkdir(path string, perm uint32) error
+	Unlink(path string) error
+	Rmdir(path string) error
+	Getwd() (string, error)
+
+	// ReadDirent and ParseDirent go together: ParseDirent need only
+	// understand the buffers filled in by the same back end's
+	// ReadDirent.
+	ReadDirent(fd int, buf []byte) (n int, err error)
+	ParseDirent(buf []byte, max int, names []string) (consumed int, count int, newnames []string)
+
+	// Child processes; see process_unix.go.
+	StartProcess(argv0 string, argv []string, attr *syscall.ProcAttr) (pid int, err error)
+	Wait4(pid int, options int) (wpid int, status syscall.WaitStatus, err error)
+	Pipe(p []int) error
+	Kill(pid int, sig syscall.Signal) error
+
+	// Sockets; see socket_linux.go. Of the socket calls only these
+	// can name a path, through the address of a Unix-domain socket.
+	Socket(domain, typ, proto int) (fd int, err error)
+	Bind(fd int, sa syscall.Sockaddr) error
+	Connect(fd int, sa syscall.Sockaddr) error
+	Sendto(fd int, p []byte, flags int, to syscall.Sockaddr) error
+
+	// CheckHostFd fails if the program may not hand descriptor fd
+	// to the host's other socket calls, such as Listen and
+	// Recvfrom, which socket_linux.go makes directly.
+	CheckHostFd(fd int) error
+}
+
+// sysBackend is the back end used by the emulated syscall functions.
+// Like CapturedOutput it is shared by all interpreters in the process.
+var sysBackend SyscallBackend = HostBackend{}
+
+// SetSyscallBackend arranges for the file-system system calls of
+// subsequently interpreted programs to go to b. A nil b restores the
+// host back end.
+func SetSyscallBackend(b SyscallBackend) {
+	if b == nil {
+		b = HostBackend{}
+	}
+	sysBackend = b
+}
+
+func init() {
+	getwd = func() (string, error) { return sysBackend.Getwd() }
+}
+
+// GetSyscallBackend returns the back end currently in use.
+func GetSyscallBackend() SyscallBackend {
+	return sysBackend
+}
+
+// The descriptors a run of the program has got from the back end, or
+// as sockets from the host, are recorded in its interpreter until it
+// closes them, so that Restart can close those left open. A MemFS
+// passes on to the host only these and the standard descriptors 0, 1
+// and 2.
+
+// noteFd records that this run of the program got descriptor fd.
+func (i *interpreter) noteFd(fd int) {
+	i.hostMu.Lock()
+	i.fds[fd] = true
+	i.hostMu.Unlock()
+}
+
+// forgetFd records that this run of the program has closed
+// descriptor fd.
+func (i *interpreter) forgetFd(fd int) {
+	i.hostMu.Lock()
+	delete(i.fds, fd)
+	i.hostMu.Unlock()
+}
+
+// isHostFd reports whether fd is a host descriptor the program may
+// use: 0, 1 or 2, or one recorded by noteFd.
+func isHostFd(fd int) bool {
+	if 0 <= fd && fd <= 2 {
+		return true
+	}
+	if i == nil {
+		return false
+	}
+	i.hostMu.Lock()
+	defer i.hostMu.Unlock()
+	return i.fds[fd]
+}
+
+// HostBackend passes system calls through to the host operating
+// system.
+type HostBackend struct{}
+
+func (HostBackend) Open(path string, mode int, perm uint32) (int, error) {
+	return syscall.Open(path, mode, perm)
+}
+
+func (HostBackend) Close(fd int) error {
+	return syscall.Close(fd)
+}
+
+func (HostBackend) Read(fd int, p []byte) (int, error) {
+	return syscall.Read(fd, p)
+}
+
+// Write goes through write() so that output on fds 1 and 2 is seen by
+// CapturedOutput.
+func (HostBackend) Write(fd int, p []byte) (int, error) {
+	return write(fd, p)
+}
+
+func (HostBackend) Seek(fd int, offset int64, whence int) (int64, error) {
+	return syscall.Seek(fd, offset, whence)
+}
+
+func (HostBackend) Fstat(fd int, st *syscall.Stat_t) error {
+	return syscall.Fstat(fd, st)
+}
+
+func (HostBackend) Stat(path string, st *syscall.Stat_t) error {
+	return syscall.Stat(path, st)
+}
+
+func (HostBackend) Lstat(path string, st *syscall.Stat_t) error {
+	return syscall.Lstat(path, st)
+}
+
+func (HostBackend) Mkdir(path string, perm uint32) error {
+	return syscall.Mkdir(path, perm)
+}
+
+func (HostBackend) Unlink(path string) error {
+	return syscall.Unlink(path)
+}
+
+func (HostBackend) Rmdir(path string) error {
+	return syscall.Rmdir(path)
+}
+
+func (HostBackend) Getwd() (string, error) {
+	return syscall.Getwd()
+}
+
+func (HostBackend) ReadDirent(fd int, buf []byte) (int, error) {
+	return syscall.ReadDirent(fd, buf)
+}
+
+func (HostBackend) ParseDirent(buf []byte, max int, names []string) (int, int, []string) {
+	return syscall.ParseDirent(buf, max, names)
+}
+
+func (HostBackend) Socket(domain, typ, proto int) (int, error) {
+	return syscall.Socket(domain, typ, proto)
+}
+
+func (HostBackend) Bind(fd int, sa syscall.Sockaddr) error {
+	return syscall.Bind(fd, sa)
+}
+
+func (HostBackend) Connect(fd int, sa syscall.Sockaddr) error {
+	return syscall.Connect(fd, sa)
+}
+
+func (HostBackend) Sendto(fd int, p []byte, flags int, to syscall.Sockaddr) error {
+	return syscall.Sendto(fd, p, flags, to)
+}
+
+// CheckHostFd lets the program use any descriptor.
+func (HostBackend) CheckHostFd(fd int) error {
+	return nil
+}
+
+// sockaddrPath returns the path named by sa, if it is the address of
+// a Unix-domain socket in the file system. Abstract addresses, which
+// start with '@', name no path.
+func sockaddrPath(sa syscall.Sockaddr) (string, bool) {
+	if sa, ok := sa.(*syscall.SockaddrUnix); ok && sa.Name != "" && sa.Name[0] != '@' {
+		return sa.Name, true
+	}
+	return "", false
+}
+
+// A Policy limits what an interpreted program may do through the back
+// end it wraps.
+type Policy struct {
+	// ReadOnly refuses, with EROFS, anything that would change the
+	// file system.
+	ReadOnly bool
+
+	// Deny lists paths which may not be touched at all; access
+	// fails with EACCES. A directory entry covers everything below
+	// it, and entries may contain filepath.Match patterns.
+	Deny []string
+
+	// NoExec refuses, with EACCES, to start child processes.
+	NoExec bool
+}
+
+type policyBackend struct {
+	SyscallBackend
+	policy Policy
+}
+
+// NewPolicyBackend returns a back end that checks each call against p
+// before passing it on to b.
+func NewPolicyBackend(b SyscallBackend, p Policy) SyscallBackend {
+	return &policyBackend{b, p}
+}
+
+// onHost reports whether b, perhaps through other policies, passes
+// paths to the host's file system, where they may go through symbolic
+// links. A MemFS has none.
+func onHost(b SyscallBackend) bool {
+	switch b := b.(type) {
+	case HostBackend:
+		return true
+	case *policyBackend:
+		return onHost(b.SyscallBackend)
+	}
+	return false
+}
+
+// resolveSymlinks returns the absolute path with the symbolic links
+// in it followed. Of a path that doesn't exist yet, the longest
+// existing parent is resolved.
+func resolveSymlinks(path string) string {
+	for p := path; ; p = filepath.Dir(p) {
+		if r, err := filepath.EvalSymlinks(p); err == nil {
+			rest, _ := filepath.Rel(p, path)
+			return filepath.Join(r, rest)
+		}
+		if p == filepath.Dir(p) {
+			return path
+		}
+	}
+}
+
+// denied reports whether path falls under one of the Deny entries.
+// Relative paths are resolved against the wrapped back end's working
+// directory. On the host, the path and the entries are also compared
+// with their symbolic links followed, so that a link can't lead into
+// a denied directory.
+func (pb *policyBackend) denied(path string) bool {
+	if !filepath.IsAbs(path) {
+		if wd, err := pb.SyscallBackend.Getwd(); err == nil {
+			path = filepath.Join(wd, path)
+		}
+	}
+	paths := []string{filepath.Clean(path)}
+	host := onHost(pb.SyscallBackend)
+	if host {
+		paths = append(paths, resolveSymlinks(paths[0]))
+	}
+	for _, d := range pb.policy.Deny {
+		ds := []string{filepath.Clean(d)}
+		if host {
+			ds = append(ds, resolveSymlinks(ds[0]))
+		}
+		for _, p := range paths {
+			for _, d := range ds {
+				if p == d || strings.HasPrefix(p, d+"/") {
+					return true
+				}
+				if ok, _ := filepath.Match(d, p); ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (pb *policyBackend) check(path string, modifies bool) error {
+	if pb.denied(path) {
+		return syscall.EACCES
+	}
+	if modifies && pb.policy.ReadOnly {
+		return syscall.EROFS
+	}
+	return nil
+}
+
+func (pb *policyBackend) Open(path string, mode int, perm uint32) (int, error) {
+	modifies := mode&(syscall.O_WRONLY|syscall.O_RDWR|syscall.O_CREAT|syscall.O_TRUNC|syscall.O_APPEND) != 0
+	if err := pb.check(path, modifies); err != nil {
+		return -1, err
+	}
+	return pb.SyscallBackend.Open(path, mode, perm)
+}
+
+func (pb *policyBackend) Stat(path string, st *syscall.Stat_t) error {
+	if err := pb.check(path, false); err != nil {
+		return err
+	}
+	return pb.SyscallBackend.Stat(path, st)
+}
+
+func (pb *policyBackend) Lstat(path string, st *syscall.Stat_t) error {
+	if err := pb.check(path, false); err != nil {
+		return err
+	}
+	return pb.SyscallBackend.Lstat(path, st)
+}
+
+func (pb *policyBackend) Mkdir(path string, perm uint32) error {
+	if err := pb.check(path, true); err != nil {
+		return err
+	}
+	return pb.SyscallBackend.Mkdir(path, perm)
+}
+
+func (pb *policyBackend) Unlink(path string) error {
+	if err := pb.check(path, true); err != nil {
+		return err
+	}
+	return pb.SyscallBackend.Unlink(path)
+}
+
+func (pb *policyBackend) Rmdir(path string) error {
+	if err := pb.check(path, true); err != nil {
+		return err
+	}
+	return pb.SyscallBackend.Rmdir(path)
+}
+
+func (pb *policyBackend) StartProcess(argv0 string, argv []string, attr *syscall.ProcAttr) (int, error) {
+	if pb.policy.NoExec || pb.denied(argv0) || (attr != nil && attr.Dir != "" && pb.denied(attr.Dir)) {
+		return 0, syscall.EACCES
+	}
+	return pb.SyscallBackend.StartProcess(argv0, argv, attr)
+}
+
+// Kill refuses, with EPERM, to signal any process under NoExec: the
+// program can have started none of its own.
+func (pb *policyBackend) Kill(pid int, sig syscall.Signal) error {
+	if pb.policy.NoExec {
+		return syscall.EPERM
+	}
+	return pb.SyscallBackend.Kill(pid, sig)
+}
+
+// Bind checks the path of a Unix-domain socket address like a file
+// about to be created; binding makes the socket file.
+func (pb *policyBackend) Bind(fd int, sa syscall.Sockaddr) error {
+	if path, ok := sockaddrPath(sa); ok {
+		if err := pb.check(path, true); err != nil {
+			return err
+		}
+	}
+	return pb.SyscallBackend.Bind(fd, sa)
+}
+
+func (pb *policyBackend) Connect(fd int, sa syscall.Sockaddr) error {
+	if path, ok := sockaddrPath(sa); ok {
+		if err := pb.check(path, false); err != nil {
+			return err
+		}
+	}
+	return pb.SyscallBackend.Connect(fd, sa)
+}
+
+func (pb *policyBackend) Sendto(fd int, p []byte, flags int, to syscall.Sockaddr) error {
+	if path, ok := sockaddrPath(to); ok {
+		if err := pb.check(path, false); err != nil {
+			return err
+		}
+	}
+	return pb.SyscallBackend.Sendto(fd, p, flags, to)
+}
+
+// wrapErrno is like wrapError but turns a syscall.Errno into a value
+// of the interpreted program's syscall.Errno type, so that tests such
+// as os.IsNotExist(err) and err == syscall.EAGAIN still work.
+func wrapErrno(fr *Frame, err error) Value {
+	if errno, ok := err.(syscall.Errno); ok && fr != nil {
+		if pkg := fr.i.prog.ImportedPackage("syscall"); pkg != nil {
+			if t := pkg.Type("Errno"); t != nil {
+				return iface{t: t.Object().Type(), v: uintptr(errno)}
+			}
+		}
+	}
+	return wrapError(err)
+}
+
+// syscallType returns the type called name in the interpreted
+// program's syscall package.
+func syscallType(fr *Frame, name string) types.Type {
+	pkg := fr.i.prog.ImportedPackage("syscall")
+	if pkg == nil {
+		panic("syscall package not loaded")
+	}
+	t := pkg.Type(name)
+	if t == nil {
+		panic("syscall." + name + " not found")
+	}
+	return t.Object().Type()
+}
+
+// fieldIndex returns the index of the field called name in struct
+// type t.
+func fieldIndex(t types.Type, name string) int {
+	st := t.Underlying().(*types.Struct)
+	for i, n := 0, st.NumFields(); i < n; i++ {
+		if st.Field(i).Name() == name {
+			return i
+		}
+	}
+	panic(fmt.Sprintf("no field %s in %s", name, t))
+}
@@ -1,6 +1,9 @@
 package a
 
-import "testing"
+import (
+	"fmt"
+	"testing"
+)
 
 func TestFoo(t *testing.T) {
 	t.Error("foo")
@@ -14,4 +17,17 @@ func BenchmarkWiz(b *testing.B) {
 	b.Error("wiz")
 }
 
-// Don't test Examples since that testing package needs pipe(2) for that.
+func ExampleGood() {
+	fmt.Println("good")
+	// Output: good
+}
+
+func ExampleBad() {
+	fmt.Println("bad")
+	// Output: worse
+}
+
+// Examples without an Output comment aren't run.
+func ExampleUnchecked() {
+	panic("unchecked example was run")
+}
@@ -0,0 +1,192 @@
+// Tests of sync/atomic and of the sync primitives built on the
+// runtime semaphore hooks.
+
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+func assert(b bool, msg string) {
+	if !b {
+		panic(msg)
+	}
+}
+
+const nWorkers = 10
+const nIters = 100
+
+func testAdd() {
+	var i32 int32
+	var i64 int64
+	var u32 uint32
+	var u64 uint64
+	var wg sync.WaitGroup
+	for w := 0; w < nWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			for j := 0; j < nIters; j++ {
+				atomic.AddInt32(&i32, 1)
+				atomic.AddInt64(&i64, 2)
+				atomic.AddUint32(&u32, 3)
+				atomic.AddUint64(&u64, 4)
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	assert(atomic.LoadInt32(&i32) == nWorkers*nIters, "AddInt32")
+	assert(atomic.LoadInt64(&i64) == 2*nWorkers*nIters, "AddInt64")
+	assert(atomic.LoadUint32(&u32) == 3*nWorkers*nIters, "AddUint32")
+	assert(atomic.LoadUint64(&u64) == 4*nWorkers*nIters, "AddUint64")
+}
+
+func testCompareAndSwap() {
+	var u64 uint64 = 1
+	assert(!atomic.CompareAndSwapUint64(&u64, 2, 3), "CAS wrongly swapped")
+	assert(atomic.CompareAndSwapUint64(&u64, 1, 3), "CAS didn't swap")
+	assert(u64 == 3, "CAS stored wrong value")
+
+	var up uintptr
+	atomic.StoreUintptr(&up, 7)
+	assert(atomic.CompareAndSwapUintptr(&up, 7, 8), "CAS uintptr")
+	assert(atomic.SwapUintptr(&up, 9) == 8, "Swap uintptr")
+	assert(atomic.LoadUintptr(&up) == 9, "Load uintptr")
+}
+
+func testPointer() {
+	x, y, z := 1, 2, 3
+	var p unsafe.Pointer
+	assert(atomic.LoadPointer(&p) == nil, "LoadPointer of nil")
+	atomic.StorePointer(&p, unsafe.Pointer(&x))
+	assert(*(*int)(atomic.LoadPointer(&p)) == 1, "StorePointer")
+	assert(atomic.SwapPointer(&p, unsafe.Pointer(&y)) == unsafe.Pointer(&x),
+		"SwapPointer returned wrong value")
+	assert(!atomic.CompareAndSwapPointer(&p, unsafe.Pointer(&x), unsafe.Pointer(&z)),
+		"CompareAndSwapPointer wrongly swapped")
+	assert(atomic.CompareAndSwapPointer(&p, unsafe.Pointer(&y), unsafe.Pointer(&z)),
+		"CompareAndSwapPointer didn't swap")
+	assert(*(*int)(atomic.LoadPointer(&p)) == 3, "CompareAndSwapPointer stored wrong value")
+}
+
+func testValue() {
+	var v atomic.Value
+	assert(v.Load() == nil, "Load of empty Value")
+	v.Store("one")
+	assert(v.Load().(string) == "one", "Value Store")
+	v.Store("two")
+	assert(v.Load().(string) == "two", "Value Store again")
+
+	func() {
+		defer func() {
+			assert(recover() != nil, "Value Store of another type didn't panic")
+		}()
+		v.Store(3)
+	}()
+	func() {
+		defer func() {
+			assert(recover() != nil, "Value Store of nil didn't panic")
+		}()
+		v.Store(nil)
+	}()
+	assert(v.Load().(string) == "two", "failed Value Store changed the value")
+}
+
+func testMutex() {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	count := 0
+	for w := 0; w < nWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			for j := 0; j < nIters; j++ {
+				mu.Lock()
+				count++
+				mu.Unlock()
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	assert(count == nWorkers*nIters, "Mutex")
+}
+
+func testRWMutex() {
+	var mu sync.RWMutex
+	var wg sync.WaitGroup
+	count := 0
+	for w := 0; w < nWorkers; w++ {
+		wg.Add(2)
+		go func() {
+			for j := 0; j < nIters; j++ {
+				mu.Lock()
+				count++
+				mu.Unlock()
+			}
+			wg.Done()
+		}()
+		go func() {
+			for j := 0; j < nIters; j++ {
+				mu.RLock()
+				assert(count >= 0, "RWMutex reader")
+				mu.RUnlock()
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	assert(count == nWorkers*nIters, "RWMutex")
+
+	// A writer waits for the readers which hold the lock, and new
+	// readers wait for the writer.
+	mu.RLock()
+	locked := make(chan bool)
+	go func() {
+		mu.Lock()
+		count = 0
+		mu.Unlock()
+		locked <- true
+	}()
+	select {
+	case <-locked:
+		panic("RWMutex writer didn't wait for reader")
+	default:
+	}
+	mu.RUnlock()
+	<-locked
+	mu.RLock()
+	assert(count == 0, "RWMutex writer")
+	mu.RUnlock()
+}
+
+func testCond() {
+	var mu sync.Mutex
+	cond := sync.NewCond(&mu)
+	ready := false
+	done := make(chan bool)
+	go func() {
+		mu.Lock()
+		for !ready {
+			cond.Wait()
+		}
+		mu.Unlock()
+		done <- true
+	}()
+	mu.Lock()
+	ready = true
+	cond.Signal()
+	mu.Unlock()
+	<-done
+}
+
+func main() {
+	testAdd()
+	testCompareAndSwap()
+	testPointer()
+	testValue()
+	testMutex()
+	testRWMutex()
+	testCond()
+}
@@ -0,0 +1,75 @@
+// Tests of the emulated process-creation system calls: running
+// commands with os/exec, piping to and from them, and getting their
+// exit status.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func assert(b bool, msg string) {
+	if !b {
+		panic(msg)
+	}
+}
+
+func check(err error) {
+	if err != nil {
+		panic(err.Error())
+	}
+}
+
+func main() {
+	out, err := exec.Command("/bin/sh", "-c", "echo hello").Output()
+	check(err)
+	assert(string(out) == "hello\n", "Output: "+string(out))
+
+	// Stdin from a Reader and stdout to a Buffer both go through
+	// pipes.
+	cmd := exec.Command("/bin/sh", "-c", "tr a-z A-Z")
+	cmd.Stdin = strings.NewReader("shout")
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+	check(cmd.Run())
+	assert(buf.String() == "SHOUT", "pipes: "+buf.String())
+
+	// StdoutPipe hands us the read end as an *os.File.
+	cmd = exec.Command("/bin/sh", "-c", "echo piped")
+	stdout, err := cmd.StdoutPipe()
+	check(err)
+	check(cmd.Start())
+	b := make([]byte, 100)
+	n, err := stdout.Read(b)
+	check(err)
+	check(cmd.Wait())
+	assert(string(b[:n]) == "piped\n", "StdoutPipe: "+string(b[:n]))
+
+	err = exec.Command("/bin/sh", "-c", "exit 3").Run()
+	exitErr, ok := err.(*exec.ExitError)
+	assert(ok, "no ExitError")
+	status := exitErr.Sys().(syscall.WaitStatus)
+	assert(status.ExitStatus() == 3, "wrong exit status")
+
+	// A child reaped with WNOHANG is gone: waiting for it again is
+	// ECHILD.
+	proc, err := os.StartProcess("/bin/sh", []string{"sh", "-c", "exit 4"}, &os.ProcAttr{})
+	check(err)
+	var ws syscall.WaitStatus
+	for {
+		wpid, err := syscall.Wait4(proc.Pid, &ws, syscall.WNOHANG, nil)
+		check(err)
+		if wpid == proc.Pid {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert(ws.ExitStatus() == 4, "WNOHANG: wrong exit status")
+	_, err = syscall.Wait4(proc.Pid, &ws, 0, nil)
+	assert(err == syscall.ECHILD, "waited twice for the same child")
+}
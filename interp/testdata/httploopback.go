@@ -0,0 +1,51 @@
+// Tests of the emulated socket system calls: an HTTP server and client
+// talking over the loopback interface, both in the interpreter.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+)
+
+func assert(b bool, msg string) {
+	if !b {
+		panic(msg)
+	}
+}
+
+func check(err error) {
+	if err != nil {
+		panic(err.Error())
+	}
+}
+
+func main() {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	check(err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "hello, %s", r.URL.Query().Get("who"))
+	})
+	go http.Serve(ln, mux)
+
+	url := fmt.Sprintf("http://%s/hello?who=tortoise", ln.Addr())
+	resp, err := http.Get(url)
+	check(err)
+	body, err := ioutil.ReadAll(resp.Body)
+	check(err)
+	resp.Body.Close()
+	assert(resp.StatusCode == http.StatusOK, "status: "+resp.Status)
+	assert(string(body) == "hello, tortoise", "body: "+string(body))
+
+	resp, err = http.Get(fmt.Sprintf("http://%s/nowhere", ln.Addr()))
+	check(err)
+	resp.Body.Close()
+	assert(resp.StatusCode == http.StatusNotFound, "status: "+resp.Status)
+
+	// Closing the listener must wake up the server's Accept.
+	check(ln.Close())
+}
@@ -0,0 +1,90 @@
+// Tests of the in-memory file system back end. This is run by
+// TestMemFS, which preloads the file system; it fails on the host's.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+)
+
+func assert(b bool, msg string) {
+	if !b {
+		panic(msg)
+	}
+}
+
+func check(err error) {
+	if err != nil {
+		panic(err.Error())
+	}
+}
+
+func main() {
+	wd, err := os.Getwd()
+	check(err)
+	assert(wd == "/", "Getwd: "+wd)
+
+	data, err := ioutil.ReadFile("/etc/motd")
+	check(err)
+	assert(string(data) == "hello, sandbox\n", "ReadFile: "+string(data))
+
+	// Writes stay in memory.
+	check(ioutil.WriteFile("tmp/out", []byte("abc"), 0644))
+	f, err := os.OpenFile("/tmp/out", os.O_WRONLY|os.O_APPEND, 0)
+	check(err)
+	_, err = f.Write([]byte("def"))
+	check(err)
+	check(f.Close())
+	data, err = ioutil.ReadFile("/tmp/out")
+	check(err)
+	assert(string(data) == "abcdef", "append: "+string(data))
+
+	fi, err := os.Stat("/tmp/out")
+	check(err)
+	assert(fi.Size() == 6 && !fi.IsDir(), "Stat")
+
+	check(os.Mkdir("/tmp/sub", 0755))
+	infos, err := ioutil.ReadDir("/tmp")
+	check(err)
+	var names []string
+	for _, fi := range infos {
+		names = append(names, fi.Name())
+	}
+	assert(strings.Join(names, " ") == "out sub", "ReadDir: "+strings.Join(names, " "))
+
+	check(os.Remove("/tmp/out"))
+	check(os.Remove("/tmp/sub"))
+	_, err = os.Stat("/tmp/out")
+	assert(os.IsNotExist(err), "Remove")
+
+	// Host descriptors other than 0, 1 and 2, such as the
+	// interpreter's own, are out of reach.
+	_, err = syscall.Read(3, make([]byte, 1))
+	assert(err == syscall.EBADF, "host descriptor 3 was readable")
+
+	// So they are to the socket calls.
+	assert(syscall.Listen(3, 1) == syscall.EBADF, "Listen on host descriptor 3")
+	assert(syscall.Shutdown(3, syscall.SHUT_RDWR) == syscall.EBADF, "Shutdown of host descriptor 3")
+	_, _, err = syscall.Recvfrom(3, make([]byte, 1), 0)
+	assert(err == syscall.EBADF, "Recvfrom on host descriptor 3")
+	_, err = syscall.Getsockname(3)
+	assert(err == syscall.EBADF, "Getsockname of host descriptor 3")
+	_, err = syscall.GetsockoptInt(3, syscall.SOL_SOCKET, syscall.SO_TYPE)
+	assert(err == syscall.EBADF, "GetsockoptInt on host descriptor 3")
+	err = syscall.SetsockoptInt(3, syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+	assert(err == syscall.EBADF, "SetsockoptInt on host descriptor 3")
+	assert(syscall.SetNonblock(3, true) == syscall.EBADF, "SetNonblock on host descriptor 3")
+
+	// A MemFS file isn't a socket.
+	fd, err := syscall.Open("/etc/motd", syscall.O_RDONLY, 0)
+	check(err)
+	assert(syscall.Listen(fd, 1) == syscall.ENOTSOCK, "Listen on a MemFS file")
+	check(syscall.Close(fd))
+
+	// The policy keeps us out of /secret.
+	_, err = ioutil.ReadFile("/secret/key")
+	assert(os.IsPermission(err), "denied path was readable")
+}
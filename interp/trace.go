@@ -101,6 +101,13 @@ func SetStepOff(fr *Frame) {
 	fr.tracing = TRACE_STEP_NONE
 }
 
+// StmtStepping reports whether fr stops at every statement, rather
+// than only at those with a breakpoint.
+func (fr *Frame) StmtStepping() bool {
+	return fr.tracing == TRACE_STEP_IN ||
+		(fr.tracing == TRACE_STEP_OVER) && GlobalStmtTracing()
+}
+
 func SetInstTracing() {
 	i.TraceMode |= EnableTracing
 }
@@ -0,0 +1,519 @@
+// Copyright 2013 Rocky Bernstein.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interp
+
+// Typed values for the debugger's expression evaluator.
+//
+// The representation of an interpreted value doesn't say which type
+// it came from: a struct is just a structure, i.e. a []Value, and a
+// pointer is a *Value. To select a field by name, to follow an
+// embedded field or to find a method, the evaluator needs the static
+// type too, so it passes values around as TypedValues.
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"reflect"
+
+	"code.google.com/p/go.tools/go/types"
+	"github.com/rocky/ssa-interp"
+)
+
+// A TypedValue is an interpreted value together with its static type.
+type TypedValue struct {
+	Type  types.Type
+	Value Value
+
+	// Addr is the variable holding Value, if the value is
+	// addressable, as local and global variables, fields of
+	// addressable structs and things pointed to are. Otherwise it
+	// is nil.
+	Addr *Value
+
+	// For a map element m[k], which isn't addressable but can be
+	// assigned to, the map and the key.
+	mapVal, mapKey Value
+}
+
+// NewTypedVariable returns the TypedValue of the variable at addr,
+// which is of type t.
+func NewTypedVariable(t types.Type, addr *Value) TypedValue {
+	return TypedValue{Type: t, Value: *addr, Addr: addr}
+}
+
+func (tv TypedValue) String() string {
+	return ToInspectTyped(tv.Value, tv.Type)
+}
+
+// Deref returns the value tv points to.
+func (tv TypedValue) Deref() (TypedValue, error) {
+	ptr, ok := tv.Type.Underlying().(*types.Pointer)
+	if !ok {
+		return TypedValue{}, fmt.Errorf("invalid indirect of type %s", tv.Type)
+	}
+	addr, _ := tv.Value.(*Value)
+	if addr == nil {
+		return TypedValue{}, errors.New("invalid memory address or nil pointer dereference")
+	}
+	return NewTypedVariable(ptr.Elem(), addr), nil
+}
+
+// DynamicValue returns the value held in interface tv, with its
+// dynamic type. ok is false if tv is the nil interface.
+func (tv TypedValue) DynamicValue() (dv TypedValue, ok bool) {
+	itf, isIface := tv.Value.(iface)
+	if !isIface || itf.t == nil {
+		return TypedValue{}, false
+	}
+	return TypedValue{Type: itf.t, Value: itf.v}, true
+}
+
+// TypeAssert evaluates the type assertion tv.(t), as in typeAssert,
+// but returns an error rather than panicking.
+func (tv TypedValue) TypeAssert(t types.Type) (TypedValue, error) {
+	if _, ok := tv.Type.Underlying().(*types.Interface); !ok {
+		return TypedValue{}, fmt.Errorf("invalid type assertion: non-interface type %s",
+			tv.Type)
+	}
+	itf := tv.Value.(iface)
+	if itf.t == nil {
+		return TypedValue{}, fmt.Errorf("interface conversion: interface is nil, not %s", t)
+	}
+	if idst, ok := t.Underlying().(*types.Interface); ok {
+		if err := checkInterface(i, idst, itf); err != "" {
+			return TypedValue{}, errors.New(err)
+		}
+		return TypedValue{Type: t, Value: itf}, nil
+	}
+	if !types.IsIdentical(itf.t, t) {
+		return TypedValue{}, fmt.Errorf("interface conversion: interface is %s, not %s", itf.t, t)
+	}
+	return TypedValue{Type: t, Value: copyVal(itf.v)}, nil
+}
+
+// field returns field number index of struct tv, automatically
+// dereferencing a pointer to a struct.
+func (tv TypedValue) field(index int) (TypedValue, error) {
+	if _, ok := tv.Type.Underlying().(*types.Pointer); ok {
+		var err error
+		if tv, err = tv.Deref(); err != nil {
+			return TypedValue{}, err
+		}
+	}
+	st := tv.Type.Underlying().(*types.Struct)
+	s := tv.Value.(structure)
+	f := TypedValue{Type: st.Field(index).Type(), Value: s[index]}
+	if tv.Addr != nil {
+		f.Addr = &s[index]
+	}
+	return f, nil
+}
+
+// fieldPath returns the index path of the field called name in the
+// struct type t, or in a struct t points to, looking through embedded
+// fields breadth first as the language's selector rules require. It
+// returns nil if there is no such field, or if name is ambiguous.
+func fieldPath(t types.Type, pkg *types.Package, name string) []int {
+	type entry struct {
+		t    types.Type
+		path []int
+	}
+	current := []entry{{t, nil}}
+	seen := make(map[*types.Named]bool)
+	for len(current) > 0 {
+		var next []entry
+		var found []int
+		nfound := 0
+		for _, e := range current {
+			t := e.t
+			if ptr, ok := t.Underlying().(*types.Pointer); ok {
+				t = ptr.Elem()
+			}
+			if named, ok := t.(*types.Named); ok {
+				if seen[named] {
+					continue
+				}
+				seen[named] = true
+			}
+			st, ok := t.Underlying().(*types.Struct)
+			if !ok {
+				continue
+			}
+			for i, n := 0, st.NumFields(); i < n; i++ {
+				f := st.Field(i)
+				path := append(append([]int(nil), e.path...), i)
+				if f.Name() == name && (ast.IsExported(name) || f.Pkg() == pkg) {
+					found = path
+					nfound++
+				}
+				if f.Anonymous() {
+					next = append(next, entry{f.Type(), path})
+				}
+			}
+		}
+		switch {
+		case nfound == 1:
+			return found
+		case nfound > 1:
+			return nil // ambiguous selector
+		}
+		current = next
+	}
+	return nil
+}
+
+// walk follows the field index path from tv.
+func (tv TypedValue) walk(path []int) (TypedValue, error) {
+	var err error
+	for _, index := range path {
+		if tv, err = tv.field(index); err != nil {
+			return TypedValue{}, err
+		}
+	}
+	return tv, nil
+}
+
+// Select evaluates the selector expression tv.name, where tv is a
+// struct, a pointer to one, or a value with methods. Fields of
+// embedded structs are promoted, and a method selection yields a
+// method value: a closure with the receiver bound. pkg is the package
+// the expression is evaluated in, which decides whether unexported
+// names are visible.
+func (tv TypedValue) Select(prog *ssa2.Program, pkg *types.Package, name string) (TypedValue, error) {
+	if path := fieldPath(tv.Type, pkg, name); path != nil {
+		return tv.walk(path)
+	}
+
+	mset := tv.Type.MethodSet()
+	sel := mset.Lookup(pkg, name)
+	if sel == nil {
+		// Addressable values get the methods of the pointer type too.
+		if _, isPtr := tv.Type.Underlying().(*types.Pointer); !isPtr && tv.Addr != nil {
+			sel = types.NewPointer(tv.Type).MethodSet().Lookup(pkg, name)
+		}
+	}
+	if sel == nil {
+		return TypedValue{}, fmt.Errorf("type %s has no field or method %s", tv.Type, name)
+	}
+
+	// Find the receiver, which may be an embedded field.
+	index := sel.Index()
+	recv, err := tv.walk(index[:len(index)-1])
+	if err != nil {
+		return TypedValue{}, err
+	}
+	obj := sel.Obj().(*types.Func)
+	sig := obj.Type().(*types.Signature)
+	if _, isIface := recv.Type.Underlying().(*types.Interface); isIface {
+		if recv.Value.(iface).t == nil {
+			return TypedValue{}, errors.New("invalid memory address or nil pointer dereference")
+		}
+	} else {
+		_, wantPtr := sig.Recv().Type().Underlying().(*types.Pointer)
+		_, havePtr := recv.Type.Underlying().(*types.Pointer)
+		switch {
+		case wantPtr && !havePtr:
+			if recv.Addr == nil {
+				return TypedValue{}, fmt.Errorf("cannot take the address of a value of type %s", recv.Type)
+			}
+			recv = TypedValue{Type: types.NewPointer(recv.Type), Value: recv.Addr}
+		case !wantPtr && havePtr:
+			if recv, err = recv.Deref(); err != nil {
+				return TypedValue{}, err
+			}
+			recv.Value = copyVal(recv.Value)
+		}
+	}
+	fn := prog.BoundMethodWrapper(obj)
+	return TypedValue{
+		Type:  types.NewSignature(nil, nil, sig.Params(), sig.Results(), sig.IsVariadic()),
+		Value: &closure{fn, []Value{recv.Value}},
+	}, nil
+}
+
+// Index evaluates the index expression tv[idx], where tv is an array,
+// a pointer to an array, a slice, a string or a map. idx is converted
+// to the key or index type as by ConvertValue.
+func (tv TypedValue) Index(idx interface{}) (TypedValue, error) {
+	if ptr, ok := tv.Type.Underlying().(*types.Pointer); ok {
+		if _, ok := ptr.Elem().Underlying().(*types.Array); ok {
+			var err error
+			if tv, err = tv.Deref(); err != nil {
+				return TypedValue{}, err
+			}
+		}
+	}
+	if m, ok := tv.Type.Underlying().(*types.Map); ok {
+		key, err := ConvertValue(idx, m.Key())
+		if err != nil {
+			return TypedValue{}, err
+		}
+		var v Value
+		switch x := tv.Value.(type) {
+		case map[Value]Value:
+			v = x[key]
+		case *hashmap:
+			if x != nil {
+				v = x.lookup(key.(hashable))
+			}
+		}
+		if v == nil {
+			v = zero(m.Elem())
+		}
+		return TypedValue{Type: m.Elem(), Value: copyVal(v), mapVal: tv.Value, mapKey: key}, nil
+	}
+
+	ix, err := ConvertValue(idx, types.Typ[types.Int])
+	if err != nil {
+		return TypedValue{}, err
+	}
+	n := ix.(int)
+	var elems []Value
+	var elemType types.Type
+	addressable := true
+	switch t := tv.Type.Underlying().(type) {
+	case *types.Slice:
+		elems, elemType = tv.Value.([]Value), t.Elem()
+	case *types.Array:
+		elems, elemType = tv.Value.(array), t.Elem()
+		addressable = tv.Addr != nil
+	case *types.Basic:
+		if t.Info()&types.IsString == 0 {
+			return TypedValue{}, fmt.Errorf("cannot index value of type %s", tv.Type)
+		}
+		str := tv.Value.(string)
+		if n < 0 || n >= len(str) {
+			return TypedValue{}, fmt.Errorf("index out of range [%d] with length %d", n, len(str))
+		}
+		return TypedValue{Type: types.Typ[types.Byte], Value: str[n]}, nil
+	default:
+		return TypedValue{}, fmt.Errorf("cannot index value of type %s", tv.Type)
+	}
+	if n < 0 || n >= len(elems) {
+		return TypedValue{}, fmt.Errorf("index out of range [%d] with length %d", n, len(elems))
+	}
+	elem := TypedValue{Type: elemType, Value: elems[n]}
+	if addressable {
+		elem.Addr = &elems[n]
+	}
+	return elem, nil
+}
+
+// Assign stores x, converted to tv's type as by ConvertValue, in the
+// variable or map element tv came from, and returns the new value.
+func (tv TypedValue) Assign(x interface{}) (TypedValue, error) {
+	v, err := ConvertValue(x, tv.Type)
+	if err != nil {
+		return TypedValue{}, err
+	}
+	switch {
+	case tv.Addr != nil:
+		*tv.Addr = v
+	case tv.mapVal != nil:
+		switch m := tv.mapVal.(type) {
+		case map[Value]Value:
+			if m == nil {
+				return TypedValue{}, errors.New("assignment to entry in nil map")
+			}
+			m[tv.mapKey] = v
+		case *hashmap:
+			if m == nil {
+				return TypedValue{}, errors.New("assignment to entry in nil map")
+			}
+			m.insert(tv.mapKey.(hashable), v)
+		}
+	default:
+		return TypedValue{}, errors.New("cannot assign to a value that is not a variable")
+	}
+	tv.Value = v
+	return tv, nil
+}
+
+// hostTypes gives the Go type used to represent values of each basic
+// kind.
+var hostTypes = map[types.BasicKind]reflect.Type{
+	types.Bool:       reflect.TypeOf(false),
+	types.Int:        reflect.TypeOf(int(0)),
+	types.Int8:       reflect.TypeOf(int8(0)),
+	types.Int16:      reflect.TypeOf(int16(0)),
+	types.Int32:      reflect.TypeOf(int32(0)),
+	types.Int64:      reflect.TypeOf(int64(0)),
+	types.Uint:       reflect.TypeOf(uint(0)),
+	types.Uint8:      reflect.TypeOf(uint8(0)),
+	types.Uint16:     reflect.TypeOf(uint16(0)),
+	types.Uint32:     reflect.TypeOf(uint32(0)),
+	types.Uint64:     reflect.TypeOf(uint64(0)),
+	types.Uintptr:    reflect.TypeOf(uintptr(0)),
+	types.Float32:    reflect.TypeOf(float32(0)),
+	types.Float64:    reflect.TypeOf(float64(0)),
+	types.Complex64:  reflect.TypeOf(complex64(0)),
+	types.Complex128: reflect.TypeOf(complex128(0)),
+	types.String:     reflect.TypeOf(""),
+}
+
+// defaultTypes gives the type a host value of each kind gets when it
+// is put in an interface. As for untyped constants, integers become
+// int, floating-point numbers float64, and so on.
+var defaultTypes = map[reflect.Kind]types.BasicKind{
+	reflect.Bool:       types.Bool,
+	reflect.Int:        types.Int,
+	reflect.Int8:       types.Int,
+	reflect.Int16:      types.Int,
+	reflect.Int32:      types.Int,
+	reflect.Int64:      types.Int,
+	reflect.Uint:       types.Int,
+	reflect.Uint8:      types.Int,
+	reflect.Uint16:     types.Int,
+	reflect.Uint32:     types.Int,
+	reflect.Uint64:     types.Int,
+	reflect.Uintptr:    types.Int,
+	reflect.Float32:    types.Float64,
+	reflect.Float64:    types.Float64,
+	reflect.Complex64:  types.Complex128,
+	reflect.Complex128: types.Complex128,
+	reflect.String:     types.String,
+}
+
+// ConvertValue converts x, a result of the debugger's expression
+// evaluator, to the interpreted representation of a value of type t,
+// checking that x may be assigned to a variable of type t. x is a
+// TypedValue, a host value of a basic type such as 0xfaded/eval
+// produces for constants and arithmetic, or nil.
+//
+// Host numbers are taken to be constants: they convert to any
+// numeric type that can represent them, so 3 is a valid float64 or
+// uint8, but 3.5 isn't a valid int.
+func ConvertValue(x interface{}, t types.Type) (Value, error) {
+	switch x := x.(type) {
+	case nil:
+		switch t.Underlying().(type) {
+		case *types.Pointer, *types.Slice, *types.Map, *types.Chan,
+			*types.Signature, *types.Interface:
+			return zero(t), nil
+		}
+		return nil, fmt.Errorf("cannot use nil as type %s", t)
+
+	case TypedValue:
+		if types.IsIdentical(x.Type, t) {
+			return copyVal(x.Value), nil
+		}
+		if it, ok := t.Underlying().(*types.Interface); ok {
+			if _, ok := x.Type.Underlying().(*types.Interface); ok {
+				// Interface to interface: the dynamic value
+				// must implement t.
+				itf := x.Value.(iface)
+				if itf.t == nil {
+					return iface{}, nil
+				}
+				if err := checkInterface(i, it, itf); err != "" {
+					return nil, errors.New(err)
+				}
+				return itf, nil
+			}
+			if meth, _ := types.MissingMethod(x.Type, it, true); meth != nil {
+				return nil, fmt.Errorf("cannot use value of type %s as type %s: missing method %s",
+					x.Type, t, meth.Name())
+			}
+			return iface{x.Type, copyVal(x.Value)}, nil
+		}
+		_, xNamed := x.Type.(*types.Named)
+		_, tNamed := t.(*types.Named)
+		if !(xNamed && tNamed) && types.IsIdentical(x.Type.Underlying(), t.Underlying()) {
+			return copyVal(x.Value), nil
+		}
+		return nil, fmt.Errorf("cannot use value of type %s as type %s", x.Type, t)
+	}
+
+	v := reflect.ValueOf(x)
+	if it, ok := t.Underlying().(*types.Interface); ok {
+		kind, ok := defaultTypes[v.Kind()]
+		if !ok {
+			return nil, fmt.Errorf("cannot use %v as type %s", x, t)
+		}
+		xt := types.Typ[kind]
+		if meth, _ := types.MissingMethod(xt, it, true); meth != nil {
+			return nil, fmt.Errorf("cannot use %v (type %s) as type %s: missing method %s",
+				x, xt, t, meth.Name())
+		}
+		return iface{xt, v.Convert(hostTypes[kind]).Interface()}, nil
+	}
+	b, ok := t.Underlying().(*types.Basic)
+	if !ok || hostTypes[b.Kind()] == nil {
+		return nil, fmt.Errorf("cannot use %v as type %s", x, t)
+	}
+	target := reflect.New(hostTypes[b.Kind()]).Elem()
+	overflow := fmt.Errorf("constant %v overflows %s", x, t)
+	switch {
+	case b.Info()&types.IsBoolean != 0 && v.Kind() == reflect.Bool:
+		target.SetBool(v.Bool())
+	case b.Info()&types.IsString != 0 && v.Kind() == reflect.String:
+		target.SetString(v.String())
+	case b.Info()&types.IsInteger != 0:
+		var n int64
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = v.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if v.Uint() > 1<<63-1 {
+				if b.Info()&types.IsUnsigned == 0 || target.OverflowUint(v.Uint()) {
+					return nil, overflow
+				}
+				target.SetUint(v.Uint())
+				return target.Interface(), nil
+			}
+			n = int64(v.Uint())
+		case reflect.Float32, reflect.Float64:
+			f := v.Float()
+			if f != float64(int64(f)) {
+				return nil, fmt.Errorf("constant %v truncated to integer", x)
+			}
+			n = int64(f)
+		default:
+			return nil, fmt.Errorf("cannot use %v as type %s", x, t)
+		}
+		if b.Info()&types.IsUnsigned != 0 {
+			if n < 0 || target.OverflowUint(uint64(n)) {
+				return nil, overflow
+			}
+			target.SetUint(uint64(n))
+		} else {
+			if target.OverflowInt(n) {
+				return nil, overflow
+			}
+			target.SetInt(n)
+		}
+	case b.Info()&types.IsFloat != 0:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			target.SetFloat(float64(v.Int()))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			target.SetFloat(float64(v.Uint()))
+		case reflect.Float32, reflect.Float64:
+			if target.OverflowFloat(v.Float()) {
+				return nil, overflow
+			}
+			target.SetFloat(v.Float())
+		default:
+			return nil, fmt.Errorf("cannot use %v as type %s", x, t)
+		}
+	case b.Info()&types.IsComplex != 0:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			target.SetComplex(complex(float64(v.Int()), 0))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			target.SetComplex(complex(float64(v.Uint()), 0))
+		case reflect.Float32, reflect.Float64:
+			target.SetComplex(complex(v.Float(), 0))
+		case reflect.Complex64, reflect.Complex128:
+			target.SetComplex(v.Complex())
+		default:
+			return nil, fmt.Errorf("cannot use %v as type %s", x, t)
+		}
+	default:
+		return nil, fmt.Errorf("cannot use %v as type %s", x, t)
+	}
+	return target.Interface(), nil
+}
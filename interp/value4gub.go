@@ -104,6 +104,9 @@ func toInspect(w io.Writer, v Value) {
 	case rtype:
 		io.WriteString(w, v.t.String())
 
+	case TypedValue:
+		io.WriteString(w, ToInspectTyped(v.Value, v.Type))
+
 	case tuple:
 		// Unreachable in well-formed Go programs
 		io.WriteString(w, "(")
@@ -198,3 +201,9 @@ func Type(v Value) string {
 		return "?"
 	}
 }
+
+// TupleValues returns the elements of v, the result of calling a
+// function with more than one result.
+func TupleValues(v Value) []Value {
+	return []Value(v.(tuple))
+}
@@ -0,0 +1,331 @@
+package ssa2
+
+/*
+
+This file contains routines beyond ssa proper for dumping the SSA form
+of a program as JSON, for tools that would rather not parse the
+output of DumpTo. The JSON* types give the schema; JSONVersion changes
+whenever a field is changed or removed.
+
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"reflect"
+	"sort"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+// JSONVersion is the version of the schema of the JSON* types.
+const JSONVersion = 1
+
+// JSONProgram is the JSON form of a Program.
+type JSONProgram struct {
+	Version  int            `json:"version"`
+	Packages []*JSONPackage `json:"packages"`
+	// Functions belonging to no package, such as wrappers of
+	// methods of unnamed types.
+	Functions []*JSONFunction `json:"functions,omitempty"`
+}
+
+// JSONPackage is the JSON form of a Package. Its functions include
+// methods, anonymous functions and synthetic wrappers as well as the
+// package-level functions among its members.
+type JSONPackage struct {
+	Path      string          `json:"path"`
+	Name      string          `json:"name"`
+	Members   []*JSONMember   `json:"members"`
+	Functions []*JSONFunction `json:"functions"`
+}
+
+// JSONMember is the JSON form of a package Member.
+type JSONMember struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"` // "const", "var", "func" or "type"
+	Type string `json:"type"`
+}
+
+// JSONFunction is the JSON form of a Function. Other functions are
+// referred to by their String().
+type JSONFunction struct {
+	Name      string       `json:"name"`
+	Signature string       `json:"signature"`
+	Synthetic string       `json:"synthetic,omitempty"`
+	Enclosing string       `json:"enclosing,omitempty"`
+	AnonFuncs []string     `json:"anonFuncs,omitempty"`
+	Range     *JSONRange   `json:"range,omitempty"`
+	Scope     ScopeId      `json:"scope,omitempty"`
+	Params    []*JSONVar   `json:"params,omitempty"`
+	FreeVars  []*JSONVar   `json:"freeVars,omitempty"`
+	Locals    []*JSONVar   `json:"locals,omitempty"`
+	Recover   int          `json:"recover"` // index of the recover block; -1 if none
+	Blocks    []*JSONBlock `json:"blocks,omitempty"` // none for external functions
+}
+
+// JSONVar is the JSON form of a Parameter, Capture or Alloc. The type
+// of a local is that of the variable, not of its address.
+type JSONVar struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// JSONBlock is the JSON form of a BasicBlock. Blocks are referred to
+// by their index.
+type JSONBlock struct {
+	Index       int          `json:"index"`
+	Comment     string       `json:"comment,omitempty"`
+	Preds       []int        `json:"preds"`
+	Succs       []int        `json:"succs"`
+	Idom        int          `json:"idom"` // immediate dominator; -1 for the entry block
+	DomChildren []int        `json:"domChildren"`
+	Scope       ScopeId      `json:"scope,omitempty"`
+	Instrs      []*JSONInstr `json:"instrs"`
+}
+
+// JSONInstr is the JSON form of an Instruction. Operands are given as
+// in disassembly; Text is the instruction as disassembly shows it.
+type JSONInstr struct {
+	Op       string     `json:"op"`             // e.g. "Call"
+	Name     string     `json:"name,omitempty"` // register name, if a Value
+	Type     string     `json:"type,omitempty"` // result type, if a Value
+	Operands []string   `json:"operands"`
+	Text     string     `json:"text"`
+	Range    *JSONRange `json:"range,omitempty"`
+	Scope    ScopeId    `json:"scope,omitempty"` // for Trace instructions
+}
+
+// JSONRange is a range of source positions. The end is left out when
+// unknown.
+type JSONRange struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine,omitempty"`
+	EndColumn int    `json:"endColumn,omitempty"`
+}
+
+// jsonRange gives the JSONRange from start to end, or nil if start
+// isn't known.
+func jsonRange(fset *token.FileSet, start, end token.Pos) *JSONRange {
+	if !start.IsValid() {
+		return nil
+	}
+	p := fset.Position(start)
+	r := &JSONRange{File: p.Filename, Line: p.Line, Column: p.Column}
+	if end.IsValid() {
+		e := fset.Position(end)
+		r.EndLine, r.EndColumn = e.Line, e.Column
+	}
+	return r
+}
+
+func scopeId(s *Scope) ScopeId {
+	if s == nil {
+		return 0
+	}
+	return s.scopeId
+}
+
+func blockIndexes(blocks []*BasicBlock) []int {
+	indexes := make([]int, len(blocks))
+	for i, b := range blocks {
+		indexes[i] = b.Index
+	}
+	return indexes
+}
+
+// JSON gives the JSON form of the whole of prog.
+//
+// Precondition: all packages are built.
+//
+func (prog *Program) JSON() *JSONProgram {
+	jprog := &JSONProgram{Version: JSONVersion}
+	pkgs := make(map[*Package]*JSONPackage)
+	var paths []string
+	for path := range prog.PackagesByPath {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		pkg := prog.PackagesByPath[path]
+		jpkg := pkg.json()
+		pkgs[pkg] = jpkg
+		jprog.Packages = append(jprog.Packages, jpkg)
+	}
+
+	var fns []*Function
+	for fn := range AllFunctions(prog) {
+		fns = append(fns, fn)
+	}
+	sort.Sort(byName(fns))
+	for _, fn := range fns {
+		if jpkg := pkgs[fn.Pkg]; jpkg != nil {
+			jpkg.Functions = append(jpkg.Functions, fn.JSON())
+		} else {
+			jprog.Functions = append(jprog.Functions, fn.JSON())
+		}
+	}
+	return jprog
+}
+
+// json gives the JSON form of p without its functions.
+func (p *Package) json() *JSONPackage {
+	jpkg := &JSONPackage{
+		Path:      p.Object.Path(),
+		Name:      p.Object.Name(),
+		Members:   []*JSONMember{},
+		Functions: []*JSONFunction{},
+	}
+	var names []string
+	for name := range p.Members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		mem := p.Members[name]
+		t := mem.Type()
+		if g, ok := mem.(*Global); ok {
+			t = g.Type().(*types.Pointer).Elem()
+		}
+		jpkg.Members = append(jpkg.Members, &JSONMember{
+			Name: name,
+			Kind: mem.Token().String(),
+			Type: relType(t, p.Object),
+		})
+	}
+	return jpkg
+}
+
+// JSON gives the JSON form of f.
+func (f *Function) JSON() *JSONFunction {
+	from := f.pkgobj()
+	jfn := &JSONFunction{
+		Name:      f.String(),
+		Signature: relType(f.Signature, from),
+		Synthetic: f.Synthetic,
+		Range:     jsonRange(f.Prog.Fset, f.Pos(), f.EndP()),
+		Scope:     scopeId(f.Scope),
+		Recover:   -1,
+	}
+	if f.Enclosing != nil {
+		jfn.Enclosing = f.Enclosing.String()
+	}
+	for _, anon := range f.AnonFuncs {
+		jfn.AnonFuncs = append(jfn.AnonFuncs, anon.String())
+	}
+	for _, p := range f.Params {
+		jfn.Params = append(jfn.Params, &JSONVar{p.Name(), relType(p.Type(), from)})
+	}
+	for _, fv := range f.FreeVars {
+		jfn.FreeVars = append(jfn.FreeVars, &JSONVar{fv.Name(), relType(fv.Type(), from)})
+	}
+	for _, l := range f.Locals {
+		jfn.Locals = append(jfn.Locals, &JSONVar{l.Name(), relType(deref(l.Type()), from)})
+	}
+	if f.Recover != nil {
+		jfn.Recover = f.Recover.Index
+	}
+	if f.Blocks == nil {
+		return jfn
+	}
+	f.domTree()
+	for _, b := range f.Blocks {
+		jb := &JSONBlock{
+			Index:       b.Index,
+			Comment:     b.Comment,
+			Preds:       blockIndexes(b.Preds),
+			Succs:       blockIndexes(b.Succs),
+			Idom:        -1,
+			DomChildren: []int{},
+			Scope:       scopeId(b.Scope),
+			Instrs:      []*JSONInstr{},
+		}
+		if idom := b.dom.Idom; idom != nil {
+			jb.Idom = idom.Block.Index
+		}
+		for _, child := range b.dom.Children {
+			jb.DomChildren = append(jb.DomChildren, child.Block.Index)
+		}
+		for _, instr := range b.Instrs {
+			jb.Instrs = append(jb.Instrs, jsonInstr(instr, from))
+		}
+		jfn.Blocks = append(jfn.Blocks, jb)
+	}
+	return jfn
+}
+
+// jsonInstr gives the JSON form of instr, a instruction of a function
+// of package from.
+func jsonInstr(instr Instruction, from *types.Package) *JSONInstr {
+	fset := instr.Parent().Prog.Fset
+	ji := &JSONInstr{
+		Op:       reflect.TypeOf(instr).Elem().Name(),
+		Operands: []string{},
+		Text:     instr.String(),
+	}
+	if v, ok := instr.(Value); ok {
+		ji.Name = v.Name()
+		if t := v.Type(); t != nil {
+			ji.Type = relType(t, from)
+		}
+	}
+	var buf [10]*Value
+	for _, op := range instr.Operands(buf[:0]) {
+		if *op == nil {
+			ji.Operands = append(ji.Operands, "nil")
+		} else {
+			ji.Operands = append(ji.Operands, relName(*op, instr))
+		}
+	}
+	switch instr := instr.(type) {
+	case *Trace:
+		ji.Range = jsonRange(fset, instr.Start, instr.End)
+		ji.Scope = scopeId(instr.Scope)
+	case interface {
+		Pos() token.Pos
+		EndP() token.Pos
+	}:
+		ji.Range = jsonRange(fset, instr.Pos(), instr.EndP())
+	default:
+		ji.Range = jsonRange(fset, instr.Pos(), token.NoPos)
+	}
+	return ji
+}
+
+// DumpJSON writes the JSON form of prog to w, indented.
+//
+// Precondition: all packages are built.
+//
+func (prog *Program) DumpJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(prog.JSON(), "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	_, err = w.Write(data)
+	return err
+}
+
+// ReadJSON reads a program dumped by DumpJSON.
+func ReadJSON(r io.Reader) (*JSONProgram, error) {
+	var jprog JSONProgram
+	if err := json.NewDecoder(r).Decode(&jprog); err != nil {
+		return nil, err
+	}
+	if jprog.Version != JSONVersion {
+		return nil, fmt.Errorf("JSON SSA dump has version %d; expecting %d",
+			jprog.Version, JSONVersion)
+	}
+	return &jprog, nil
+}
+
+// byName sorts functions by their String().
+type byName []*Function
+
+func (s byName) Len() int           { return len(s) }
+func (s byName) Less(i, j int) bool { return s[i].String() < s[j].String() }
+func (s byName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,91 @@
+package ssa2_test
+
+import (
+	"bytes"
+	"go/parser"
+	"reflect"
+	"testing"
+
+	"code.google.com/p/go.tools/importer"
+	"github.com/rocky/ssa-interp"
+)
+
+// Tests that the JSON dump of a program reads back as what was
+// dumped, and that it has the blocks, dominators and instructions
+// we expect.
+func TestJSONRoundTrip(t *testing.T) {
+	test := `
+package main
+
+var g int
+
+func f(x int) int {
+	if x > 0 {
+		return x
+	}
+	return -x
+}
+
+func main() {
+	add := func(y int) int { return g + y }
+	g = f(add(1))
+}
+`
+	imp := importer.New(new(importer.Config))
+	file, err := parser.ParseFile(imp.Fset, "<input>", test, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imp.CreatePackage("main", file)
+
+	prog := ssa2.NewProgram(imp.Fset, ssa2.NaiveForm|ssa2.DebugInfo)
+	if err := prog.CreatePackages(imp); err != nil {
+		t.Fatal(err)
+	}
+	prog.BuildAll()
+
+	var buf bytes.Buffer
+	if err := prog.DumpJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ssa2.ReadJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := prog.JSON()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("JSON dump doesn't read back as dumped")
+	}
+
+	var main *ssa2.JSONPackage
+	for _, pkg := range got.Packages {
+		if pkg.Path == "main" {
+			main = pkg
+		}
+	}
+	if main == nil {
+		t.Fatal("no main package in JSON dump")
+	}
+	fns := make(map[string]*ssa2.JSONFunction)
+	for _, fn := range main.Functions {
+		fns[fn.Name] = fn
+	}
+	f := fns["main.f"]
+	if f == nil {
+		t.Fatal("no main.f in JSON dump")
+	}
+	if len(f.Params) != 1 || f.Params[0].Name != "x" || f.Params[0].Type != "int" {
+		t.Errorf("main.f has params %v; want x int", f.Params)
+	}
+	entry := f.Blocks[0]
+	if entry.Idom != -1 || len(entry.Succs) != 2 || len(entry.DomChildren) == 0 {
+		t.Errorf("bad entry block of main.f: %+v", entry)
+	}
+	last := entry.Instrs[len(entry.Instrs)-1]
+	if last.Op != "If" || len(last.Operands) != 1 {
+		t.Errorf("entry block of main.f ends with %+v; want If", last)
+	}
+	if anon := fns["main.main$1"]; anon == nil || anon.Enclosing != "main.main" {
+		t.Errorf("bad anonymous function of main.main: %+v", anon)
+	}
+}
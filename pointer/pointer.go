@@ -0,0 +1,241 @@
+// Copyright 2013 Rocky Bernstein.
+
+// Package pointer is an Andersen-style points-to analysis of ssa2
+// programs.
+//
+// The analysis is inclusion-based, flow- and context-insensitive, and
+// field-insensitive: an object is one abstract location, whichever of
+// its fields or elements is used. Objects are labeled with what
+// creates them: an Alloc, a Global, a MakeSlice, MakeMap or MakeChan,
+// a call of append, a MakeClosure or a Function for function values,
+// and a MakeInterface for the box holding the dynamic value of an
+// interface.
+//
+// Starting from some root functions, such as main and init, calls are
+// resolved on the fly: calls through interfaces go to the methods of
+// the types of the boxes the interface may hold, and calls of
+// function values to the functions and closures the value may be.
+// Functions without code, such as those implemented in the
+// interpreter, are assumed to return nothing and keep nothing they
+// are passed, so what goes through them is missed.
+package pointer
+
+import (
+	"sort"
+
+	"code.google.com/p/go.tools/go/types"
+	"github.com/rocky/ssa-interp"
+)
+
+// An Object is an abstract memory location or function value.
+type Object struct {
+	// Label is what creates the object; see the package comment.
+	Label    ssa2.Value
+	contents *node // what may be stored in the object
+	fn       *ssa2.Function
+}
+
+// Func gives the function which creates o, or nil for globals and
+// functions.
+func (o *Object) Func() *ssa2.Function { return o.fn }
+
+// String describes o and where it is created.
+func (o *Object) String() string {
+	var s string
+	switch l := o.Label.(type) {
+	case *ssa2.Function:
+		return "func " + l.String()
+	case *ssa2.Global:
+		return "global " + l.FullName()
+	case *ssa2.Call:
+		s = "append array"
+	case ssa2.Instruction:
+		s = l.String()
+	default:
+		s = o.Label.Name()
+	}
+	if o.fn != nil {
+		s += " in " + o.fn.String()
+		if position := o.fn.Prog.Fset.Position(o.Label.Pos()); position.IsValid() {
+			s += " at " + position.String()
+		}
+	}
+	return s
+}
+
+// byPosition sorts objects by the position of their labels, then by
+// description.
+type byPosition []*Object
+
+func (s byPosition) Len() int      { return len(s) }
+func (s byPosition) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byPosition) Less(i, j int) bool {
+	if a, b := s[i].Label.Pos(), s[j].Label.Pos(); a != b {
+		return a < b
+	}
+	return s[i].String() < s[j].String()
+}
+
+// A Result gives the points-to sets the analysis found.
+type Result struct {
+	a *analysis
+}
+
+// Analyze runs the points-to analysis of the functions of prog
+// reachable from roots.
+//
+// Precondition: all packages are built.
+//
+func Analyze(prog *ssa2.Program, roots []*ssa2.Function) *Result {
+	a := newAnalysis(prog)
+	for _, fn := range roots {
+		a.addFunction(fn)
+	}
+	a.solve()
+	return &Result{a}
+}
+
+func sortedObjects(set map[*Object]bool) []*Object {
+	objs := make([]*Object, 0, len(set))
+	for o := range set {
+		objs = append(objs, o)
+	}
+	sort.Sort(byPosition(objs))
+	return objs
+}
+
+// PointsTo gives the objects v may point to: for pointers those they
+// may be the address of, for slices their arrays, for maps, channels
+// and functions what makes them, and for interfaces their boxes.
+func (r *Result) PointsTo(v ssa2.Value) []*Object {
+	if n := r.a.nodes[v]; n != nil {
+		return sortedObjects(n.pts)
+	}
+	return nil
+}
+
+// Contents gives the objects that what is stored in o may point to.
+func (r *Result) Contents(o *Object) []*Object {
+	return sortedObjects(o.contents.pts)
+}
+
+// PointsToVar gives the objects the value of a variable may point to,
+// given the Alloc or Global for its address.
+func (r *Result) PointsToVar(v ssa2.Value) []*Object {
+	set := make(map[*Object]bool)
+	if n := r.a.nodes[v]; n != nil {
+		for o := range n.pts {
+			for p := range o.contents.pts {
+				set[p] = true
+			}
+		}
+	}
+	return sortedObjects(set)
+}
+
+// MayAlias reports whether v and w may point to the same object.
+func (r *Result) MayAlias(v, w ssa2.Value) bool {
+	n, m := r.a.nodes[v], r.a.nodes[w]
+	if n == nil || m == nil {
+		return false
+	}
+	for o := range n.pts {
+		if m.pts[o] {
+			return true
+		}
+	}
+	return false
+}
+
+// SendChannels gives the channels, labeled by their MakeChan, which
+// send may send on.
+func (r *Result) SendChannels(send *ssa2.Send) []*Object {
+	var chans []*Object
+	for _, o := range r.PointsTo(send.Chan) {
+		if _, ok := o.Label.(*ssa2.MakeChan); ok {
+			chans = append(chans, o)
+		}
+	}
+	return chans
+}
+
+// Reachable reports whether the analysis found fn may be called.
+func (r *Result) Reachable(fn *ssa2.Function) bool {
+	return r.a.funcs[fn] != nil
+}
+
+// Escapes reports whether the variable of alloc may be used after its
+// function returns: whether a value of another function may point to
+// it, or an object which escapes or isn't local to the function may
+// hold its address. The builder decides Alloc.Heap from the syntax
+// alone, so this can be false for heap allocations.
+func (r *Result) Escapes(alloc *ssa2.Alloc) bool {
+	n := r.a.nodes[alloc]
+	if n == nil {
+		return false
+	}
+	for o := range n.pts {
+		if o.Label == alloc {
+			return r.a.escapes()[o]
+		}
+	}
+	return false
+}
+
+// escapes gives the objects which escape, computing them the first
+// time.
+func (a *analysis) escapes() map[*Object]bool {
+	if a.escaped != nil {
+		return a.escaped
+	}
+	esc := make(map[*Object]bool)
+	var mark func(o *Object)
+	mark = func(o *Object) {
+		if esc[o] {
+			return
+		}
+		esc[o] = true
+		for p := range o.contents.pts {
+			mark(p)
+		}
+	}
+	// Objects which aren't local, and those which values of other
+	// functions point to, escape. So does whatever they hold.
+	for _, o := range a.objects {
+		if _, local := o.Label.(*ssa2.Alloc); !local {
+			mark(o)
+		}
+	}
+	for v, n := range a.nodes {
+		instr, ok := v.(ssa2.Instruction)
+		var fn *ssa2.Function
+		switch v := v.(type) {
+		case *ssa2.Parameter:
+			fn = v.Parent()
+		case *ssa2.Capture:
+			fn = v.Parent()
+		default:
+			if ok {
+				fn = instr.Parent()
+			}
+		}
+		for o := range n.pts {
+			if fn == nil || o.fn != fn {
+				mark(o)
+			}
+		}
+	}
+	for _, n := range a.returns {
+		for o := range n.pts {
+			mark(o)
+		}
+	}
+	a.escaped = esc
+	return esc
+}
+
+// isInterface reports whether t is an interface type.
+func isInterface(t types.Type) bool {
+	_, ok := t.Underlying().(*types.Interface)
+	return ok
+}
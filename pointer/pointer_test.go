@@ -0,0 +1,142 @@
+// Copyright 2013 Rocky Bernstein.
+
+package pointer_test
+
+import (
+	"go/parser"
+	"testing"
+
+	"code.google.com/p/go.tools/importer"
+	"github.com/rocky/ssa-interp"
+	"github.com/rocky/ssa-interp/pointer"
+)
+
+const src = `
+package main
+
+type I interface{ Get() *int }
+
+type T struct{ p *int }
+
+func (t *T) Get() *int { return t.p }
+
+var g *int
+
+func keep(p *int) { g = p }
+
+func id(p *int) *int { return p }
+
+func main() {
+	x, y, z := 1, 2, 3
+	var p *int
+	if x > 0 {
+		p = &x
+	} else {
+		p = &y
+	}
+	var i I = &T{p}
+	q := i.Get()
+	f := id
+	r := f(&z)
+	keep(r)
+
+	c1, c2 := make(chan *int, 1), make(chan *int, 1)
+	c := c1
+	c <- q
+	<-c2
+
+	local := 4
+	s := &local
+	_ = *s
+}
+`
+
+func analyze(t *testing.T) (*ssa2.Package, *pointer.Result) {
+	imp := importer.New(new(importer.Config))
+	file, err := parser.ParseFile(imp.Fset, "<input>", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := imp.CreatePackage("main", file)
+	prog := ssa2.NewProgram(imp.Fset, ssa2.NaiveForm)
+	if err := prog.CreatePackages(imp); err != nil {
+		t.Fatal(err)
+	}
+	prog.BuildAll()
+	pkg := prog.Package(info.Pkg)
+	roots := []*ssa2.Function{pkg.Func("init"), pkg.Func("main")}
+	return pkg, pointer.Analyze(prog, roots)
+}
+
+// local gives the Alloc of the variable called name in fn.
+func local(t *testing.T, fn *ssa2.Function, name string) *ssa2.Alloc {
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			if a, ok := instr.(*ssa2.Alloc); ok && a.Comment == name {
+				return a
+			}
+		}
+	}
+	t.Fatalf("no variable %s in %s", name, fn)
+	return nil
+}
+
+// labels gives the labels of objs.
+func labels(objs []*pointer.Object) map[ssa2.Value]bool {
+	m := make(map[ssa2.Value]bool)
+	for _, o := range objs {
+		m[o.Label] = true
+	}
+	return m
+}
+
+func TestPointsTo(t *testing.T) {
+	pkg, r := analyze(t)
+	main := pkg.Func("main")
+	x, y, z := local(t, main, "x"), local(t, main, "y"), local(t, main, "z")
+
+	// p points to x or y; through the interface call, so does q.
+	for _, name := range []string{"p", "q"} {
+		pts := labels(r.PointsToVar(local(t, main, name)))
+		if len(pts) != 2 || !pts[x] || !pts[y] {
+			t.Errorf("%s points to %v; want x and y", name, r.PointsToVar(local(t, main, name)))
+		}
+	}
+
+	// Through the call of a function value, r points to z.
+	if pts := labels(r.PointsToVar(local(t, main, "r"))); len(pts) != 1 || !pts[z] {
+		t.Errorf("r points to %v; want z", r.PointsToVar(local(t, main, "r")))
+	}
+
+	// z is kept in a global, so it escapes; local doesn't.
+	if !r.Escapes(z) {
+		t.Errorf("z should escape")
+	}
+	if r.Escapes(local(t, main, "local")) {
+		t.Errorf("local shouldn't escape")
+	}
+}
+
+func TestSendChannels(t *testing.T) {
+	pkg, r := analyze(t)
+	main := pkg.Func("main")
+	var send *ssa2.Send
+	for _, b := range main.Blocks {
+		for _, instr := range b.Instrs {
+			if s, ok := instr.(*ssa2.Send); ok {
+				send = s
+			}
+		}
+	}
+	if send == nil {
+		t.Fatal("no send in main")
+	}
+	chans := r.SendChannels(send)
+	c1 := r.PointsToVar(local(t, main, "c1"))
+	if len(chans) != 1 || len(c1) != 1 || chans[0] != c1[0] {
+		t.Errorf("c <- q sends on %v; want only c1's %v", chans, c1)
+	}
+	if !r.Reachable(pkg.Func("keep")) || !r.Reachable(pkg.Func("id")) {
+		t.Errorf("keep and id should be reachable")
+	}
+}
@@ -0,0 +1,361 @@
+// Copyright 2013 Rocky Bernstein.
+
+package pointer
+
+import (
+	"go/token"
+
+	"code.google.com/p/go.tools/go/types"
+	"github.com/rocky/ssa-interp"
+)
+
+// A node is a set of objects the analysis finds something may point
+// to, with the constraints on it: its objects are copied to succs,
+// what they hold is copied to loads, and what stores hold is copied
+// into them. Through calls, a function value or interface node calls
+// what its objects stand for.
+type node struct {
+	pts    map[*Object]bool
+	delta  map[*Object]bool // objects not yet passed on
+	succs  []*node
+	loads  []*node
+	stores []*node
+	calls  []*callSite
+	queued bool
+}
+
+// A callSite is a call of a function value or through an interface.
+type callSite struct {
+	site   ssa2.CallInstruction
+	invoke bool
+}
+
+// A funcInfo holds the nodes of a function the analysis reached.
+type funcInfo struct {
+	params []*node
+	result *node // all results, field-insensitively
+}
+
+type analysis struct {
+	prog     *ssa2.Program
+	nodes    map[ssa2.Value]*node
+	objects  map[ssa2.Value]*Object
+	funcs    map[*ssa2.Function]*funcInfo
+	returns  []*node
+	worklist []*node
+	escaped  map[*Object]bool
+}
+
+func newAnalysis(prog *ssa2.Program) *analysis {
+	return &analysis{
+		prog:    prog,
+		nodes:   make(map[ssa2.Value]*node),
+		objects: make(map[ssa2.Value]*Object),
+		funcs:   make(map[*ssa2.Function]*funcInfo),
+	}
+}
+
+func newNode() *node {
+	return &node{pts: make(map[*Object]bool), delta: make(map[*Object]bool)}
+}
+
+// valueNode gives the node of v, adding one if need be. Functions and
+// globals point to their objects.
+func (a *analysis) valueNode(v ssa2.Value) *node {
+	n := a.nodes[v]
+	if n == nil {
+		n = newNode()
+		a.nodes[v] = n
+		switch v := v.(type) {
+		case *ssa2.Function:
+			a.addObject(n, a.object(v, nil))
+		case *ssa2.Global:
+			a.addObject(n, a.object(v, nil))
+		}
+	}
+	return n
+}
+
+// object gives the object labeled with label, created in fn.
+func (a *analysis) object(label ssa2.Value, fn *ssa2.Function) *Object {
+	o := a.objects[label]
+	if o == nil {
+		o = &Object{Label: label, contents: newNode(), fn: fn}
+		a.objects[label] = o
+	}
+	return o
+}
+
+// addObject adds o to the objects of n.
+func (a *analysis) addObject(n *node, o *Object) {
+	if n.pts[o] {
+		return
+	}
+	n.pts[o] = true
+	n.delta[o] = true
+	if !n.queued {
+		n.queued = true
+		a.worklist = append(a.worklist, n)
+	}
+}
+
+// copyEdge makes the objects of dst include those of src.
+func (a *analysis) copyEdge(src, dst *node) {
+	if src == dst {
+		return
+	}
+	src.succs = append(src.succs, dst)
+	for o := range src.pts {
+		a.addObject(dst, o)
+	}
+}
+
+// load makes dst include what the objects of src hold.
+func (a *analysis) load(src, dst *node) {
+	src.loads = append(src.loads, dst)
+	for o := range src.pts {
+		a.copyEdge(o.contents, dst)
+	}
+}
+
+// store makes the objects of dst hold the objects of src.
+func (a *analysis) store(src, dst *node) {
+	dst.stores = append(dst.stores, src)
+	for o := range dst.pts {
+		a.copyEdge(src, o.contents)
+	}
+}
+
+// dynCall makes the call at site of what the objects of n stand for.
+func (a *analysis) dynCall(n *node, site ssa2.CallInstruction, invoke bool) {
+	cs := &callSite{site, invoke}
+	n.calls = append(n.calls, cs)
+	for o := range n.pts {
+		a.callObject(cs, o)
+	}
+}
+
+// callObject makes the call cs of what o stands for: a function, a
+// closure or, for calls through interfaces, the method of the type of
+// a box.
+func (a *analysis) callObject(cs *callSite, o *Object) {
+	call := cs.site.Common()
+	if cs.invoke {
+		mi, ok := o.Label.(*ssa2.MakeInterface)
+		if !ok {
+			return
+		}
+		T := mi.X.Type()
+		sel := T.MethodSet().Lookup(call.Method.Pkg(), call.Method.Name())
+		if sel == nil {
+			return
+		}
+		fn := a.prog.Method(sel)
+		info := a.addFunction(fn)
+		if len(info.params) > 0 {
+			a.copyEdge(o.contents, info.params[0])
+		}
+		a.connect(cs.site, fn, call.Args, 1)
+		return
+	}
+	switch l := o.Label.(type) {
+	case *ssa2.Function:
+		a.connect(cs.site, l, call.Args, 0)
+	case *ssa2.MakeClosure:
+		a.connect(cs.site, l.Fn.(*ssa2.Function), call.Args, 0)
+	}
+}
+
+// connect passes args of the call at site to the parameters of fn
+// from the first-th on, and its results back.
+func (a *analysis) connect(site ssa2.CallInstruction, fn *ssa2.Function, args []ssa2.Value, first int) {
+	info := a.addFunction(fn)
+	for i, arg := range args {
+		if first+i < len(info.params) {
+			a.copyEdge(a.valueNode(arg), info.params[first+i])
+		}
+	}
+	if v := site.Value(); v != nil {
+		a.copyEdge(info.result, a.valueNode(v))
+	}
+}
+
+// solve passes objects along the constraints until nothing changes.
+func (a *analysis) solve() {
+	for len(a.worklist) > 0 {
+		n := a.worklist[0]
+		a.worklist = a.worklist[1:]
+		n.queued = false
+		delta := n.delta
+		n.delta = make(map[*Object]bool)
+		for o := range delta {
+			for _, dst := range n.loads {
+				a.copyEdge(o.contents, dst)
+			}
+			for _, src := range n.stores {
+				a.copyEdge(src, o.contents)
+			}
+			for _, cs := range n.calls {
+				a.callObject(cs, o)
+			}
+			for _, succ := range n.succs {
+				a.addObject(succ, o)
+			}
+		}
+	}
+}
+
+// addFunction adds the constraints of the code of fn, if it wasn't
+// reached before.
+func (a *analysis) addFunction(fn *ssa2.Function) *funcInfo {
+	if info := a.funcs[fn]; info != nil {
+		return info
+	}
+	info := &funcInfo{result: newNode()}
+	a.funcs[fn] = info
+	a.returns = append(a.returns, info.result)
+	for _, p := range fn.Params {
+		info.params = append(info.params, a.valueNode(p))
+	}
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			a.addInstr(fn, info, instr)
+		}
+	}
+	return info
+}
+
+// addInstr adds the constraints of instr, an instruction of fn.
+func (a *analysis) addInstr(fn *ssa2.Function, info *funcInfo, instr ssa2.Instruction) {
+	val := func(v ssa2.Value) *node { return a.valueNode(v) }
+	switch instr := instr.(type) {
+	case *ssa2.Alloc:
+		a.addObject(val(instr), a.object(instr, fn))
+	case *ssa2.MakeSlice:
+		a.addObject(val(instr), a.object(instr, fn))
+	case *ssa2.MakeMap:
+		a.addObject(val(instr), a.object(instr, fn))
+	case *ssa2.MakeChan:
+		a.addObject(val(instr), a.object(instr, fn))
+	case *ssa2.MakeInterface:
+		o := a.object(instr, fn)
+		a.addObject(val(instr), o)
+		a.copyEdge(val(instr.X), o.contents)
+	case *ssa2.MakeClosure:
+		a.addObject(val(instr), a.object(instr, fn))
+		closure := instr.Fn.(*ssa2.Function)
+		a.addFunction(closure)
+		for i, b := range instr.Bindings {
+			if i < len(closure.FreeVars) {
+				a.copyEdge(val(b), val(closure.FreeVars[i]))
+			}
+		}
+	case *ssa2.Phi:
+		for _, e := range instr.Edges {
+			a.copyEdge(val(e), val(instr))
+		}
+	case *ssa2.ChangeType:
+		a.copyEdge(val(instr.X), val(instr))
+	case *ssa2.Convert:
+		a.copyEdge(val(instr.X), val(instr))
+	case *ssa2.ChangeInterface:
+		a.copyEdge(val(instr.X), val(instr))
+	case *ssa2.Slice:
+		a.copyEdge(val(instr.X), val(instr))
+	case *ssa2.FieldAddr:
+		a.copyEdge(val(instr.X), val(instr))
+	case *ssa2.Field:
+		a.copyEdge(val(instr.X), val(instr))
+	case *ssa2.IndexAddr:
+		a.copyEdge(val(instr.X), val(instr))
+	case *ssa2.Index:
+		a.copyEdge(val(instr.X), val(instr))
+	case *ssa2.Extract:
+		a.copyEdge(val(instr.Tuple), val(instr))
+	case *ssa2.Range:
+		a.copyEdge(val(instr.X), val(instr))
+	case *ssa2.Next:
+		if !instr.IsString {
+			a.load(val(instr.Iter), val(instr))
+		}
+	case *ssa2.Lookup:
+		if _, isString := instr.X.Type().Underlying().(*types.Basic); !isString {
+			a.load(val(instr.X), val(instr))
+		}
+	case *ssa2.TypeAssert:
+		if isInterface(instr.AssertedType) {
+			a.copyEdge(val(instr.X), val(instr))
+		} else {
+			a.load(val(instr.X), val(instr))
+		}
+	case *ssa2.UnOp:
+		if instr.Op == token.MUL || instr.Op == token.ARROW {
+			a.load(val(instr.X), val(instr))
+		}
+	case *ssa2.Store:
+		a.store(val(instr.Val), val(instr.Addr))
+	case *ssa2.Send:
+		a.store(val(instr.X), val(instr.Chan))
+	case *ssa2.MapUpdate:
+		a.store(val(instr.Key), val(instr.Map))
+		a.store(val(instr.Value), val(instr.Map))
+	case *ssa2.Select:
+		for _, st := range instr.States {
+			if st.Send != nil {
+				a.store(val(st.Send), val(st.Chan))
+			} else {
+				a.load(val(st.Chan), val(instr))
+			}
+		}
+	case *ssa2.Return:
+		for _, r := range instr.Results {
+			a.copyEdge(val(r), info.result)
+		}
+	case ssa2.CallInstruction:
+		a.addCall(fn, instr)
+	}
+}
+
+// addCall adds the constraints of the call at site, in fn.
+func (a *analysis) addCall(fn *ssa2.Function, site ssa2.CallInstruction) {
+	call := site.Common()
+	if b, ok := call.Value.(*ssa2.Builtin); ok {
+		a.addBuiltinCall(fn, site, b.Name())
+		return
+	}
+	switch {
+	case call.IsInvoke():
+		a.dynCall(a.valueNode(call.Value), site, true)
+	case call.StaticCallee() != nil:
+		a.connect(site, call.StaticCallee(), call.Args, 0)
+	default:
+		a.dynCall(a.valueNode(call.Value), site, false)
+	}
+}
+
+// addBuiltinCall adds the constraints of the call at site, in fn, of
+// the built-in called name. Only append and copy move pointers.
+func (a *analysis) addBuiltinCall(fn *ssa2.Function, site ssa2.CallInstruction, name string) {
+	args := site.Common().Args
+	switch name {
+	case "append":
+		// The result may be the array of the first argument or a new
+		// one, holding the elements of both arguments.
+		v := site.Value()
+		if v == nil {
+			return
+		}
+		res := a.valueNode(v)
+		a.addObject(res, a.object(v, fn))
+		a.copyEdge(a.valueNode(args[0]), res)
+		elems := newNode()
+		for _, arg := range args {
+			a.load(a.valueNode(arg), elems)
+		}
+		a.store(elems, res)
+	case "copy":
+		elems := newNode()
+		a.load(a.valueNode(args[1]), elems)
+		a.store(elems, a.valueNode(args[0]))
+	}
+}
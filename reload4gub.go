@@ -0,0 +1,548 @@
+package ssa2
+
+/*
+
+This file contains the reloading of packages whose source has changed
+while a program runs, for the gub debugger's "reload" command.
+
+A reloaded package is type-checked and built afresh, as a new Package,
+and its code is then moved into the old one. Its package-level
+declarations must not have changed, so that code which isn't reloaded
+can go on using them: variables keep their storage, and named types
+of the new package are replaced in the new code by the old ones.
+
+*/
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"sort"
+	"time"
+
+	"code.google.com/p/go.tools/go/types"
+	"code.google.com/p/go.tools/importer"
+)
+
+// A ReloadResult tells what Program.Reload did.
+type ReloadResult struct {
+	Packages []*Package    // the packages rebuilt
+	Files    []*token.File // their source files as they were before
+	Changed  []*Function   // functions whose code changed
+	Running  []*Function   // changed functions left running the old code
+
+	// The functions left running, and the ones which stand for
+	// them from now on.
+	replaced map[*Function]*Function
+}
+
+// Stale reports whether pos is in a source file that was replaced.
+func (r *ReloadResult) Stale(fset *token.FileSet, pos token.Pos) bool {
+	file := fset.File(pos)
+	for _, f := range r.Files {
+		if f == file {
+			return true
+		}
+	}
+	return false
+}
+
+// noteModTimes records the modification times of the source files of
+// info, so that Reload can tell which have changed.
+func (prog *Program) noteModTimes(info *importer.PackageInfo) {
+	if prog.modTimes == nil {
+		prog.modTimes = make(map[string]time.Time)
+	}
+	for _, file := range info.Files {
+		name := prog.Fset.File(file.Pos()).Name()
+		if fi, err := os.Stat(name); err == nil {
+			prog.modTimes[name] = fi.ModTime()
+		}
+	}
+}
+
+// changed reports whether a source file of pkg has been modified
+// since it was built.
+func (prog *Program) changed(pkg *Package) bool {
+	if pkg.info == nil {
+		return false
+	}
+	for _, file := range pkg.info.Files {
+		name := prog.Fset.File(file.Pos()).Name()
+		fi, err := os.Stat(name)
+		if err == nil && !fi.ModTime().Equal(prog.modTimes[name]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reload rebuilds the packages of prog which have a source file
+// modified since they were built. Each function of such a package
+// gets the code of its new version, from its next call on, unless
+// running reports that it is on the stack of some goroutine. Then
+// the code of the program and its method sets are made to call a new
+// Function instead, while the frames of the old one go on running
+// its old code; so do function values made from it before the
+// reload.
+//
+// The bodies of functions can change, but the package-level
+// declarations of a package, including the signatures of its
+// functions and the methods of its types, must stay the same;
+// otherwise Reload gives an error and leaves the program as it was.
+// The initializers of package-level variables aren't run again.
+//
+func (prog *Program) Reload(running func(*Function) bool) (*ReloadResult, error) {
+	if prog.imp == nil {
+		return nil, fmt.Errorf("the program wasn't loaded from source by an importer")
+	}
+	var stale []*Package
+	for _, pkg := range prog.AllPackages() {
+		if prog.changed(pkg) {
+			stale = append(stale, pkg)
+		}
+	}
+	stale = importOrder(stale)
+
+	// Type-check all the packages before changing any, so that an
+	// error leaves the program alone.
+	infos := make([]*importer.PackageInfo, len(stale))
+	for i, pkg := range stale {
+		info, err := prog.recheck(pkg)
+		if err != nil {
+			return nil, err
+		}
+		infos[i] = info
+	}
+
+	result := &ReloadResult{replaced: make(map[*Function]*Function)}
+	for i, pkg := range stale {
+		for _, file := range pkg.info.Files {
+			result.Files = append(result.Files, prog.Fset.File(file.Pos()))
+		}
+		prog.rebuild(pkg, infos[i], running, result)
+		prog.noteModTimes(infos[i])
+		result.Packages = append(result.Packages, pkg)
+	}
+	if len(result.replaced) > 0 {
+		prog.redirect(result.replaced)
+	}
+	sort.Sort(byPos(result.Changed))
+	sort.Sort(byPos(result.Running))
+	return result, nil
+}
+
+// importOrder sorts pkgs so that each comes after the packages it
+// imports.
+func importOrder(pkgs []*Package) []*Package {
+	byObject := make(map[*types.Package]*Package)
+	for _, pkg := range pkgs {
+		byObject[pkg.Object] = pkg
+	}
+	var order []*Package
+	seen := make(map[*Package]bool)
+	var visit func(pkg *Package)
+	visit = func(pkg *Package) {
+		if seen[pkg] {
+			return
+		}
+		seen[pkg] = true
+		for _, imp := range pkg.Object.Imports() {
+			if dep := byObject[imp]; dep != nil {
+				visit(dep)
+			}
+		}
+		order = append(order, pkg)
+	}
+	for _, pkg := range pkgs {
+		visit(pkg)
+	}
+	return order
+}
+
+// recheck parses and type-checks the source files of pkg again. It
+// gives an error if they don't type-check, or if a package-level
+// declaration has changed.
+func (prog *Program) recheck(pkg *Package) (*importer.PackageInfo, error) {
+	var files []*ast.File
+	for _, file := range pkg.info.Files {
+		name := prog.Fset.File(file.Pos()).Name()
+		f, err := parser.ParseFile(prog.Fset, name, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	info := prog.imp.CreatePackage(pkg.Object.Path(), files...)
+	if info.Err != nil {
+		return nil, info.Err
+	}
+	if change := declChange(pkg.Object.Scope(), info.Pkg.Scope()); change != "" {
+		return nil, fmt.Errorf("package %s: %s; restart the program instead",
+			pkg.Object.Path(), change)
+	}
+	return info, nil
+}
+
+// declChange describes how the package-level declarations of scope
+// new differ from those of scope old, or gives "" if they are the
+// same.
+func declChange(old, new *types.Scope) string {
+	for _, name := range old.Names() {
+		if new.Lookup(name) == nil {
+			return fmt.Sprintf("%s was removed", name)
+		}
+	}
+	for _, name := range new.Names() {
+		o, n := old.Lookup(name), new.Lookup(name)
+		if o == nil {
+			return fmt.Sprintf("%s was added", name)
+		}
+		if fmt.Sprintf("%T", o) != fmt.Sprintf("%T", n) || o.Type().String() != n.Type().String() {
+			return fmt.Sprintf("the declaration of %s changed", name)
+		}
+		switch o := o.(type) {
+		case *types.Const:
+			if o.Val().String() != n.(*types.Const).Val().String() {
+				return fmt.Sprintf("the value of constant %s changed", name)
+			}
+		case *types.TypeName:
+			if o.Type().Underlying().String() != n.Type().Underlying().String() {
+				return fmt.Sprintf("the declaration of type %s changed", name)
+			}
+			if named, ok := o.Type().(*types.Named); ok {
+				if methodSigs(named) != methodSigs(n.Type().(*types.Named)) {
+					return fmt.Sprintf("the methods of type %s changed", name)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// methodSigs describes the methods declared for named, in order of
+// name.
+func methodSigs(named *types.Named) string {
+	var sigs []string
+	for i, n := 0, named.NumMethods(); i < n; i++ {
+		m := named.Method(i)
+		sigs = append(sigs, m.Name()+" "+m.Type().String())
+	}
+	sort.Strings(sigs)
+	return fmt.Sprint(sigs)
+}
+
+// rebuild builds the package of info, the new version of pkg, and
+// moves its code into pkg, as Reload describes.
+func (prog *Program) rebuild(pkg *Package, info *importer.PackageInfo, running func(*Function) bool, result *ReloadResult) {
+	byPath, byName := prog.PackagesByPath[pkg.Object.Path()], prog.PackagesByName[pkg.Object.Name()]
+	oldDecls := funcDecls(prog.Fset, pkg.info)
+	newDecls := funcDecls(prog.Fset, info)
+
+	// Match the new package-level objects with the old ones.
+	// Variables are the old ones, keeping their storage.
+	newPkg := prog.CreatePackage(info)
+	objs := make(map[types.Object]types.Object)
+	if prog.reloaded == nil {
+		prog.reloaded = make(map[*types.Named]*types.Named)
+	}
+	oldScope := pkg.Object.Scope()
+	for _, name := range info.Pkg.Scope().Names() {
+		n, o := info.Pkg.Scope().Lookup(name), oldScope.Lookup(name)
+		objs[n] = o
+		if _, ok := n.(*types.Var); ok {
+			newPkg.values[n] = pkg.values[o]
+			newPkg.Members[name] = pkg.Members[name]
+		}
+		if _, ok := n.(*types.TypeName); !ok {
+			continue
+		}
+		if n, ok := n.Type().(*types.Named); ok {
+			o := o.Type().(*types.Named)
+			prog.reloaded[n] = o
+			for i := 0; i < n.NumMethods(); i++ {
+				for j := 0; j < o.NumMethods(); j++ {
+					if n.Method(i).Name() == o.Method(j).Name() {
+						objs[n.Method(i)] = o.Method(j)
+					}
+				}
+			}
+		}
+	}
+	newPkg.Build()
+
+	// Decide which function each new one stands for from now on.
+	canon := make(map[*Function]*Function)
+	for n, o := range objs {
+		newFn, ok := newPkg.values[n].(*Function)
+		if !ok {
+			continue
+		}
+		oldFn := pkg.values[o].(*Function)
+		key := funcKey(n.(*types.Func))
+		changed := oldDecls[key] != newDecls[key]
+		if changed {
+			result.Changed = append(result.Changed, oldFn)
+		}
+		if running != nil && running(oldFn) {
+			if changed {
+				result.Running = append(result.Running, oldFn)
+			}
+			newFn.Breakpoint = oldFn.Breakpoint
+			canon[newFn] = newFn
+			result.replaced[oldFn] = newFn
+			pkg.values[o] = newFn
+			if pkg.Members[o.Name()] == Member(oldFn) {
+				pkg.Members[o.Name()] = newFn
+			}
+		} else {
+			oldFn.replaceBody(newFn)
+			canon[newFn] = oldFn
+		}
+	}
+
+	// Fix up the new code, then make pkg the package of both the
+	// old and the new package objects.
+	for newFn := range canon {
+		prog.fixReloaded(newFn, pkg, canon)
+	}
+	for obj, v := range newPkg.values {
+		if fn, ok := v.(*Function); ok && canon[fn] != nil {
+			v = canon[fn]
+		}
+		if c, ok := v.(*Const); ok {
+			c.typ = prog.substType(c.typ)
+		}
+		pkg.values[obj] = v
+	}
+	for scope, s := range newPkg.TypeScope2Scope {
+		pkg.TypeScope2Scope[scope] = s
+	}
+	pkg.locs = newPkg.locs
+	for i := range pkg.locs {
+		if fn := pkg.locs[i].Fn; fn != nil && canon[fn] != nil {
+			pkg.locs[i].Fn = canon[fn]
+		}
+	}
+	pkg.info = info
+	prog.packages[info.Pkg] = pkg
+	if byPath != nil {
+		prog.PackagesByPath[pkg.Object.Path()] = byPath
+	} else {
+		delete(prog.PackagesByPath, pkg.Object.Path())
+	}
+	if byName != nil {
+		prog.PackagesByName[pkg.Object.Name()] = byName
+	} else {
+		delete(prog.PackagesByName, pkg.Object.Name())
+	}
+}
+
+// redirect makes the code of prog, including that of packages which
+// weren't reloaded, and its method sets call the functions replaced
+// gives in place of the running functions they replace. Function
+// values the program has already made can't be changed.
+func (prog *Program) redirect(replaced map[*Function]*Function) {
+	var rands []*Value
+	for fn := range AllFunctions(prog) {
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				rands = instr.Operands(rands[:0])
+				for _, rand := range rands {
+					if f, ok := (*rand).(*Function); ok && replaced[f] != nil {
+						*rand = replaced[f]
+					}
+				}
+			}
+		}
+	}
+	prog.methodsMu.Lock()
+	defer prog.methodsMu.Unlock()
+	prog.methodSets.Iterate(func(T types.Type, v interface{}) {
+		mapping := v.(*methodSet).mapping
+		for id, f := range mapping {
+			if replaced[f] != nil {
+				mapping[id] = replaced[f]
+			}
+		}
+	})
+}
+
+// funcDecls gives the source text of each function and method
+// declaration of info, without its doc comment, keyed by funcKey.
+func funcDecls(fset *token.FileSet, info *importer.PackageInfo) map[string]string {
+	decls := make(map[string]string)
+	for _, file := range info.Files {
+		for _, decl := range file.Decls {
+			decl, ok := decl.(*ast.FuncDecl)
+			if !ok || isBlankIdent(decl.Name) {
+				continue
+			}
+			d := *decl
+			d.Doc = nil
+			var buf bytes.Buffer
+			printer.Fprint(&buf, fset, &d)
+			if obj, ok := info.ObjectOf(decl.Name).(*types.Func); ok {
+				decls[funcKey(obj)] = buf.String()
+			}
+		}
+	}
+	return decls
+}
+
+// funcKey names the function or method obj within its package, the
+// same way for each version of the package.
+func funcKey(obj *types.Func) string {
+	if recv := obj.Type().(*types.Signature).Recv(); recv != nil {
+		if named, ok := deref(recv.Type()).(*types.Named); ok {
+			return named.Obj().Name() + "." + obj.Name()
+		}
+	}
+	return obj.Name()
+}
+
+// replaceBody gives f the code of g, a build of a newer version of
+// f's source.
+func (f *Function) replaceBody(g *Function) {
+	f.pos, f.endP, f.syntax, f.Scope = g.pos, g.endP, g.syntax, g.Scope
+	f.Params, f.Locals, f.Blocks, f.Recover = g.Params, g.Locals, g.Blocks, g.Recover
+	f.AnonFuncs, f.deadStmts = g.AnonFuncs, g.deadStmts
+	for _, p := range f.Params {
+		p.parent = f
+	}
+	for _, b := range f.Blocks {
+		b.parent = f
+	}
+	for _, anon := range f.AnonFuncs {
+		anon.Enclosing = f
+	}
+}
+
+// fixReloaded makes the code of fn, just built for a reload of pkg,
+// and of its anonymous functions, belong to pkg, call the functions
+// canon gives in place of the new ones, and use the types of pkg in
+// place of the new ones.
+func (prog *Program) fixReloaded(fn *Function, pkg *Package, canon map[*Function]*Function) {
+	if f := canon[fn]; f != nil {
+		fn = f
+	}
+	fn.Pkg = pkg
+	if fn.Enclosing != nil || canon[fn] == fn {
+		fn.Signature = prog.substType(fn.Signature).(*types.Signature)
+	}
+	for _, p := range fn.Params {
+		p.typ = prog.substType(p.typ)
+	}
+	for _, fv := range fn.FreeVars {
+		fv.typ = prog.substType(fv.typ)
+	}
+	var rands []*Value
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			if v, ok := instr.(interface {
+				setType(types.Type)
+			}); ok {
+				v.setType(prog.substType(instr.(Value).Type()))
+			}
+			if ta, ok := instr.(*TypeAssert); ok {
+				ta.AssertedType = prog.substType(ta.AssertedType)
+			}
+			rands = instr.Operands(rands[:0])
+			for _, rand := range rands {
+				switch v := (*rand).(type) {
+				case *Function:
+					if f := canon[v]; f != nil {
+						*rand = f
+					}
+				case *Const:
+					v.typ = prog.substType(v.typ)
+				}
+			}
+		}
+	}
+	for _, anon := range fn.AnonFuncs {
+		prog.fixReloaded(anon, pkg, canon)
+	}
+}
+
+// substType gives t with each named type of a reloaded package
+// replaced by the one it replaces. Struct and interface types are
+// left as they are.
+func (prog *Program) substType(t types.Type) types.Type {
+	switch t := t.(type) {
+	case *types.Named:
+		if old := prog.reloaded[t]; old != nil {
+			return old
+		}
+	case *types.Pointer:
+		if elem := prog.substType(t.Elem()); elem != t.Elem() {
+			return types.NewPointer(elem)
+		}
+	case *types.Slice:
+		if elem := prog.substType(t.Elem()); elem != t.Elem() {
+			return types.NewSlice(elem)
+		}
+	case *types.Array:
+		if elem := prog.substType(t.Elem()); elem != t.Elem() {
+			return types.NewArray(elem, t.Len())
+		}
+	case *types.Chan:
+		if elem := prog.substType(t.Elem()); elem != t.Elem() {
+			return types.NewChan(t.Dir(), elem)
+		}
+	case *types.Map:
+		key, elem := prog.substType(t.Key()), prog.substType(t.Elem())
+		if key != t.Key() || elem != t.Elem() {
+			return types.NewMap(key, elem)
+		}
+	case *types.Tuple:
+		return prog.substTuple(t)
+	case *types.Signature:
+		recv := t.Recv()
+		if recv != nil {
+			recv = prog.substVar(recv)
+		}
+		params, results := prog.substTuple(t.Params()), prog.substTuple(t.Results())
+		if recv != t.Recv() || params != t.Params() || results != t.Results() {
+			return types.NewSignature(nil, recv, params, results, t.IsVariadic())
+		}
+	}
+	return t
+}
+
+// substTuple is substType for tuples, which may be nil.
+func (prog *Program) substTuple(t *types.Tuple) *types.Tuple {
+	if t == nil {
+		return t
+	}
+	vars := make([]*types.Var, t.Len())
+	changed := false
+	for i := range vars {
+		vars[i] = prog.substVar(t.At(i))
+		changed = changed || vars[i] != t.At(i)
+	}
+	if !changed {
+		return t
+	}
+	return types.NewTuple(vars...)
+}
+
+// substVar gives v, or if substType changes its type, a copy of v
+// with the new type.
+func (prog *Program) substVar(v *types.Var) *types.Var {
+	if t := prog.substType(v.Type()); t != v.Type() {
+		return types.NewVar(v.Pos(), v.Pkg(), v.Name(), t)
+	}
+	return v
+}
+
+// byPos sorts functions by position.
+type byPos []*Function
+
+func (s byPos) Len() int           { return len(s) }
+func (s byPos) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byPos) Less(i, j int) bool { return s[i].Pos() < s[j].Pos() }
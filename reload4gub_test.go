@@ -0,0 +1,163 @@
+package ssa2_test
+
+import (
+	"go/parser"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"code.google.com/p/go.tools/go/types"
+	"code.google.com/p/go.tools/importer"
+	"github.com/rocky/ssa-interp"
+)
+
+const reloadSrc = `
+package main
+
+type T struct{ n int }
+
+func (t *T) get() int { return t.n }
+
+var g = &T{1}
+
+func f() int {
+	return 1
+}
+
+func main() {
+	println(f(), g.get())
+}
+`
+
+// writeSource writes src to path, making sure that its modification
+// time changes.
+func writeSource(t *testing.T, path, src string, mtime time.Time) {
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// returned gives the value of the constant which fn returns.
+func returned(fn *ssa2.Function) string {
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			if ret, ok := instr.(*ssa2.Return); ok && len(ret.Results) == 1 {
+				if c, ok := ret.Results[0].(*ssa2.Const); ok {
+					return c.Value.String()
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// calls reports whether caller has a static call of callee.
+func calls(caller, callee *ssa2.Function) bool {
+	for _, b := range caller.Blocks {
+		for _, instr := range b.Instrs {
+			if call, ok := instr.(ssa2.CallInstruction); ok && call.Common().StaticCallee() == callee {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "main.go")
+	mtime := time.Now().Add(-time.Hour)
+	writeSource(t, path, reloadSrc, mtime)
+
+	imp := importer.New(new(importer.Config))
+	file, err := parser.ParseFile(imp.Fset, path, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := imp.CreatePackage("main", file)
+	prog := ssa2.NewProgram(imp.Fset, ssa2.NaiveForm)
+	if err := prog.CreatePackages(imp); err != nil {
+		t.Fatal(err)
+	}
+	prog.BuildAll()
+	pkg := prog.Package(info.Pkg)
+	f, main := pkg.Func("f"), pkg.Func("main")
+
+	// Nothing has changed.
+	if result, err := prog.Reload(nil); err != nil || len(result.Packages) != 0 {
+		t.Fatalf("Reload of unchanged program gave %v, %v", result, err)
+	}
+
+	// f isn't running, so it gets the new code.
+	mtime = mtime.Add(time.Minute)
+	writeSource(t, path, strings.Replace(reloadSrc, "return 1", "return 2", 1), mtime)
+	result, err := prog.Reload(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Changed) != 1 || result.Changed[0] != f || len(result.Running) != 0 {
+		t.Errorf("Reload changed %v, left running %v; want f changed", result.Changed, result.Running)
+	}
+	if pkg.Func("f") != f || returned(f) != "2" {
+		t.Errorf("f returns %s after reload; want 2", returned(f))
+	}
+	if !calls(main, f) {
+		t.Errorf("main doesn't call f after reload")
+	}
+	if !result.Stale(prog.Fset, file.Pos()) || result.Stale(prog.Fset, f.Pos()) {
+		t.Errorf("Stale is wrong about the old and the new main.go")
+	}
+
+	// f is running, so it keeps its code, and calls go to a new f.
+	mtime = mtime.Add(time.Minute)
+	writeSource(t, path, strings.Replace(reloadSrc, "return 1", "return 3", 1), mtime)
+	result, err = prog.Reload(func(fn *ssa2.Function) bool { return fn == f })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Running) != 1 || result.Running[0] != f {
+		t.Errorf("Reload left running %v; want f", result.Running)
+	}
+	newF := pkg.Func("f")
+	if newF == f || returned(f) != "2" || returned(newF) != "3" {
+		t.Errorf("after reload, old f returns %s and new f %s; want 2 and 3",
+			returned(f), returned(newF))
+	}
+	if !calls(main, newF) {
+		t.Errorf("main doesn't call the new f after reload")
+	}
+
+	// A running method is replaced in the method sets as well.
+	T := pkg.Type("T").Object().Type()
+	sel := types.NewPointer(T).MethodSet().Lookup(pkg.Object, "get")
+	get := prog.Method(sel)
+	mtime = mtime.Add(time.Minute)
+	src := strings.Replace(reloadSrc, "return 1", "return 3", 1)
+	writeSource(t, path, strings.Replace(src, "return t.n", "return t.n + 1", 1), mtime)
+	if _, err := prog.Reload(func(fn *ssa2.Function) bool { return fn == get }); err != nil {
+		t.Fatal(err)
+	}
+	if prog.Method(sel) == get {
+		t.Errorf("the method set of *T has the old get after reload")
+	}
+
+	// Declarations can't change.
+	mtime = mtime.Add(time.Minute)
+	writeSource(t, path, reloadSrc+"\nfunc h() {}\n", mtime)
+	if _, err := prog.Reload(nil); err == nil {
+		t.Errorf("Reload of a program with a new function succeeded")
+	}
+	if pkg.Func("f") != newF || returned(newF) != "3" {
+		t.Errorf("failed Reload changed the program")
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"go/ast"
 	"go/token"
 	"sync"
+	"time"
 
 	"code.google.com/p/go.tools/go/exact"
 	"code.google.com/p/go.tools/go/types"
@@ -33,6 +34,11 @@ type Program struct {
 	methodSets          typemap.M                 // maps type to its concrete MethodSet
 	boundMethodWrappers map[*types.Func]*Function // wrappers for curried x.Method closures
 	ifaceMethodWrappers map[*types.Func]*Function // wrappers for curried I.Method functions
+
+	// For Reload; see reload4gub.go.
+	imp      *importer.Importer           // the importer the packages came from
+	modTimes map[string]time.Time         // modification times of the source files built
+	reloaded map[*types.Named]*types.Named // named types of reloaded packages, to the ones they replace
 }
 
 // A Package is a single analyzed Go package containing Members for
@@ -292,15 +298,12 @@ type Function struct {
 	targets      *targets                // linked stack of branch targets
 	lblocks      map[*ast.Object]*lblock // labelled blocks
 
-	/* Allows lookup by string name, return is index into Locals +1. 0
-       means not found. FIXME: this is not right. There can be several
-       locals with the same name. We need to disambiguate with some
-       sort of environment setting.  */
-	LocalsByName map[NameScope]uint
-
 	Breakpoint bool    // Set on runtime if we should stop here
 	Scope      *Scope  // Scope number of its first basic block.
 
+	panicBlocks map[*BasicBlock]*Panic // blocks begun after a panic (transient)
+	deadStmts   []DeadStmt             // statements after a panic, which were deleted
+
 
 }
 
@@ -476,6 +479,10 @@ type Alloc struct {
 	Comment string
 	Heap    bool
 	index   int // dense numbering; for lifting
+
+	// For a source variable, the statements after liveStart and
+	// before liveEnd are those where it is declared and in scope.
+	liveStart, liveEnd token.Pos
 }
 
 // The Phi instruction represents an SSA φ-node, which combines values
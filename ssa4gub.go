@@ -39,11 +39,6 @@ type Scope struct {
 	node *ast.Node
 }
 
-type NameScope struct {
-	Name     string
-	Scope    *Scope
-}
-
 func (s *Alloc)     EndP() token.Pos            { return s.endP }
 func (s *Builtin)   EndP() token.Pos            { return s.endP }
 func (s *Capture)   EndP() token.Pos            { return s.endP }
@@ -65,5 +60,45 @@ func (v *LocInst)   Pos() token.Pos             { return v.pos }
 func (p *Package)   Locs() []LocInst { return p.locs }
 func (p *Package)   Info() *importer.PackageInfo { return p.info }
 
+// BoundMethodWrapper returns the synthetic wrapper function for the
+// method value x.obj; the receiver x is the single free variable.
+func (prog *Program) BoundMethodWrapper(obj *types.Func) *Function {
+	return boundMethodWrapper(prog, obj)
+}
+
 func (s *Scope) ScopeId() ScopeId   { return s.scopeId }
+
+// setLiveRange records where local v of fn for variable obj is live:
+// from its declaration to the end of the scope it is declared in.
+// The syntax of a function's own scope is its signature, so for a
+// variable declared there, such as one in a function literal's body,
+// the scope ends with fn's body instead.
+func (v *Alloc) setLiveRange(obj types.Object, fn *Function) {
+	v.liveStart = obj.Pos()
+	if v.Scope == nil || v.Scope.node == nil {
+		return
+	}
+	if _, ok := (*v.Scope.node).(*ast.FuncType); ok {
+		v.liveEnd = fn.endP
+	} else {
+		v.liveEnd = (*v.Scope.node).End()
+	}
+}
+
+// LiveRange gives the positions between which statements see local v
+// declared and in scope. They are NoPos if v isn't a source variable
+// or we don't know.
+func (v *Alloc) LiveRange() (start, end token.Pos) {
+	return v.liveStart, v.liveEnd
+}
+
+// Live reports whether local v is declared and in scope at a
+// statement starting at pos. A variable's own declaration is not
+// within its range, since it is declared only once that has run.
+func (v *Alloc) Live(pos token.Pos) bool {
+	if !v.liveStart.IsValid() || !pos.IsValid() {
+		return true
+	}
+	return v.liveStart < pos && (!v.liveEnd.IsValid() || pos < v.liveEnd)
+}
 func (s *Scope) Node()    *ast.Node { return s.node }
@@ -10,6 +10,7 @@ package ssa2
 
 import (
 	"go/ast"
+	"go/doc"
 	"go/token"
 	"os"
 	"strings"
@@ -43,6 +44,9 @@ func (prog *Program) CreateTestMainPackage(pkgs ...*Package) *Package {
 	}
 	init.startBody(nil)
 	var expfuncs []*Function // all exported functions of *_test.go in pkgs, unordered
+
+	// Expected output of each runnable ExampleXXX function.
+	outputs := make(map[string]string)
 	for _, pkg := range pkgs {
 		// Initialize package to test.
 		var v Call
@@ -58,6 +62,15 @@ func (prog *Program) CreateTestMainPackage(pkgs ...*Package) *Package {
 				expfuncs = append(expfuncs, f)
 			}
 		}
+
+		// Only examples with an "Output:" comment are run.
+		if pkg.info != nil {
+			for _, ex := range doc.Examples(pkg.info.Files...) {
+				if ex.Output != "" {
+					outputs["Example"+ex.Name] = ex.Output
+				}
+			}
+		}
 	}
 	init.emit(new(Return))
 	init.finishBody()
@@ -77,12 +90,13 @@ func (prog *Program) CreateTestMainPackage(pkgs ...*Package) *Package {
 
 	// The generated code is as if compiled from this:
 	//
+	// func matchString(pat, str string) (bool, error) // external
+	//
 	// func main() {
-	//      match      := func(_, _ string) (bool, error) { return true, nil }
 	//      tests      := []testing.InternalTest{{"TestFoo", TestFoo}, ...}
 	//      benchmarks := []testing.InternalBenchmark{...}
-	//      examples   := []testing.InternalExample{...}
-	// 	testing.Main(match, tests, benchmarks, examples)
+	//      examples   := []testing.InternalExample{{"ExampleFoo", ExampleFoo, "output"}, ...}
+	// 	testing.Main(matchString, tests, benchmarks, examples)
 	// }
 
 	main := &Function{
@@ -93,26 +107,26 @@ func (prog *Program) CreateTestMainPackage(pkgs ...*Package) *Package {
 		Pkg:       testmain,
 	}
 
+	// The matcher, which filters tests and benchmarks by the
+	// -test.run and -test.bench regular expressions, has no body:
+	// like the functions of package regexp it would call, it must
+	// be provided by the interpreter.
 	matcher := &Function{
-		name:      "matcher",
+		name:      "matchString",
 		Signature: testingMainParams.At(0).Type().(*types.Signature),
 		Synthetic: "test matcher predicate",
-		Enclosing: main,
 		Pkg:       testmain,
 		Prog:      prog,
 	}
-	main.AnonFuncs = append(main.AnonFuncs, matcher)
-	matcher.startBody(nil)
-	matcher.emit(&Return{Results: []Value{vTrue, nilConst(types.Universe.Lookup("error").Type())}})
-	matcher.finishBody()
+	testmain.Members[matcher.name] = matcher
 
 	main.startBody(nil)
 	var c Call
 	c.Call.Value = testingMain
 
-	tests := testMainSlice(main, expfuncs, "Test", testingMainParams.At(1).Type())
-	benchmarks := testMainSlice(main, expfuncs, "Benchmark", testingMainParams.At(2).Type())
-	examples := testMainSlice(main, expfuncs, "Example", testingMainParams.At(3).Type())
+	tests := testMainSlice(main, expfuncs, "Test", testingMainParams.At(1).Type(), nil)
+	benchmarks := testMainSlice(main, expfuncs, "Benchmark", testingMainParams.At(2).Type(), nil)
+	examples := testMainSlice(main, expfuncs, "Example", testingMainParams.At(3).Type(), outputs)
 	_, noTests := tests.(*Const) // i.e. nil slice
 	_, noBenchmarks := benchmarks.(*Const)
 	_, noExamples := examples.(*Const)
@@ -142,17 +156,23 @@ func (prog *Program) CreateTestMainPackage(pkgs ...*Package) *Package {
 // (one of []testing.Internal{Test,Benchmark,Example}) for all
 // functions in expfuncs whose name starts with prefix (one of
 // "Test", "Benchmark" or "Example") and whose type is appropriate.
+// If outputs is non-nil, only functions that have an entry in it are
+// included, and the entry is stored in the element's Output field.
 // It returns the slice value.
 //
-func testMainSlice(fn *Function, expfuncs []*Function, prefix string, slice types.Type) Value {
+func testMainSlice(fn *Function, expfuncs []*Function, prefix string, slice types.Type, outputs map[string]string) Value {
 	tElem := slice.(*types.Slice).Elem()
 	tFunc := tElem.Underlying().(*types.Struct).Field(1).Type()
 
 	var testfuncs []*Function
 	for _, f := range expfuncs {
-		if isTest(f.Name(), prefix) && types.IsIdentical(f.Signature, tFunc) {
-			testfuncs = append(testfuncs, f)
+		if !isTest(f.Name(), prefix) || !types.IsIdentical(f.Signature, tFunc) {
+			continue
 		}
+		if _, ok := outputs[f.Name()]; outputs != nil && !ok {
+			continue
+		}
+		testfuncs = append(testfuncs, f)
 	}
 	if testfuncs == nil {
 		return nilConst(slice)
@@ -190,6 +210,18 @@ func testMainSlice(fn *Function, expfuncs []*Function, prefix string, slice type
 
 		// Emit: *pfunc = testfunc
 		emitStore(fn, pfunc, testfunc)
+
+		if outputs != nil {
+			// Emit: poutput = &pitem.Output
+			fa = &FieldAddr{X: pitem, Field: 2} // .Output
+			fa.setType(tPtrString)
+			poutput := fn.emit(fa)
+
+			// Emit: *poutput = "output"
+			emitStore(fn, poutput,
+				NewConst(exact.MakeString(outputs[testfunc.Name()]), tString,
+					token.NoPos, token.NoPos))
+		}
 	}
 
 	// Emit: slice array[:]
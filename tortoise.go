@@ -12,16 +12,22 @@ import (
 	"flag"
 	"fmt"
 	"go/build"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime"
 	"runtime/pprof"
+	"strings"
+	"time"
 
 	"code.google.com/p/go.tools/importer"
 	"github.com/rocky/ssa-interp"
+	"github.com/rocky/ssa-interp/callgraph"
 	"github.com/rocky/ssa-interp/interp"
 	"github.com/rocky/ssa-interp/gub"
 	"github.com/rocky/ssa-interp/gub/cmd"
+	"github.com/rocky/ssa-interp/vet"
 )
 
 var buildFlag = flag.String("build", "", `Options controlling the SSA builder.
@@ -33,8 +39,26 @@ F	log [F]unction SSA code.
 S	log [S]ource locations as SSA builder progresses.
 G	use binary object files from gc to provide imports (no code).
 L	build distinct packages seria[L]ly instead of in parallel.
+B	write the control-flow graph of [B]asic blocks of each function
+	of the initial packages to a GraphViz FN.cfg.dot file.
+O	write the d[O]minator tree of each function of the initial
+	packages to a GraphViz FN.dom.dot file.
+A	write the static c[A]ll graph of each initial package, found by
+	Class Hierarchy Analysis, to a GraphViz PKG.calls.dot file.
 `)
 
+var dotDirFlag = flag.String("dotdir", ".",
+	"Directory in which -build=B, O and A write their .dot files.")
+
+var dumpJSONFlag = flag.String("dump-json", "",
+	"Write the SSA form of the whole program as JSON to the named file, or to stdout for '-'.")
+
+var vetFlag = flag.Bool("vet", false,
+	"Run static checks over the SSA form of the initial packages.")
+
+var vetChecksFlag = flag.String("vetchecks", "",
+	"Comma-separated list of the -vet checks to run; all by default. Use -vetchecks=help to list them.")
+
 var runFlag = flag.Bool("run", false, "Invokes the SSA interpreter on the program.")
 
 var interpFlag = flag.String("interp", "", `Options controlling the interpreter.
@@ -43,6 +67,8 @@ R	disable [R]ecover() from panic; show interpreter crash instead.
 T	[T]race execution of the program.  Best for single-threaded programs!
 I	trace [I]int() functions before main.main()
 S	[S]atement tracing
+P	stop in the debugger at a [P]anic that nothing can recover, for
+	post-mortem debugging
 `)
 
 var gubFlag = flag.String("gub", "", `Options passed to the gub debugger.
@@ -51,15 +77,36 @@ var gubFlag = flag.String("gub", "", `Options passed to the gub debugger.
 const usage = `SSA builder and interpreter.
 Usage: tortoise [<flag> ...] [<file.go> ...] [<arg> ...]
        tortoise [<flag> ...] <import/path>   [<arg> ...]
+       tortoise [<flag> ...] test [<import/path> ...] [<test flag> ...]
 Use -help flag to display options.
 
 Examples:
 % tortoise -run -interp=S hello.go     # interpret a program, with statement tracing
 % tortoise -build=FPG hello.go         # quickly dump SSA form of a single package
+% tortoise -build=BOA hello.go         # write GraphViz graphs of a package's code
+% tortoise -dump-json=- hello.go       # dump SSA form of a program as JSON
+% tortoise -vet hello.go               # run static checks on a package
+% tortoise test ./pkg -run=Foo -v      # run a package's tests matching Foo
 `
 
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
 
+var fsFlag = flag.String("fs", "host", `File system seen by the interpreted program:
+host		the real file system
+mem		an empty in-memory file system
+mem:DIR		an in-memory copy of directory DIR
+mem:FILE.tar	an in-memory file system loaded from a tar file (.tar.gz and .tgz too)
+`)
+
+var fsReadOnlyFlag = flag.Bool("fs-readonly", false,
+	"Don't let the interpreted program modify the file system.")
+
+var fsDenyFlag = flag.String("fs-deny", "",
+	"Comma-separated list of paths the interpreted program may not access.")
+
+var noExecFlag = flag.Bool("noexec", false,
+	"Don't let the interpreted program start other processes.")
+
 func init() {
 	// If $GOMAXPROCS isn't set, use the full capacity of the machine.
 	// For small machines, use at least 4 threads.
@@ -79,6 +126,7 @@ func main() {
 	impctx := importer.Config{Build: &build.Default}
 
 	var mode ssa2.BuilderMode = ssa2.NaiveForm
+	var dotCFG, dotDom, dotCalls bool
 
 	for _, c := range *buildFlag {
 		switch c {
@@ -96,6 +144,12 @@ func main() {
 			impctx.Build = nil
 		case 'L':
 			mode |= ssa2.BuildSerially
+		case 'B':
+			dotCFG = true
+		case 'O':
+			dotDom = true
+		case 'A':
+			dotCalls = true
 		default:
 			log.Fatalf("Unknown -build option: '%c'.", c)
 		}
@@ -114,6 +168,9 @@ func main() {
 			mode |= ssa2.DebugInfo
 		case 'T':
 			interpTraceMode |= interp.EnableTracing
+		case 'P':
+			interpMode |= interp.EnablePostMortem
+			mode |= ssa2.DebugInfo
 		default:
 			log.Fatalf("Unknown -interp option: '%c'.", c)
 		}
@@ -124,6 +181,32 @@ func main() {
 		os.Exit(1)
 	}
 
+	var backend interp.SyscallBackend
+	switch {
+	case *fsFlag == "host":
+		backend = interp.HostBackend{}
+	case *fsFlag == "mem":
+		backend = interp.NewMemFS()
+	case strings.HasPrefix(*fsFlag, "mem:"):
+		memfs, err := interp.NewMemFSFromPath((*fsFlag)[len("mem:"):])
+		if err != nil {
+			log.Fatalf("Can't load -fs file system: %s", err)
+		}
+		backend = memfs
+	default:
+		log.Fatalf("Unknown -fs option: '%s'.", *fsFlag)
+	}
+	if *fsReadOnlyFlag || *fsDenyFlag != "" || *noExecFlag {
+		var policy interp.Policy
+		policy.ReadOnly = *fsReadOnlyFlag
+		policy.NoExec = *noExecFlag
+		if *fsDenyFlag != "" {
+			policy.Deny = strings.Split(*fsDenyFlag, ",")
+		}
+		backend = interp.NewPolicyBackend(backend, policy)
+	}
+	interp.SetSyscallBackend(backend)
+
 	// Profiling support.
 	if *cpuprofile != "" {
 		f, err := os.Create(*cpuprofile)
@@ -134,6 +217,12 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
+	if args[0] == "test" {
+		status := runTests(args[1:], &impctx, mode, interpMode, interpTraceMode)
+		pprof.StopCPUProfile()
+		os.Exit(status)
+	}
+
 	// Load, parse and type-check the program.
 	imp := importer.New(&impctx)
 	prog_args := args[1:]
@@ -157,6 +246,40 @@ func main() {
 
 	prog.BuildAll()
 
+	if dotCFG || dotDom || dotCalls {
+		var pkgs []*ssa2.Package
+		for _, info := range infos {
+			pkgs = append(pkgs, prog.Package(info.Pkg))
+		}
+		writeDotFiles(prog, pkgs, dotCFG, dotDom, dotCalls)
+	}
+
+	if *vetFlag {
+		var pkgs []*ssa2.Package
+		for _, info := range infos {
+			pkgs = append(pkgs, prog.Package(info.Pkg))
+		}
+		status := runVet(prog, pkgs)
+		if !*runFlag {
+			os.Exit(status)
+		}
+	}
+
+	if *dumpJSONFlag != "" {
+		w := os.Stdout
+		if *dumpJSONFlag != "-" {
+			f, err := os.Create(*dumpJSONFlag)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := prog.DumpJSON(w); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	// Run the interpreter.
 	if *runFlag {
 		// If some package defines main, run that.
@@ -177,7 +300,8 @@ func main() {
 		if main == nil {
 			log.Fatal("No main package and no tests")
 		}
-		if interpTraceMode & interp.EnableStmtTracing != 0 {
+		if interpTraceMode & interp.EnableStmtTracing != 0 ||
+			interpMode & interp.EnablePostMortem != 0 {
 			gubcmd.Init()
 			gub.Install(gubFlag)
 		}
@@ -187,3 +311,218 @@ func main() {
 			prog_args)
 	}
 }
+
+// writeDotFiles writes the GraphViz graphs the -build flags ask for,
+// for the functions of pkgs, in the -dotdir directory.
+func writeDotFiles(prog *ssa2.Program, pkgs []*ssa2.Package, cfg, dom, calls bool) {
+	create := func(name string, write func(w io.Writer) error) {
+		path := filepath.Join(*dotDirFlag, name)
+		f, err := os.Create(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		if err := write(f); err != nil {
+			log.Printf("%s: %s", path, err)
+		}
+	}
+	var callGraph *callgraph.Graph
+	if calls {
+		callGraph = callgraph.CHA(prog)
+	}
+	inPkgs := make(map[*ssa2.Package]bool)
+	for _, pkg := range pkgs {
+		inPkgs[pkg] = true
+		if calls {
+			pkg := pkg
+			create(ssa2.DotFileName(pkg.Object.Path(), "calls"), func(w io.Writer) error {
+				callGraph.WriteDot(w, pkg)
+				return nil
+			})
+		}
+	}
+	if !cfg && !dom {
+		return
+	}
+	for fn := range ssa2.AllFunctions(prog) {
+		if !inPkgs[fn.Pkg] || fn.Blocks == nil {
+			continue
+		}
+		if cfg {
+			create(ssa2.DotFileName(fn.String(), "cfg"), func(w io.Writer) error {
+				return fn.WriteCFGDot(w, nil)
+			})
+		}
+		if dom {
+			create(ssa2.DotFileName(fn.String(), "dom"), fn.WriteDomTreeDot)
+		}
+	}
+}
+
+// runVet runs the -vet checks over pkgs, reporting on stderr. It
+// returns the exit status for tortoise: 1 if there was a problem.
+func runVet(prog *ssa2.Program, pkgs []*ssa2.Package) int {
+	var checks []*vet.Checker
+	if *vetChecksFlag == "help" {
+		for _, c := range vet.Checkers() {
+			fmt.Fprintf(os.Stderr, "%-12s %s\n", c.Id, c.Doc)
+		}
+		return 0
+	}
+	if *vetChecksFlag != "" {
+		for _, id := range strings.Split(*vetChecksFlag, ",") {
+			c := vet.Lookup(id)
+			if c == nil {
+				log.Fatalf("Unknown -vetchecks check: '%s'.", id)
+			}
+			checks = append(checks, c)
+		}
+	}
+	diags := vet.Run(prog, pkgs, checks)
+	for _, d := range diags {
+		fmt.Fprintln(os.Stderr, d)
+	}
+	if len(diags) > 0 {
+		return 1
+	}
+	return 0
+}
+
+const testUsage = `Usage: tortoise [<flag> ...] test [<import/path> ...] [<test flag> ...]
+Runs the tests, benchmarks and examples of each package, "." by
+default, in the interpreter. The test flags are:
+`
+
+// runTests implements "tortoise test": it interprets the synthetic
+// test main package of each package named in args, reporting on each
+// as "go test" does. It returns the exit status for tortoise.
+func runTests(args []string, impctx *importer.Config, mode ssa2.BuilderMode,
+	interpMode interp.Mode, interpTraceMode interp.TraceMode) int {
+
+	testFlags := flag.NewFlagSet("test", flag.ExitOnError)
+	runRE := testFlags.String("run", "",
+		"Run only the tests and examples matching this regular expression.")
+	benchRE := testFlags.String("bench", "",
+		"Run the benchmarks matching this regular expression.")
+	verbose := testFlags.Bool("v", false, "Log each test as it is run.")
+	short := testFlags.Bool("short", false, "Tell long-running tests to cut corners.")
+	testFlags.Usage = func() {
+		fmt.Fprint(os.Stderr, testUsage)
+		testFlags.PrintDefaults()
+	}
+
+	// As with "go test", packages come before the flags.
+	var paths []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		paths = append(paths, args[0])
+		args = args[1:]
+	}
+	testFlags.Parse(args)
+	paths = append(paths, testFlags.Args()...)
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	// These are handled by package testing in the interpreted
+	// program.
+	var progArgs []string
+	if *runRE != "" {
+		progArgs = append(progArgs, "-test.run="+*runRE)
+	}
+	if *benchRE != "" {
+		progArgs = append(progArgs, "-test.bench="+*benchRE)
+	}
+	if *verbose {
+		progArgs = append(progArgs, "-test.v")
+	}
+	if *short {
+		progArgs = append(progArgs, "-test.short")
+	}
+
+	if interpTraceMode&interp.EnableStmtTracing != 0 ||
+		interpMode&interp.EnablePostMortem != 0 {
+		gubcmd.Init()
+		gub.Install(gubFlag)
+	}
+
+	status := 0
+	for _, path := range paths {
+		if !testPackage(path, impctx, mode, interpMode, interpTraceMode, progArgs) {
+			status = 1
+		}
+	}
+	return status
+}
+
+// testPackage loads the package at path together with its _test.go
+// files, then runs its tests in the interpreter. It reports whether
+// they all passed.
+func testPackage(path string, impctx *importer.Config, mode ssa2.BuilderMode,
+	interpMode interp.Mode, interpTraceMode interp.TraceMode, progArgs []string) bool {
+
+	start := time.Now()
+	cwd, _ := os.Getwd()
+	bp, err := build.Import(path, cwd, 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can't load package: %s\n", err)
+		return false
+	}
+
+	imp := importer.New(impctx)
+	var infos []*importer.PackageInfo
+	files, err := importer.ParseFiles(imp.Fset, bp.Dir,
+		append(bp.GoFiles, bp.TestGoFiles...)...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+	infos = append(infos, imp.CreatePackage(bp.ImportPath, files...))
+
+	// External tests ("package foo_test") make a package of their own.
+	if len(bp.XTestGoFiles) > 0 {
+		files, err := importer.ParseFiles(imp.Fset, bp.Dir, bp.XTestGoFiles...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return false
+		}
+		infos = append(infos, imp.CreatePackage(bp.ImportPath+"_test", files...))
+	}
+	for _, info := range infos {
+		if info.Err != nil {
+			fmt.Fprintln(os.Stderr, info.Err)
+			return false
+		}
+	}
+
+	if _, err := imp.LoadPackage("runtime"); err != nil {
+		log.Fatalf("LoadPackage(runtime) failed: %s", err)
+	}
+
+	prog := ssa2.NewProgram(imp.Fset, mode)
+	if err := prog.CreatePackages(imp); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+	prog.BuildAll()
+
+	var pkgs []*ssa2.Package
+	for _, info := range infos {
+		pkgs = append(pkgs, prog.Package(info.Pkg))
+	}
+	main := prog.CreateTestMainPackage(pkgs...)
+	if main == nil {
+		fmt.Printf("?   \t%s\t[no test files]\n", bp.ImportPath)
+		return true
+	}
+
+	// testing.Main ends with os.Exit; that mustn't end tortoise too.
+	exitCode := interp.Interpret(main, interpMode|interp.DisableHostExit,
+		interpTraceMode, main.Object.Path(), progArgs)
+	elapsed := time.Since(start).Seconds()
+	if exitCode != 0 {
+		fmt.Printf("FAIL\t%s\t%.3fs\n", bp.ImportPath, elapsed)
+		return false
+	}
+	fmt.Printf("ok  \t%s\t%.3fs\n", bp.ImportPath, elapsed)
+	return true
+}
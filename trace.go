@@ -29,10 +29,14 @@ const (
 	STEP_INSTRUCTION
 	STMT_IN_LIST
 	SWITCH_COND
+	GO_START
+	GO_EXIT
+	RECOVER
+	POST_MORTEM
 )
 
 const TRACE_EVENT_FIRST = OTHER
-const TRACE_EVENT_LAST  = SWITCH_COND
+const TRACE_EVENT_LAST  = POST_MORTEM
 
 type TraceEventMask map[TraceEvent]bool
 
@@ -53,7 +57,12 @@ func init() {
 		FOR_INIT        : "FOR initialize",
 		FOR_COND        : "FOR condition",
 		FOR_ITER        : "FOR iteration",
+		GO_START        : "goroutine start",
+		GO_EXIT         : "goroutine exit",
 		MAIN            : "before main()",
+		PANIC           : "panic",
+		POST_MORTEM     : "unrecovered panic",
+		RECOVER         : "recover",
 		RANGE_STMT      : "range statement",
 		SELECT_TYPE     : "SELECT type",
 	    STEP_INSTRUCTION: "Instruction step",
@@ -82,6 +91,7 @@ type Trace struct {
 	End   token.Pos    // end position of source
 	Event TraceEvent
 	Breakpoint bool    // Set if we should stop here
+	Scope  *Scope      // innermost scope of the statement; nil if unknown
 }
 
 // FIXME: arrange to put in ast
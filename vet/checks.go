@@ -0,0 +1,294 @@
+// Copyright 2013 Rocky Bernstein.
+
+package vet
+
+import (
+	"go/ast"
+	"go/token"
+
+	"code.google.com/p/go.tools/go/types"
+	"github.com/rocky/ssa-interp"
+)
+
+func init() {
+	Register(&Checker{
+		Id:  "unreachable",
+		Doc: "statements after a call of panic",
+		Run: checkUnreachable,
+	})
+	Register(&Checker{
+		Id:  "unusederror",
+		Doc: "error results of calls which are thrown away",
+		Run: checkUnusedError,
+	})
+	Register(&Checker{
+		Id:  "selfassign",
+		Doc: "assignments of a variable to itself",
+		Run: checkSelfAssign,
+	})
+	Register(&Checker{
+		Id:  "nilderef",
+		Doc: "dereferences of a pointer just tested to be nil",
+		Run: checkNilDeref,
+	})
+	Register(&Checker{
+		Id:  "loopclosure",
+		Doc: "goroutine closures which capture a loop variable",
+		Run: checkLoopClosure,
+	})
+}
+
+// checkUnreachable reports the first statement after each call of
+// panic. The builder deletes the code, so it keeps track of them.
+func checkUnreachable(pass *Pass) {
+	for _, dead := range pass.Fn.DeadStmts() {
+		pass.Reportf(dead.Trace.Start, "unreachable code after panic")
+	}
+}
+
+var errorType = types.Universe.Lookup("error").Type()
+
+// errorResult gives the index of the error result of a call of type
+// t: -1 if t is error, the last index if that result of a tuple is an
+// error, or -2 if there is no error.
+func errorResult(t types.Type) int {
+	if types.IsIdentical(t, errorType) {
+		return -1
+	}
+	if tuple, ok := t.(*types.Tuple); ok && tuple.Len() > 0 &&
+		types.IsIdentical(tuple.At(tuple.Len()-1).Type(), errorType) {
+		return tuple.Len() - 1
+	}
+	return -2
+}
+
+// ignoredCall reports whether call is of a function whose error is
+// customarily thrown away, the printing functions of fmt.
+func ignoredCall(call *ssa2.CallCommon) bool {
+	fn := call.StaticCallee()
+	if fn == nil || fn.Pkg == nil || fn.Pkg.Object.Path() != "fmt" {
+		return false
+	}
+	switch fn.Name() {
+	case "Print", "Printf", "Println":
+		return true
+	}
+	return false
+}
+
+// checkUnusedError reports calls of functions returning an error
+// whose error isn't used.
+func checkUnusedError(pass *Pass) {
+	for _, b := range pass.Fn.Blocks {
+		for _, instr := range b.Instrs {
+			call, ok := instr.(*ssa2.Call)
+			if !ok || ignoredCall(call.Common()) {
+				continue
+			}
+			index := errorResult(call.Type())
+			if index == -2 {
+				continue
+			}
+			used := false
+			for _, ref := range *call.Referrers() {
+				switch ref := ref.(type) {
+				case *ssa2.DebugRef:
+				case *ssa2.Extract:
+					if ref.Index == index && hasUses(ref) {
+						used = true
+					}
+				default:
+					used = true
+				}
+			}
+			if !used {
+				pass.Reportf(stmtPos(call), "error result of %s is not used",
+					callName(call.Common()))
+			}
+		}
+	}
+}
+
+// hasUses reports whether v is used other than by DebugRefs. Extract
+// instructions are emitted even for results assigned to _.
+func hasUses(v ssa2.Value) bool {
+	for _, ref := range *v.Referrers() {
+		if _, ok := ref.(*ssa2.DebugRef); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// callName names what call calls, for messages.
+func callName(call *ssa2.CallCommon) string {
+	switch {
+	case call.IsInvoke():
+		return call.Method.Name()
+	case call.StaticCallee() != nil:
+		return call.StaticCallee().String()
+	}
+	return call.Value.Name()
+}
+
+// sameAddr reports whether a and b are sure to be the same address:
+// the same variable, or the same field or constant index of the same
+// address, or loads of the same pointer variable.
+func sameAddr(a, b ssa2.Value) bool {
+	if a == b {
+		return true
+	}
+	switch a := a.(type) {
+	case *ssa2.FieldAddr:
+		b, ok := b.(*ssa2.FieldAddr)
+		return ok && a.Field == b.Field && sameAddr(a.X, b.X)
+	case *ssa2.IndexAddr:
+		b, ok := b.(*ssa2.IndexAddr)
+		if !ok {
+			return false
+		}
+		ai, aok := a.Index.(*ssa2.Const)
+		bi, bok := b.Index.(*ssa2.Const)
+		return aok && bok && ai.Int64() == bi.Int64() && sameAddr(a.X, b.X)
+	case *ssa2.UnOp:
+		b, ok := b.(*ssa2.UnOp)
+		return ok && a.Op == token.MUL && b.Op == token.MUL && isVar(a.X) && a.X == b.X
+	}
+	return false
+}
+
+// isVar reports whether v is the address of a variable.
+func isVar(v ssa2.Value) bool {
+	switch v.(type) {
+	case *ssa2.Alloc, *ssa2.Global:
+		return true
+	}
+	return false
+}
+
+// checkSelfAssign reports stores of what was loaded from the same
+// address, as for x = x or s.f = s.f.
+func checkSelfAssign(pass *Pass) {
+	for _, b := range pass.Fn.Blocks {
+		for _, instr := range b.Instrs {
+			store, ok := instr.(*ssa2.Store)
+			if !ok {
+				continue
+			}
+			load, ok := store.Val.(*ssa2.UnOp)
+			if ok && load.Op == token.MUL && sameAddr(load.X, store.Addr) {
+				name := "a variable"
+				if alloc, ok := store.Addr.(*ssa2.Alloc); ok && alloc.Comment != "" {
+					name = alloc.Comment
+				}
+				pass.Reportf(stmtPos(store), "self-assignment of %s", name)
+			}
+		}
+	}
+}
+
+// nilTest gives the pointer an If in b tests against nil, and the
+// successor of b where the pointer is nil.
+func nilTest(b *ssa2.BasicBlock) (ssa2.Value, *ssa2.BasicBlock) {
+	ifInstr, ok := b.Instrs[len(b.Instrs)-1].(*ssa2.If)
+	if !ok {
+		return nil, nil
+	}
+	cond, ok := ifInstr.Cond.(*ssa2.BinOp)
+	if !ok || (cond.Op != token.EQL && cond.Op != token.NEQ) {
+		return nil, nil
+	}
+	x := cond.X
+	if c, ok := cond.X.(*ssa2.Const); ok && c.IsNil() {
+		x = cond.Y
+	} else if c, ok := cond.Y.(*ssa2.Const); !ok || !c.IsNil() {
+		return nil, nil
+	}
+	if _, ok := x.Type().Underlying().(*types.Pointer); !ok {
+		return nil, nil
+	}
+	if cond.Op == token.EQL {
+		return x, b.Succs[0]
+	}
+	return x, b.Succs[1]
+}
+
+// checkNilDeref reports dereferences of a pointer in the block where
+// an If has just found it to be nil.
+func checkNilDeref(pass *Pass) {
+	for _, b := range pass.Fn.Blocks {
+		x, succ := nilTest(b)
+		if x == nil || len(succ.Preds) != 1 {
+			continue
+		}
+	scan:
+		for _, instr := range succ.Instrs {
+			var addr ssa2.Value
+			switch instr := instr.(type) {
+			case *ssa2.UnOp:
+				if instr.Op == token.MUL {
+					addr = instr.X
+				}
+			case *ssa2.FieldAddr:
+				addr = instr.X
+			case *ssa2.IndexAddr:
+				addr = instr.X
+			case *ssa2.Store:
+				if load, ok := x.(*ssa2.UnOp); ok && instr.Addr == load.X {
+					// The pointer variable changes.
+					break scan
+				}
+				addr = instr.Addr
+			case *ssa2.Call:
+				// The pointer variable may change.
+				break scan
+			}
+			if addr != nil && (addr == x || sameAddr(addr, x)) {
+				pass.Reportf(stmtPos(instr), "dereference of nil pointer %s", varName(x))
+				break
+			}
+		}
+	}
+}
+
+// varName names the variable v was loaded from, if any.
+func varName(v ssa2.Value) string {
+	if load, ok := v.(*ssa2.UnOp); ok {
+		if alloc, ok := load.X.(*ssa2.Alloc); ok && alloc.Comment != "" {
+			return alloc.Comment
+		}
+		return load.X.Name()
+	}
+	return v.Name()
+}
+
+// checkLoopClosure reports go statements in a loop whose closures
+// capture a variable of the loop. All iterations share the variable,
+// so the goroutines are likely to see a later value.
+func checkLoopClosure(pass *Pass) {
+	for _, b := range pass.Fn.Blocks {
+		for _, instr := range b.Instrs {
+			g, ok := instr.(*ssa2.Go)
+			if !ok {
+				continue
+			}
+			mc, ok := g.Call.Value.(*ssa2.MakeClosure)
+			if !ok {
+				continue
+			}
+			for _, binding := range mc.Bindings {
+				alloc, ok := binding.(*ssa2.Alloc)
+				if !ok || alloc.Scope == nil || alloc.Scope.Node() == nil {
+					continue
+				}
+				switch loop := (*alloc.Scope.Node()).(type) {
+				case *ast.ForStmt, *ast.RangeStmt:
+					if loop.Pos() <= g.Pos() && g.Pos() < loop.End() {
+						pass.Reportf(g.Pos(), "goroutine closure captures loop variable %s",
+							alloc.Comment)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2013 Rocky Bernstein.
+
+// Package vet runs static checks over the SSA form of ssa2 programs.
+//
+// Each check is a Checker with a stable identifier, run over each
+// function with code. Checks report problems through a Pass, giving
+// a position; Run collects the reports as Diagnostics, sorted by
+// position. New checks are added with Register.
+//
+// The checks here work on the naive SSA form tortoise builds as well
+// as the lifted one.
+package vet
+
+import (
+	"fmt"
+	"go/token"
+	"sort"
+
+	"github.com/rocky/ssa-interp"
+)
+
+// A Checker is a check run over each function of a program.
+type Checker struct {
+	Id  string // stable identifier, used to select it and in reports
+	Doc string // one-line description
+	Run func(pass *Pass)
+}
+
+// A Diagnostic is a problem a check reports.
+type Diagnostic struct {
+	Check    string // Id of the Checker
+	Fn       *ssa2.Function
+	Position token.Position
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s [%s]", d.Position, d.Message, d.Check)
+}
+
+// A Pass is a run of a Checker over one function.
+type Pass struct {
+	Fn      *ssa2.Function
+	checker *Checker
+	diags   *[]Diagnostic
+}
+
+// Reportf reports a problem at pos in the function of p.
+func (p *Pass) Reportf(pos token.Pos, format string, args ...interface{}) {
+	*p.diags = append(*p.diags, Diagnostic{
+		Check:    p.checker.Id,
+		Fn:       p.Fn,
+		Position: p.Fn.Prog.Fset.Position(pos),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// The registered checkers, in the order registered.
+var checkers []*Checker
+
+// Register adds c to the checks Run may run.
+func Register(c *Checker) {
+	for _, old := range checkers {
+		if old.Id == c.Id {
+			panic("vet: checker " + c.Id + " registered twice")
+		}
+	}
+	checkers = append(checkers, c)
+}
+
+// Checkers gives the registered checkers.
+func Checkers() []*Checker {
+	return checkers
+}
+
+// Lookup gives the registered checker with identifier id, or nil.
+func Lookup(id string) *Checker {
+	for _, c := range checkers {
+		if c.Id == id {
+			return c
+		}
+	}
+	return nil
+}
+
+// byPosition sorts diagnostics by position, then by check.
+type byPosition []Diagnostic
+
+func (s byPosition) Len() int      { return len(s) }
+func (s byPosition) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byPosition) Less(i, j int) bool {
+	a, b := s[i].Position, s[j].Position
+	switch {
+	case a.Filename != b.Filename:
+		return a.Filename < b.Filename
+	case a.Line != b.Line:
+		return a.Line < b.Line
+	case a.Column != b.Column:
+		return a.Column < b.Column
+	}
+	return s[i].Check < s[j].Check
+}
+
+// Run runs checks, all the registered ones if checks is nil, over
+// the functions of pkgs with code, including their methods and
+// anonymous functions.
+//
+// Precondition: all packages are built.
+//
+func Run(prog *ssa2.Program, pkgs []*ssa2.Package, checks []*Checker) []Diagnostic {
+	if checks == nil {
+		checks = checkers
+	}
+	inPkgs := make(map[*ssa2.Package]bool)
+	for _, pkg := range pkgs {
+		inPkgs[pkg] = true
+	}
+	var diags []Diagnostic
+	for fn := range ssa2.AllFunctions(prog) {
+		if !inPkgs[fn.Pkg] || fn.Blocks == nil || fn.Synthetic != "" {
+			continue
+		}
+		for _, c := range checks {
+			c.Run(&Pass{Fn: fn, checker: c, diags: &diags})
+		}
+	}
+	sort.Sort(byPosition(diags))
+	return diags
+}
+
+// stmtPos gives the position of instr, or if it has none, that of the
+// statement it is part of.
+func stmtPos(instr ssa2.Instruction) token.Pos {
+	if pos := instr.Pos(); pos.IsValid() {
+		return pos
+	}
+	b := instr.Block()
+	for i := len(b.Instrs) - 1; i >= 0; i-- {
+		if b.Instrs[i] != instr {
+			continue
+		}
+		for ; i >= 0; i-- {
+			if t, ok := b.Instrs[i].(*ssa2.Trace); ok {
+				return t.Start
+			}
+		}
+		break
+	}
+	return instr.Parent().Pos()
+}
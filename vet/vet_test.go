@@ -0,0 +1,90 @@
+// Copyright 2013 Rocky Bernstein.
+
+package vet_test
+
+import (
+	"go/parser"
+	"testing"
+
+	"code.google.com/p/go.tools/importer"
+	"github.com/rocky/ssa-interp"
+	"github.com/rocky/ssa-interp/vet"
+)
+
+const src = `package main
+
+type T struct{ f int }
+
+func fail() error { return nil }
+
+func two() (int, error) { return 0, nil }
+
+func main() {
+	fail()
+	n, _ := two()
+	x := n
+	x = x
+	var t T
+	t.f = t.f
+	var p *T
+	if p == nil {
+		p.f = 1
+	}
+	for i := 0; i < 3; i++ {
+		go func() { println(i) }()
+	}
+	for _, v := range []int{x} {
+		v := v
+		go func() { println(v) }()
+	}
+	if err := fail(); err != nil {
+		panic(err)
+		println("never")
+	}
+}
+`
+
+func TestChecks(t *testing.T) {
+	imp := importer.New(new(importer.Config))
+	file, err := parser.ParseFile(imp.Fset, "vet.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := imp.CreatePackage("main", file)
+	prog := ssa2.NewProgram(imp.Fset, ssa2.NaiveForm)
+	if err := prog.CreatePackages(imp); err != nil {
+		t.Fatal(err)
+	}
+	prog.BuildAll()
+	pkg := prog.Package(info.Pkg)
+
+	type report struct {
+		check string
+		line  int
+	}
+	want := map[report]bool{
+		{"unusederror", 10}: true,
+		{"unusederror", 11}: true,
+		{"selfassign", 13}:  true,
+		{"selfassign", 15}:  true,
+		{"nilderef", 18}:    true,
+		{"loopclosure", 21}: true,
+		{"unreachable", 29}: true,
+	}
+	for _, d := range vet.Run(prog, []*ssa2.Package{pkg}, nil) {
+		r := report{d.Check, d.Position.Line}
+		if !want[r] {
+			t.Errorf("unexpected report: %s", d)
+		}
+		delete(want, r)
+	}
+	for r := range want {
+		t.Errorf("missing %s report at line %d", r.check, r.line)
+	}
+
+	if c := vet.Lookup("selfassign"); c == nil {
+		t.Errorf("no selfassign checker")
+	} else if diags := vet.Run(prog, []*ssa2.Package{pkg}, []*vet.Checker{c}); len(diags) != 2 {
+		t.Errorf("selfassign alone gave %v; want 2 reports", diags)
+	}
+}